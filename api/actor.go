@@ -0,0 +1,12 @@
+package api
+
+import "net/http"
+
+// actorFromRequest returns who is making the request, as reported by the
+// X-User header the admin frontend sends
+func actorFromRequest(r *http.Request) string {
+	if user := r.Header.Get("X-User"); user != "" {
+		return user
+	}
+	return "unknown"
+}
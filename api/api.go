@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Bnei-Baruch/study-material-service/integrations/kabbalahmedia"
 	"github.com/Bnei-Baruch/study-material-service/storage"
@@ -18,25 +19,39 @@ type App struct {
 	cors                *cors.Cors
 	store               storage.PartStore
 	eventStore          storage.EventStore
+	revisionStore       storage.RevisionStore
 	eventTypeStore      storage.EventTypeStore
 	templateStore       storage.TemplateStore
+	transactor          storage.Transactor
 	kabbalahmediaClient *kabbalahmedia.Client
 	templateConfig      *storage.TemplateConfig
 	emailService        *EmailService
 	apiSecretKey        string
+	trashRetention      time.Duration
+	eventTypeGroups     [][]string // Event types parts may be moved or copied between; empty allows any
+	cacheControl        CacheControl
+	v1Sunset            time.Time // When the deprecated v1 reads go away; zero if not decided
+	graphQLLimits       GraphQLLimits
 }
 
 // NewApp creates a new App instance with dependencies
-func NewApp(partStore storage.PartStore, eventStore storage.EventStore, eventTypeStore storage.EventTypeStore, templateStore storage.TemplateStore, kabbalahmediaClient *kabbalahmedia.Client, templateConfig *storage.TemplateConfig, apiSecretKey string) *App {
+func NewApp(partStore storage.PartStore, eventStore storage.EventStore, revisionStore storage.RevisionStore, eventTypeStore storage.EventTypeStore, templateStore storage.TemplateStore, transactor storage.Transactor, kabbalahmediaClient *kabbalahmedia.Client, templateConfig *storage.TemplateConfig, apiSecretKey string, trashRetention time.Duration, eventTypeGroups [][]string, cacheControl CacheControl, v1Sunset time.Time, graphQLLimits GraphQLLimits) *App {
 	return &App{
 		store:               partStore,
 		eventStore:          eventStore,
+		revisionStore:       revisionStore,
 		eventTypeStore:      eventTypeStore,
 		templateStore:       templateStore,
+		transactor:          transactor,
 		kabbalahmediaClient: kabbalahmediaClient,
 		templateConfig:      templateConfig,
 		emailService:        NewEmailService(),
 		apiSecretKey:        apiSecretKey,
+		trashRetention:      trashRetention,
+		eventTypeGroups:     eventTypeGroups,
+		cacheControl:        cacheControl,
+		v1Sunset:            v1Sunset,
+		graphQLLimits:       graphQLLimits,
 	}
 }
 
@@ -46,71 +61,71 @@ func NewApp(partStore storage.PartStore, eventStore storage.EventStore, eventTyp
 // If no secret key is configured the middleware is a no-op (useful for local dev).
 func (a *App) apiKeyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow GET and OPTIONS (read-only)
-		if r.Method == http.MethodGet || r.Method == http.MethodOptions {
+		// Allow GET and OPTIONS (read-only), and GraphQL, which only reads
+		if r.Method == http.MethodGet || r.Method == http.MethodOptions || r.URL.Path == graphQLPath {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// If no API key configured, allow all (backward compatible for dev)
-		if a.apiSecretKey == "" {
-			next.ServeHTTP(w, r)
+		if !a.isTrustedRequest(r) {
+			log.Printf("[API] Rejected %s %s - RemoteAddr=%s, X-Forwarded-For=%s", r.Method, r.RequestURI, r.RemoteAddr, r.Header.Get("X-Forwarded-For"))
+			writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 			return
 		}
 
-		// Extract IP from request (check X-Forwarded-For first for proxies)
-		ip := r.RemoteAddr
-		if idx := strings.LastIndex(ip, ":"); idx != -1 {
-			// Remove port from direct connection
-			ip = ip[:idx]
-		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		log.Printf("[API] %s %s - RemoteAddr=%s, X-Forwarded-For=%s, ResolvedIP=%s", r.Method, r.RequestURI, r.RemoteAddr, r.Header.Get("X-Forwarded-For"), ip)
+// isTrustedRequest reports whether a request comes from localhost or the internal
+// network, or carries the correct X-API-Key header.
+// If no secret key is configured every request is trusted.
+func (a *App) isTrustedRequest(r *http.Request) bool {
+	// If no API key configured, allow all (backward compatible for dev)
+	if a.apiSecretKey == "" {
+		return true
+	}
 
-		// Check if direct connection is from trusted internal network first
-		if strings.HasPrefix(ip, "10.66.") || strings.HasPrefix(ip, "10.77.") || strings.HasPrefix(ip, "172.") || strings.HasPrefix(ip, "127.") {
-			// Direct connection from trusted network - allow immediately
-			next.ServeHTTP(w, r)
-			return
-		}
+	// Extract IP from request (check X-Forwarded-For first for proxies)
+	ip := r.RemoteAddr
+	if idx := strings.LastIndex(ip, ":"); idx != -1 {
+		// Remove port from direct connection
+		ip = ip[:idx]
+	}
 
-		// For external direct connections, check X-Forwarded-For (from proxy)
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			// X-Forwarded-For can contain multiple IPs, take the first one (original client)
-			if idx := strings.Index(forwarded, ","); idx != -1 {
-				ip = strings.TrimSpace(forwarded[:idx])
-			} else {
-				ip = strings.TrimSpace(forwarded)
-			}
-			log.Printf("[API] Using X-Forwarded-For IP: %s", ip)
-		}
+	// Check if direct connection is from trusted internal network first
+	if strings.HasPrefix(ip, "10.66.") || strings.HasPrefix(ip, "10.77.") || strings.HasPrefix(ip, "172.") || strings.HasPrefix(ip, "127.") {
+		// Direct connection from trusted network - allow immediately
+		return true
+	}
 
-		// Allow localhost/127.0.0.1/[::1] (container internal)
-		if ip == "127.0.0.1" || ip == "localhost" || ip == "::1" || ip == "[::1]" {
-			next.ServeHTTP(w, r)
-			return
+	// For external direct connections, check X-Forwarded-For (from proxy)
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		// X-Forwarded-For can contain multiple IPs, take the first one (original client)
+		if idx := strings.Index(forwarded, ","); idx != -1 {
+			ip = strings.TrimSpace(forwarded[:idx])
+		} else {
+			ip = strings.TrimSpace(forwarded)
 		}
+	}
 
-		// Allow internal network (10.66.0.0/16 and 10.77.0.0/16)
-		if strings.HasPrefix(ip, "10.66.") || strings.HasPrefix(ip, "10.77.") {
-			next.ServeHTTP(w, r)
-			return
-		}
+	// Allow localhost/127.0.0.1/[::1] (container internal)
+	if ip == "127.0.0.1" || ip == "localhost" || ip == "::1" || ip == "[::1]" {
+		return true
+	}
 
-		// Allow Docker internal network (172.x.x.x)
-		if strings.HasPrefix(ip, "172.") {
-			next.ServeHTTP(w, r)
-			return
-		}
+	// Allow internal network (10.66.0.0/16 and 10.77.0.0/16)
+	if strings.HasPrefix(ip, "10.66.") || strings.HasPrefix(ip, "10.77.") {
+		return true
+	}
 
-		// For external requests, require API key
-		if r.Header.Get("X-API-Key") != a.apiSecretKey {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+	// Allow Docker internal network (172.x.x.x)
+	if strings.HasPrefix(ip, "172.") {
+		return true
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	// For external requests, require API key
+	return r.Header.Get("X-API-Key") == a.apiSecretKey
 }
 
 // Init initializes and starts the API server
@@ -118,7 +133,7 @@ func (a *App) Init() {
 	a.initCors()
 	a.initRouters()
 
-	handler := a.cors.Handler(a.apiKeyMiddleware(a.router))
+	handler := requestIDMiddleware(a.cors.Handler(a.apiKeyMiddleware(a.router)))
 
 	addr := viper.GetString("server.bind-address")
 	log.Printf("Starting server on %s", addr)
@@ -132,9 +147,9 @@ func (a *App) Init() {
 func (a *App) initCors() {
 	a.cors = cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
 		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"Content-Length", "Content-Type"},
+		ExposedHeaders:   []string{"Content-Length", "Content-Type", "ETag", requestIDHeader, "Deprecation", "Sunset", "Link"},
 		AllowCredentials: false,
 		MaxAge:           300, // Cache preflight for 5 minutes
 	})
@@ -143,26 +158,72 @@ func (a *App) initCors() {
 // initRouters initializes API routes
 func (a *App) initRouters() {
 	a.router = mux.NewRouter()
+	a.router.NotFoundHandler = http.HandlerFunc(handleRouteNotFound)
+	a.router.MethodNotAllowedHandler = http.HandlerFunc(handleMethodNotAllowed)
+	a.router.Use(a.deprecationMiddleware)
 
 	// Part endpoints
 	a.router.HandleFunc("/api/parts", a.HandleCreatePart).Methods(http.MethodPost, http.MethodOptions)
 	a.router.HandleFunc("/api/parts", a.HandleListParts).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/batch", a.HandleBatchParts).Methods(http.MethodPost, http.MethodOptions)
 	a.router.HandleFunc("/api/parts/{id}", a.HandleGetPart).Methods(http.MethodGet, http.MethodOptions)
 	a.router.HandleFunc("/api/parts/{id}", a.HandleUpdatePart).Methods(http.MethodPut, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}", a.HandlePatchPart).Methods(http.MethodPatch, http.MethodOptions)
 	a.router.HandleFunc("/api/parts/{id}", a.HandleDeletePart).Methods(http.MethodDelete, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/translations", a.HandleGetPartTranslations).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/move", a.HandleMovePart).Methods(http.MethodPost, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/copy", a.HandleCopyPart).Methods(http.MethodPost, http.MethodOptions)
 	a.router.HandleFunc("/api/sources/search", a.HandleSearchSources).Methods(http.MethodGet, http.MethodOptions)
 	a.router.HandleFunc("/api/sources/title", a.HandleGetSourceTitle).Methods(http.MethodGet, http.MethodOptions)
 
 	// Event endpoints
 	a.router.HandleFunc("/api/events", a.HandleCreateEvent).Methods(http.MethodPost, http.MethodOptions)
 	a.router.HandleFunc("/api/events", a.HandleListEvents).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/import", a.HandleImportEvent).Methods(http.MethodPost, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{id}", a.HandleGetEvent).Methods(http.MethodGet, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{id}", a.HandleUpdateEvent).Methods(http.MethodPut, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}", a.HandlePatchEvent).Methods(http.MethodPatch, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{id}", a.HandleDeleteEvent).Methods(http.MethodDelete, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{id}/duplicate", a.HandleDuplicateEvent).Methods(http.MethodPost, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/export", a.HandleExportEvent).Methods(http.MethodGet, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{id}/toggle-public", a.HandleToggleEventPublic).Methods(http.MethodPut, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{id}/send-email", a.HandleSendEventEmail).Methods(http.MethodPost, http.MethodOptions)
 	a.router.HandleFunc("/api/events/{event_id}/parts", a.HandleGetEventParts).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/parts/order", a.HandleGetEventPartOrder).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/parts/order", a.HandleReorderEventParts).Methods(http.MethodPut, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/parts/order/repair", a.HandleRepairEventPartOrder).Methods(http.MethodPost, http.MethodOptions)
+
+	// Search endpoints
+	a.router.HandleFunc("/api/search", a.HandleSearch).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/public/search", a.HandlePublicSearch).Methods(http.MethodGet, http.MethodOptions)
+
+	// Public page and widget endpoints
+	a.router.HandleFunc("/api/public/events", a.HandleListPublicEvents).Methods(http.MethodGet, http.MethodOptions)
+
+	// Version 2: events with their parts in order, each part with its content by language
+	v2 := a.router.PathPrefix("/api/v2").Subrouter()
+	v2.HandleFunc("/events", a.HandleV2ListEvents).Methods(http.MethodGet, http.MethodOptions)
+	v2.HandleFunc("/events/{id}", a.HandleV2GetEvent).Methods(http.MethodGet, http.MethodOptions)
+	v2.HandleFunc("/events/{id}/parts", a.HandleV2ListEventParts).Methods(http.MethodGet, http.MethodOptions)
+	v2.HandleFunc("/parts/{id}", a.HandleV2GetPart).Methods(http.MethodGet, http.MethodOptions)
+
+	// GraphQL: read-only queries over events, parts, event types and templates
+	a.router.HandleFunc(graphQLPath, a.HandleGraphQL).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+
+	// Trash endpoints
+	a.router.HandleFunc("/api/trash", a.HandleListTrash).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/restore", a.HandleRestoreEvent).Methods(http.MethodPost, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/restore", a.HandleRestorePart).Methods(http.MethodPost, http.MethodOptions)
+
+	// Revision endpoints
+	a.router.HandleFunc("/api/parts/{id}/revisions", a.HandleListPartRevisions).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/revisions/diff", a.HandleDiffPartRevisions).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/revisions/{rev:[0-9]+}", a.HandleGetPartRevision).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/parts/{id}/revisions/{rev:[0-9]+}/restore", a.HandleRestorePartRevision).Methods(http.MethodPost, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/revisions", a.HandleListEventRevisions).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/revisions/diff", a.HandleDiffEventRevisions).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/revisions/{rev:[0-9]+}", a.HandleGetEventRevision).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/events/{id}/revisions/{rev:[0-9]+}/restore", a.HandleRestoreEventRevision).Methods(http.MethodPost, http.MethodOptions)
 
 	// Event type endpoints
 	a.router.HandleFunc("/api/event-types", a.HandleListEventTypes).Methods(http.MethodGet, http.MethodOptions)
@@ -178,6 +239,10 @@ func (a *App) initRouters() {
 	a.router.HandleFunc("/api/templates/{id}", a.HandleDeleteTemplate).Methods(http.MethodDelete, http.MethodOptions)
 	a.router.HandleFunc("/api/templates/sync", a.HandleSyncTemplates).Methods(http.MethodPost, http.MethodOptions)
 
+	// API description
+	a.router.HandleFunc("/api/openapi.json", a.HandleOpenAPI).Methods(http.MethodGet, http.MethodOptions)
+	a.router.HandleFunc("/api/docs", a.HandleAPIDocs).Methods(http.MethodGet, http.MethodOptions)
+
 	// Health check
 	a.router.HandleFunc("/health", handleHealth).Methods(http.MethodGet, http.MethodOptions)
 }
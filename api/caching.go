@@ -0,0 +1,118 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultCacheControl lets clients keep responses but revalidate them on every
+// use, which costs a 304 when nothing changed
+const defaultCacheControl = "no-cache"
+
+// CacheControl is the Cache-Control header sent with cacheable GET responses
+type CacheControl struct {
+	Default string            // For routes without their own value; "no-cache" if empty
+	Routes  map[string]string // By route path template, e.g. "/api/events/{event_id}/parts"
+}
+
+// forRequest returns the Cache-Control header for the route that matched r
+func (c CacheControl) forRequest(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			if value, ok := c.Routes[template]; ok {
+				return value
+			}
+		}
+	}
+	if c.Default != "" {
+		return c.Default
+	}
+	return defaultCacheControl
+}
+
+// versionETag is the ETag of a single document: its version, so it can be sent
+// back in If-Match as well as If-None-Match
+func versionETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// listETag builds a strong ETag for a response made of several documents from
+// the ID and version of each, plus anything else the response depends on
+type listETag struct {
+	hash hash.Hash
+}
+
+func newListETag(params ...string) *listETag {
+	e := &listETag{hash: sha256.New()}
+	for _, p := range params {
+		fmt.Fprintf(e.hash, "%s\n", p)
+	}
+	return e
+}
+
+// add records a document the response was built from
+func (e *listETag) add(kind, id string, version int) {
+	fmt.Fprintf(e.hash, "%s/%s@%d\n", kind, id, version)
+}
+
+func (e *listETag) String() string {
+	return `"` + hex.EncodeToString(e.hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the validators and Cache-Control header of a GET response and
+// answers 304 Not Modified if the client's copy is still current. lastModified
+// may be zero for responses that can't tell when they last changed.
+func (a *App) notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	header := w.Header()
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	header.Set("Cache-Control", a.cacheControl.forRequest(r))
+
+	if !unchanged(r, etag, lastModified) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// unchanged evaluates If-None-Match, or If-Modified-Since when there is none.
+// If-None-Match uses the weak comparison, so W/"tag" matches "tag".
+func unchanged(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := strings.TrimSpace(r.Header.Get("If-None-Match")); header != "" {
+		if header == "*" {
+			return true
+		}
+		for _, tag := range strings.Split(header, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// latest returns the most recent of times
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, u := range times {
+		if u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Error codes clients can rely on; the messages that go with them may change
+const (
+	codeInvalidRequestBody    = "invalid_request_body"
+	codeInvalidParameter      = "invalid_parameter"
+	codeInvalidDate           = "invalid_date"
+	codeValidationFailed      = "validation_failed"
+	codeUnsupportedMediaType  = "unsupported_media_type"
+	codeInvalidPatch          = "invalid_patch"
+	codePatchFailed           = "patch_failed"
+	codeImmutableField        = "immutable_field"
+	codeInvalidBundle         = "invalid_bundle"
+	codeInvalidPartOrder      = "invalid_part_order"
+	codePartNotFound          = "part_not_found"
+	codeEventNotFound         = "event_not_found"
+	codeEventTypeNotFound     = "event_type_not_found"
+	codeTemplateNotFound      = "template_not_found"
+	codeRevisionNotFound      = "revision_not_found"
+	codeRouteNotFound         = "route_not_found"
+	codeMethodNotAllowed      = "method_not_allowed"
+	codeUnknownEventType      = "unknown_event_type"
+	codeEventTypeExists       = "event_type_exists"
+	codeTemplateExists        = "template_exists"
+	codeIncompatibleEventType = "incompatible_event_type"
+	codePreparationPartExists = "preparation_part_exists"
+	codeEventTrashed          = "event_trashed"
+	codeVersionConflict       = "version_conflict"
+	codeNotApplied            = "not_applied"
+	codeUnauthorized          = "unauthorized"
+	codeInvalidQuery          = "invalid_query"
+	codeQueryTooDeep          = "query_too_deep"
+	codeQueryTooComplex       = "query_too_complex"
+	codeInternal              = "internal_error"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes what went wrong
+type ErrorBody struct {
+	Code      string       `json:"code"`    // Stable, machine-readable, e.g. "event_not_found"
+	Message   string       `json:"message"` // For people; may change
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError is a problem with one field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationError lists every invalid field of a request
+type validationError []FieldError
+
+func (e validationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add records a problem with a field
+func (e *validationError) add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// writeError responds with status and an error envelope
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	writeErrorBody(w, status, ErrorBody{Code: code, Message: message, RequestID: requestID(r)})
+}
+
+// writeValidationError responds 400 with the invalid fields
+func writeValidationError(w http.ResponseWriter, r *http.Request, fields validationError) {
+	writeErrorBody(w, http.StatusBadRequest, ErrorBody{
+		Code:      codeValidationFailed,
+		Message:   fields.Error(),
+		Details:   fields,
+		RequestID: requestID(r),
+	})
+}
+
+// writeFieldError responds 400 with code for a problem with one field or query
+// parameter, e.g. codeInvalidDate for "date"
+func writeFieldError(w http.ResponseWriter, r *http.Request, code, field, message string) {
+	writeErrorBody(w, http.StatusBadRequest, ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   []FieldError{{Field: field, Message: message}},
+		RequestID: requestID(r),
+	})
+}
+
+// writeInternalError logs err and responds 500 with message alone, so internal
+// details don't reach clients. The request ID ties the response to the log.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	log.Printf("[API] %s %s %s: %s: %v", requestID(r), r.Method, r.URL.Path, message, err)
+	writeError(w, r, http.StatusInternalServerError, codeInternal, message)
+}
+
+func writeErrorBody(w http.ResponseWriter, status int, body ErrorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: body})
+}
+
+// handleRouteNotFound answers requests for paths without a route
+func handleRouteNotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, codeRouteNotFound, "No such endpoint")
+}
+
+// handleMethodNotAllowed answers requests for a route with a method it doesn't serve
+func handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+}
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a request ID taken from the client
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// requestIDMiddleware gives every request an ID, sent back in the X-Request-ID
+// header and in error responses. An ID sent by the client or a proxy is kept,
+// so a request can be followed across services.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID requestIDMiddleware gave the request
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts IDs that are safe to log and echo: short, and made of
+// letters, digits and a few separators
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// graphQLPath is where the GraphQL endpoint is served
+const graphQLPath = "/api/graphql"
+
+// GraphQLRequest is a GraphQL query, sent as the body of a POST or as query
+// parameters of a GET with variables encoded as JSON
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// GraphQLResponse is the result of a GraphQL query
+type GraphQLResponse struct {
+	Data   interface{}                `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// graphQLContext is the state resolvers of one request share
+type graphQLContext struct {
+	app     *App
+	r       *http.Request
+	trusted bool // Untrusted requests only see public events and their parts
+	loaders graphQLLoaders
+
+	eventTypes      []*storage.EventType // Loaded on first use
+	eventTypeByName map[string]*storage.EventType
+}
+
+type graphQLContextKey struct{}
+
+// graphQLFrom returns the state of the request a resolver runs for
+func graphQLFrom(ctx context.Context) *graphQLContext {
+	return ctx.Value(graphQLContextKey{}).(*graphQLContext)
+}
+
+// graphQLError is an error returned to GraphQL clients with a code from
+// errors.go in its extensions
+type graphQLError struct {
+	code      string
+	message   string
+	requestID string
+}
+
+func (e *graphQLError) Error() string {
+	return e.message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *graphQLError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.code}
+	if e.requestID != "" {
+		extensions["request_id"] = e.requestID
+	}
+	return extensions
+}
+
+// internalError logs err and returns an error with message alone, so internal
+// details don't reach clients
+func (gc *graphQLContext) internalError(err error, message string) error {
+	log.Printf("[GraphQL] %s: %s: %v", requestID(gc.r), message, err)
+	return &graphQLError{code: codeInternal, message: message, requestID: requestID(gc.r)}
+}
+
+// HandleGraphQL runs a read-only GraphQL query over events, parts, event types
+// and templates. Query errors are answered in the GraphQL format, with a code in
+// the extensions of each error.
+func (a *App) HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	if r.Method == http.MethodGet {
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if variables := params.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeFieldError(w, r, codeInvalidParameter, "variables", "Invalid variables, use a JSON object")
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+	if req.Query == "" {
+		writeFieldError(w, r, codeInvalidParameter, "query", "Query is required")
+		return
+	}
+
+	schema, err := getGraphQLSchema()
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to build the GraphQL schema")
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		writeGraphQLErrors(w, r, codeInvalidQuery, gqlerrors.FormatErrors(err))
+		return
+	}
+	if validation := graphql.ValidateDocument(&schema, document, nil); !validation.IsValid {
+		writeGraphQLErrors(w, r, codeInvalidQuery, validation.Errors)
+		return
+	}
+	if code, err := a.graphQLLimits.check(&schema, document, req.OperationName, req.Variables); err != nil {
+		writeGraphQLErrors(w, r, code, gqlerrors.FormatErrors(err))
+		return
+	}
+
+	gc := &graphQLContext{app: a, r: r, trusted: a.isTrustedRequest(r)}
+	gc.loaders = newGraphQLLoaders(gc)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(r.Context(), graphQLContextKey{}, gc),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GraphQLResponse{Data: result.Data, Errors: result.Errors})
+}
+
+// writeGraphQLErrors responds 400 to a query that can't run, giving every error code
+func writeGraphQLErrors(w http.ResponseWriter, r *http.Request, code string, errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		errs[i].Extensions = map[string]interface{}{"code": code, "request_id": requestID(r)}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(GraphQLResponse{Errors: errs})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	// defaultGraphQLMaxDepth and defaultGraphQLMaxComplexity apply when no limit is configured
+	defaultGraphQLMaxDepth      = 10
+	defaultGraphQLMaxComplexity = 5000
+
+	// graphQLListSize is the assumed length of lists without a limit argument,
+	// e.g. an event's parts
+	graphQLListSize = 10
+)
+
+// GraphQLLimits bound how much work one GraphQL query may ask for
+type GraphQLLimits struct {
+	MaxDepth      int // Deepest nesting of fields; 10 if zero
+	MaxComplexity int // Estimated number of values resolved; 5000 if zero
+}
+
+// check estimates the depth and complexity of the operation that will run and
+// returns an error code and error if it isn't a query or is over the limits.
+// Each field costs 1, and the fields selected on a list cost as many times as
+// the list is long: its limit argument, or graphQLListSize. Introspection is free.
+func (l GraphQLLimits) check(schema *graphql.Schema, document *ast.Document, operationName string, variables map[string]interface{}) (string, error) {
+	maxDepth, maxComplexity := l.MaxDepth, l.MaxComplexity
+	if maxDepth <= 0 {
+		maxDepth = defaultGraphQLMaxDepth
+	}
+	if maxComplexity <= 0 {
+		maxComplexity = defaultGraphQLMaxComplexity
+	}
+
+	cost := queryCost{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			cost.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || d.Name != nil && d.Name.Value == operationName {
+				operation = d
+			}
+		}
+	}
+	if operation == nil {
+		return "", nil // Execution reports it
+	}
+	if operation.Operation != ast.OperationTypeQuery {
+		return codeInvalidQuery, fmt.Errorf("only queries are supported, the GraphQL API is read-only")
+	}
+
+	depth, complexity := cost.selectionSet(operation.SelectionSet, schema.QueryType())
+	if depth > maxDepth {
+		return codeQueryTooDeep, fmt.Errorf("query is %d levels deep, the limit is %d", depth, maxDepth)
+	}
+	if complexity > maxComplexity {
+		return codeQueryTooComplex, fmt.Errorf("query complexity is %d, the limit is %d; select fewer fields or lower limit", complexity, maxComplexity)
+	}
+	return "", nil
+}
+
+// queryCost walks a validated query
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// selectionSet returns the depth and complexity of the fields selected on a
+// value of type parent
+func (c queryCost) selectionSet(set *ast.SelectionSet, parent *graphql.Object) (depth, complexity int) {
+	if set == nil || parent == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, cx int
+		switch s := selection.(type) {
+		case *ast.Field:
+			d, cx = c.field(s, parent)
+		case *ast.InlineFragment:
+			d, cx = c.selectionSet(s.SelectionSet, parent)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[s.Name.Value]; ok {
+				d, cx = c.selectionSet(fragment.SelectionSet, parent)
+			}
+		}
+		depth = max(depth, d)
+		complexity += cx
+	}
+	return depth, complexity
+}
+
+func (c queryCost) field(field *ast.Field, parent *graphql.Object) (depth, complexity int) {
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+	definition, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return 0, 0
+	}
+	object, _ := graphql.GetNamed(definition.Type).(*graphql.Object)
+	depth, complexity = c.selectionSet(field.SelectionSet, object)
+	return depth + 1, 1 + c.listSize(field, definition)*complexity
+}
+
+// listSize returns how many values a field is expected to return
+func (c queryCost) listSize(field *ast.Field, definition *graphql.FieldDefinition) int {
+	t := definition.Type
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType
+	}
+	if _, ok := t.(*graphql.List); !ok {
+		return 1
+	}
+
+	for _, arg := range definition.Args {
+		if arg.Name() != "limit" {
+			continue
+		}
+		limit, _ := arg.DefaultValue.(int)
+		for _, given := range field.Arguments {
+			if given.Name.Value == "limit" {
+				if value, ok := c.intValue(given.Value); ok {
+					limit = value
+				}
+			}
+		}
+		return max(min(limit, maxGraphQLLimit), 1)
+	}
+	return graphQLListSize
+}
+
+// intValue returns the value of an integer literal or variable
+func (c queryCost) intValue(value ast.Value) (int, bool) {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := c.variables[v.Name.Value].(type) {
+		case float64:
+			return int(n), true
+		case int:
+			return n, true
+		}
+	}
+	return 0, false
+}
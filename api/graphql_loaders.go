@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// batchLoader loads documents by key in batches, so resolving a field of every
+// item in a list costs one query instead of one per item. Resolvers ask for a key
+// with load and return the thunk they get back. graphql-go calls thunks only once
+// every field at the same depth is resolved, so the first thunk called loads all
+// the keys asked for so far. Resolvers of a request run on one goroutine.
+type batchLoader struct {
+	fetch   func(keys []string) (map[string]interface{}, error)
+	pending []string
+	loaded  map[string]batchResult
+}
+
+type batchResult struct {
+	value interface{}
+	err   error
+}
+
+func newBatchLoader(fetch func(keys []string) (map[string]interface{}, error)) *batchLoader {
+	return &batchLoader{fetch: fetch, loaded: make(map[string]batchResult)}
+}
+
+// load queues key and returns a thunk that returns its document, or nil if
+// there is none
+func (l *batchLoader) load(key string) func() (interface{}, error) {
+	if _, ok := l.loaded[key]; !ok && !containsID(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	return func() (interface{}, error) {
+		if _, ok := l.loaded[key]; !ok {
+			l.flush()
+		}
+		result := l.loaded[key]
+		return result.value, result.err
+	}
+}
+
+// prime records a document loaded some other way, e.g. by a list query
+func (l *batchLoader) prime(key string, value interface{}) {
+	if _, ok := l.loaded[key]; !ok {
+		l.loaded[key] = batchResult{value: value}
+	}
+}
+
+// flush loads every pending key with one fetch
+func (l *batchLoader) flush() {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		l.loaded[key] = batchResult{value: values[key], err: err}
+	}
+}
+
+// graphQLLoaders are the batch loaders of one request
+type graphQLLoaders struct {
+	events       *batchLoader // *storage.Event by ID
+	partsByEvent *batchLoader // []*storage.LessonPart by event ID, in order and by language
+}
+
+func newGraphQLLoaders(gc *graphQLContext) graphQLLoaders {
+	return graphQLLoaders{
+		events: newBatchLoader(func(ids []string) (map[string]interface{}, error) {
+			filter := bson.M{"_id": bson.M{"$in": ids}}
+			if !gc.trusted {
+				filter["public"] = true
+			}
+			events, _, err := gc.app.eventStore.ListEventsFiltered(gc.r.Context(), filter, 0, 0)
+			if err != nil {
+				return nil, gc.internalError(err, "Failed to list events")
+			}
+			byID := make(map[string]interface{}, len(events))
+			for i := range events {
+				byID[events[i].ID] = &events[i]
+			}
+			return byID, nil
+		}),
+		partsByEvent: newBatchLoader(func(eventIDs []string) (map[string]interface{}, error) {
+			parts, _, err := gc.app.store.ListPartsFiltered(gc.r.Context(), storage.PartQuery{EventIDs: eventIDs})
+			if err != nil {
+				return nil, gc.internalError(err, "Failed to list parts")
+			}
+			byEvent := make(map[string]interface{}, len(eventIDs))
+			for _, eventID := range eventIDs {
+				byEvent[eventID] = []*storage.LessonPart{}
+			}
+			for _, p := range parts {
+				byEvent[p.EventID] = append(byEvent[p.EventID].([]*storage.LessonPart), p)
+			}
+			return byEvent, nil
+		}),
+	}
+}
+
+// part returns a part by ID. Without an API key, only parts of public events
+// are found.
+func (gc *graphQLContext) part(id string) (interface{}, error) {
+	part, err := gc.app.store.GetPart(gc.r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, gc.internalError(err, "Failed to get part")
+	}
+	if gc.trusted {
+		return part, nil
+	}
+	if part.EventID == "" {
+		return nil, nil
+	}
+	load := gc.loaders.events.load(part.EventID)
+	return func() (interface{}, error) {
+		event, err := load()
+		if err != nil || event == nil {
+			return nil, err
+		}
+		return part, nil
+	}, nil
+}
+
+// translations returns every language version of a part, the part included,
+// sorted by language. Versions are found among the parts of the part's event,
+// which are loaded together for every part in the response.
+func (gc *graphQLContext) translations(part *storage.LessonPart) (interface{}, error) {
+	if part.EventID == "" {
+		translations, _, err := gc.app.store.ListPartsFiltered(gc.r.Context(), storage.TranslationsQuery(part))
+		if err != nil {
+			return nil, gc.internalError(err, "Failed to list translations")
+		}
+		return translations, nil
+	}
+
+	load := gc.loaders.partsByEvent.load(part.EventID)
+	return func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		// Like storage.TranslationsQuery: the same translation group, or the
+		// same order for parts from before translation groups
+		var translations []*storage.LessonPart
+		for _, p := range value.([]*storage.LessonPart) {
+			if part.TranslationGroupID != "" && p.TranslationGroupID == part.TranslationGroupID ||
+				part.TranslationGroupID == "" && p.Order == part.Order {
+				translations = append(translations, p)
+			}
+		}
+		sort.Slice(translations, func(i, j int) bool { return translations[i].Language < translations[j].Language })
+		return translations, nil
+	}, nil
+}
+
+// listEventTypes returns every event type, loading them once per request
+func (gc *graphQLContext) listEventTypes() ([]*storage.EventType, error) {
+	if gc.eventTypeByName == nil {
+		eventTypes, err := gc.app.eventTypeStore.ListEventTypes(gc.r.Context())
+		if err != nil {
+			return nil, gc.internalError(err, "Failed to list event types")
+		}
+		gc.eventTypes = eventTypes
+		gc.eventTypeByName = make(map[string]*storage.EventType, len(eventTypes))
+		for _, eventType := range eventTypes {
+			gc.eventTypeByName[eventType.Name] = eventType
+		}
+	}
+	return gc.eventTypes, nil
+}
+
+// eventType returns the event type with a name, or nil if there is none
+func (gc *graphQLContext) eventType(name string) (interface{}, error) {
+	if _, err := gc.listEventTypes(); err != nil {
+		return nil, err
+	}
+	if eventType, ok := gc.eventTypeByName[name]; ok {
+		return eventType, nil
+	}
+	return nil, nil
+}
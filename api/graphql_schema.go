@@ -0,0 +1,520 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/graphql-go/graphql"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// defaultGraphQLLimit and maxGraphQLLimit bound the number of events or parts in a list
+	defaultGraphQLLimit = 20
+	maxGraphQLLimit     = 100
+)
+
+var (
+	graphQLOnce      sync.Once
+	graphQLSchema    graphql.Schema
+	graphQLSchemaErr error
+)
+
+// getGraphQLSchema returns the read-only GraphQL schema, built on first use
+func getGraphQLSchema() (graphql.Schema, error) {
+	graphQLOnce.Do(func() {
+		graphQLSchema, graphQLSchemaErr = newGraphQLSchema()
+	})
+	return graphQLSchema, graphQLSchemaErr
+}
+
+// graphQLTranslation is a text in one language, for fields stored as maps by language
+type graphQLTranslation struct {
+	Language string
+	Text     string
+}
+
+// graphQLTranslations lists translations by language
+func graphQLTranslations(texts map[string]string) []graphQLTranslation {
+	translations := make([]graphQLTranslation, 0, len(texts))
+	for language, text := range texts {
+		translations = append(translations, graphQLTranslation{Language: language, Text: text})
+	}
+	sort.Slice(translations, func(i, j int) bool { return translations[i].Language < translations[j].Language })
+	return translations
+}
+
+// localizedField is a title(language) field over a map of translations,
+// falling back like the public pages do
+func localizedField(description string, texts func(source interface{}) map[string]string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.String,
+		Description: description,
+		Args: graphql.FieldConfigArgument{
+			"language": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "he"},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			language, _ := p.Args["language"].(string)
+			if language == "" {
+				language = "he"
+			}
+			languages := []string{language}
+			for _, fallback := range publicFallbackLanguages {
+				if !containsID(languages, fallback) {
+					languages = append(languages, fallback)
+				}
+			}
+			return localized(texts(p.Source), languages), nil
+		},
+	}
+}
+
+// dateField formats a time field as YYYY-MM-DD
+func dateField(get func(source interface{}) time.Time) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.String),
+		Description: "YYYY-MM-DD",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source).Format("2006-01-02"), nil
+		},
+	}
+}
+
+func newGraphQLSchema() (graphql.Schema, error) {
+	nonNullString := graphql.NewNonNull(graphql.String)
+	nonNullInt := graphql.NewNonNull(graphql.Int)
+	nonNullBoolean := graphql.NewNonNull(graphql.Boolean)
+
+	translationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Translation",
+		Description: "A text in one language",
+		Fields: graphql.Fields{
+			"language": &graphql.Field{Type: nonNullString},
+			"text":     &graphql.Field{Type: nonNullString},
+		},
+	})
+
+	sourceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Source",
+		Description: "A study source from kabbalahmedia",
+		Fields: graphql.Fields{
+			"sourceId":    &graphql.Field{Type: nonNullString},
+			"sourceTitle": &graphql.Field{Type: nonNullString},
+			"sourceUrl":   &graphql.Field{Type: nonNullString},
+			"pageNumber":  &graphql.Field{Type: graphql.String},
+			"startPoint":  &graphql.Field{Type: graphql.String},
+			"endPoint":    &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	customLinkType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CustomLink",
+		Description: "A link with its own title",
+		Fields: graphql.Fields{
+			"title": &graphql.Field{Type: nonNullString},
+			"url":   &graphql.Field{Type: nonNullString},
+		},
+	})
+
+	eventTypeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "EventType",
+		Description: "A kind of event, e.g. morning_lesson",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name": &graphql.Field{Type: nonNullString},
+			"title": localizedField("Title in a language, falling back to Hebrew, then English", func(source interface{}) map[string]string {
+				return source.(*storage.EventType).Titles
+			}),
+			"titles": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(translationType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLTranslations(p.Source.(*storage.EventType).Titles), nil
+				},
+			},
+			"color":     &graphql.Field{Type: nonNullString},
+			"order":     &graphql.Field{Type: nonNullInt},
+			"version":   &graphql.Field{Type: nonNullInt},
+			"createdAt": &graphql.Field{Type: graphql.DateTime},
+			"updatedAt": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	templateType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Template",
+		Description: "A part title template",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title": localizedField("Title in a language, falling back to Hebrew, then English", func(source interface{}) map[string]string {
+				return source.(storage.TemplateDefinition).Translations
+			}),
+			"translations": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(translationType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLTranslations(p.Source.(storage.TemplateDefinition).Translations), nil
+				},
+			},
+			"visible": &graphql.Field{Type: nonNullBoolean, Description: "Whether the template is offered in the part form"},
+		},
+	})
+
+	// Events and parts refer to each other, so their fields are declared once both exist
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Event",
+		Description: "A study event, e.g. a morning lesson",
+		Fields:      graphql.Fields{},
+	})
+	partType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "LessonPart",
+		Description: "One part of an event in one language",
+		Fields:      graphql.Fields{},
+	})
+	partListType := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(partType)))
+
+	eventFields := graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"date":      dateField(func(source interface{}) time.Time { return source.(*storage.Event).Date }),
+		"startTime": &graphql.Field{Type: graphql.String, Description: "HH:MM"},
+		"endTime":   &graphql.Field{Type: graphql.String, Description: "HH:MM"},
+		"type":      &graphql.Field{Type: nonNullString, Description: "Name of the event type"},
+		"eventType": &graphql.Field{
+			Type:        eventTypeType,
+			Description: "The event type named by type",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return graphQLFrom(p.Context).eventType(p.Source.(*storage.Event).Type)
+			},
+		},
+		"number": &graphql.Field{Type: nonNullInt, Description: "Number of the event among the same day's events"},
+		"order":  &graphql.Field{Type: nonNullInt},
+		"title": localizedField("Title in a language, falling back to Hebrew, then English", func(source interface{}) map[string]string {
+			return source.(*storage.Event).Titles
+		}),
+		"titles": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(translationType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return graphQLTranslations(p.Source.(*storage.Event).Titles), nil
+			},
+		},
+		"public":    &graphql.Field{Type: nonNullBoolean},
+		"version":   &graphql.Field{Type: nonNullInt},
+		"createdAt": &graphql.Field{Type: graphql.DateTime},
+		"updatedAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*storage.Event).LastModified(), nil
+			},
+		},
+		"parts": &graphql.Field{
+			Type:        partListType,
+			Description: "The event's parts in order, in every language or only in one",
+			Args: graphql.FieldConfigArgument{
+				"language": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				language, _ := p.Args["language"].(string)
+				load := graphQLFrom(p.Context).loaders.partsByEvent.load(p.Source.(*storage.Event).ID)
+				return func() (interface{}, error) {
+					value, err := load()
+					if err != nil {
+						return nil, err
+					}
+					parts, _ := value.([]*storage.LessonPart)
+					if language == "" {
+						return parts, nil
+					}
+					inLanguage := make([]*storage.LessonPart, 0, len(parts))
+					for _, part := range parts {
+						if part.Language == language {
+							inLanguage = append(inLanguage, part)
+						}
+					}
+					return inLanguage, nil
+				}, nil
+			},
+		},
+	}
+	for name, field := range eventFields {
+		eventType.AddFieldConfig(name, field)
+	}
+
+	partFields := graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"eventId": &graphql.Field{Type: graphql.ID},
+		"event": &graphql.Field{
+			Type: eventType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				eventID := p.Source.(*storage.LessonPart).EventID
+				if eventID == "" {
+					return nil, nil
+				}
+				return graphQLFrom(p.Context).loaders.events.load(eventID), nil
+			},
+		},
+		"translationGroupId":     &graphql.Field{Type: graphql.ID, Description: "Shared by every language version of the part"},
+		"language":               &graphql.Field{Type: nonNullString},
+		"order":                  &graphql.Field{Type: nonNullInt, Description: "Position within the event; 0 is the preparation part"},
+		"title":                  &graphql.Field{Type: nonNullString},
+		"description":            &graphql.Field{Type: graphql.String},
+		"date":                   dateField(func(source interface{}) time.Time { return source.(*storage.LessonPart).Date }),
+		"partType":               &graphql.Field{Type: nonNullString, Description: "\"live_lesson\" or \"recorded_lesson\""},
+		"recordedLessonDate":     &graphql.Field{Type: graphql.String, Description: "YYYY-MM-DD"},
+		"excerptsLink":           &graphql.Field{Type: graphql.String},
+		"transcriptLink":         &graphql.Field{Type: graphql.String},
+		"lessonLink":             &graphql.Field{Type: graphql.String},
+		"programLink":            &graphql.Field{Type: graphql.String},
+		"readingBeforeSleepLink": &graphql.Field{Type: graphql.String},
+		"lessonPreparationLink":  &graphql.Field{Type: graphql.String},
+		"lineupForHostsLink":     &graphql.Field{Type: graphql.String},
+		"customLinks": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(customLinkType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return nonNilSlice(p.Source.(*storage.LessonPart).CustomLinks), nil
+			},
+		},
+		"sources": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(sourceType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return nonNilSlice(p.Source.(*storage.LessonPart).Sources), nil
+			},
+		},
+		"translationStub":  &graphql.Field{Type: nonNullBoolean, Description: "Whether the part is an untranslated copy of another language"},
+		"showUpdatedBadge": &graphql.Field{Type: nonNullBoolean},
+		"version":          &graphql.Field{Type: nonNullInt},
+		"createdAt":        &graphql.Field{Type: graphql.DateTime},
+		"updatedAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*storage.LessonPart).LastModified(), nil
+			},
+		},
+		"translations": &graphql.Field{
+			Type:        partListType,
+			Description: "Every language version of the part, this one included, by language",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return graphQLFrom(p.Context).translations(p.Source.(*storage.LessonPart))
+			},
+		},
+	}
+	for name, field := range partFields {
+		partType.AddFieldConfig(name, field)
+	}
+
+	limitArgs := graphql.FieldConfigArgument{
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultGraphQLLimit, Description: fmt.Sprintf("At most %d", maxGraphQLLimit)},
+		"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	}
+	withLimit := func(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		for name, arg := range limitArgs {
+			args[name] = arg
+		}
+		return args
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"events": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(eventType))),
+				Description: "Events, newest first within the same order. Only public events without an API key.",
+				Args: withLimit(graphql.FieldConfigArgument{
+					"public": &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"type":   &graphql.ArgumentConfig{Type: graphql.String},
+					"from":   &graphql.ArgumentConfig{Type: graphql.String, Description: "Only events on or after this day (YYYY-MM-DD)"},
+					"to":     &graphql.ArgumentConfig{Type: graphql.String, Description: "Only events on or before this day (YYYY-MM-DD)"},
+				}),
+				Resolve: resolveGraphQLEvents,
+			},
+			"event": &graphql.Field{
+				Type: eventType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLFrom(p.Context).loaders.events.load(p.Args["id"].(string)), nil
+				},
+			},
+			"parts": &graphql.Field{
+				Type:        partListType,
+				Description: "Parts by date, event and order. Only parts of public events without an API key.",
+				Args: withLimit(graphql.FieldConfigArgument{
+					"eventId":         &graphql.ArgumentConfig{Type: graphql.ID},
+					"language":        &graphql.ArgumentConfig{Type: graphql.String},
+					"partType":        &graphql.ArgumentConfig{Type: graphql.String},
+					"sourceId":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Only parts that reference this kabbalahmedia source"},
+					"from":            &graphql.ArgumentConfig{Type: graphql.String, Description: "Only parts dated on or after this day (YYYY-MM-DD)"},
+					"to":              &graphql.ArgumentConfig{Type: graphql.String, Description: "Only parts dated on or before this day (YYYY-MM-DD)"},
+					"translationStub": &graphql.ArgumentConfig{Type: graphql.Boolean},
+				}),
+				Resolve: resolveGraphQLParts,
+			},
+			"part": &graphql.Field{
+				Type: partType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLFrom(p.Context).part(p.Args["id"].(string))
+				},
+			},
+			"eventTypes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(eventTypeType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLFrom(p.Context).listEventTypes()
+				},
+			},
+			"eventType": &graphql.Field{
+				Type: eventTypeType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLFrom(p.Context).eventType(p.Args["name"].(string))
+				},
+			},
+			"templates": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(templateType))),
+				Args: graphql.FieldConfigArgument{
+					"visible": &graphql.ArgumentConfig{Type: graphql.Boolean},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					visible, filter := p.Args["visible"].(bool)
+					templates := []storage.TemplateDefinition{}
+					for _, template := range graphQLFrom(p.Context).app.templateConfig.Templates {
+						if !filter || template.Visible == visible {
+							templates = append(templates, template)
+						}
+					}
+					return templates, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// resolveGraphQLEvents lists events like GET /api/v2/events
+func resolveGraphQLEvents(p graphql.ResolveParams) (interface{}, error) {
+	gc := graphQLFrom(p.Context)
+	limit, offset, err := graphQLPage(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	if public, ok := p.Args["public"].(bool); ok {
+		filter["public"] = public
+	}
+	if !gc.trusted {
+		filter["public"] = true
+	}
+	if eventType, ok := p.Args["type"].(string); ok && eventType != "" {
+		filter["type"] = eventType
+	}
+	dateFilter := bson.M{}
+	if from, err := graphQLDate(p.Args, "from"); err != nil {
+		return nil, err
+	} else if from != nil {
+		dateFilter["$gte"] = *from
+	}
+	if to, err := graphQLDate(p.Args, "to"); err != nil {
+		return nil, err
+	} else if to != nil {
+		dateFilter["$lte"] = endOfDay(*to)
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	events, _, err := gc.app.eventStore.ListEventsFiltered(p.Context, filter, limit, offset)
+	if err != nil {
+		return nil, gc.internalError(err, "Failed to list events")
+	}
+	result := make([]*storage.Event, len(events))
+	for i := range events {
+		result[i] = &events[i]
+		gc.loaders.events.prime(events[i].ID, result[i])
+	}
+	return result, nil
+}
+
+// resolveGraphQLParts lists parts like GET /api/parts
+func resolveGraphQLParts(p graphql.ResolveParams) (interface{}, error) {
+	gc := graphQLFrom(p.Context)
+	limit, offset, err := graphQLPage(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	query := storage.PartQuery{
+		Sort:   []storage.SortField{{Field: "date", Desc: true}, {Field: "event_id"}, {Field: "order"}, {Field: "language"}},
+		Limit:  limit,
+		Offset: offset,
+	}
+	query.EventID, _ = p.Args["eventId"].(string)
+	query.Language, _ = p.Args["language"].(string)
+	query.PartType, _ = p.Args["partType"].(string)
+	query.SourceID, _ = p.Args["sourceId"].(string)
+	if stub, ok := p.Args["translationStub"].(bool); ok {
+		query.TranslationStub = &stub
+	}
+	if query.FromDate, err = graphQLDate(p.Args, "from"); err != nil {
+		return nil, err
+	}
+	if query.ToDate, err = graphQLDate(p.Args, "to"); err != nil {
+		return nil, err
+	}
+	if query.ToDate != nil {
+		to := endOfDay(*query.ToDate)
+		query.ToDate = &to
+	}
+	query.PublicEventsOnly = !gc.trusted
+
+	parts, _, err := gc.app.store.ListPartsFiltered(p.Context, query)
+	if err != nil {
+		return nil, gc.internalError(err, "Failed to list parts")
+	}
+	return parts, nil
+}
+
+// graphQLPage reads the limit and offset arguments
+func graphQLPage(args map[string]interface{}) (limit, offset int, err error) {
+	limit, _ = args["limit"].(int)
+	offset, _ = args["offset"].(int)
+	if limit <= 0 {
+		return 0, 0, &graphQLError{code: codeInvalidParameter, message: "limit must be positive"}
+	}
+	if offset < 0 {
+		return 0, 0, &graphQLError{code: codeInvalidParameter, message: "offset can't be negative"}
+	}
+	return min(limit, maxGraphQLLimit), offset, nil
+}
+
+// endOfDay returns the last instant MongoDB can store on the given day, so the
+// inclusive to filters of events and parts are both $lte endOfDay(day)
+func endOfDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1).Add(-time.Millisecond)
+}
+
+// graphQLDate reads a YYYY-MM-DD argument, returning nil if it isn't given
+func graphQLDate(args map[string]interface{}, name string) (*time.Time, error) {
+	s, _ := args[name].(string)
+	if s == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, &graphQLError{code: codeInvalidDate, message: fmt.Sprintf("invalid %s date, use YYYY-MM-DD", name)}
+	}
+	return &date, nil
+}
+
+// nonNilSlice returns an empty slice for a nil one, for non-null list fields
+func nonNilSlice[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -2,10 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
@@ -15,9 +18,17 @@ var validNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
 
 // HandleListEventTypes returns all event types sorted by order
 func (a *App) HandleListEventTypes(w http.ResponseWriter, r *http.Request) {
-	types, err := a.eventTypeStore.ListEventTypes()
+	types, err := a.eventTypeStore.ListEventTypes(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list event types: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to list event types")
+		return
+	}
+
+	etag := newListETag(strconv.Itoa(len(types)))
+	for _, et := range types {
+		etag.add("event_type", et.ID, et.Version)
+	}
+	if a.notModified(w, r, etag.String(), time.Time{}) {
 		return
 	}
 
@@ -29,9 +40,17 @@ func (a *App) HandleListEventTypes(w http.ResponseWriter, r *http.Request) {
 func (a *App) HandleGetEventType(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	et, err := a.eventTypeStore.GetEventType(id)
+	et, err := a.eventTypeStore.GetEventType(r.Context(), id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Event type not found: %v", err), http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventTypeNotFound, "Event type not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event type")
+		return
+	}
+
+	if a.notModified(w, r, versionETag(et.Version), et.UpdatedAt) {
 		return
 	}
 
@@ -43,17 +62,19 @@ func (a *App) HandleGetEventType(w http.ResponseWriter, r *http.Request) {
 func (a *App) HandleCreateEventType(w http.ResponseWriter, r *http.Request) {
 	var req storage.CreateEventTypeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	req.Name = strings.TrimSpace(strings.ToLower(req.Name))
+	var invalid validationError
 	if req.Name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
-		return
+		invalid.add("name", "name is required")
+	} else if !validNameRe.MatchString(req.Name) {
+		invalid.add("name", "name must contain only lowercase letters, digits, and underscores")
 	}
-	if !validNameRe.MatchString(req.Name) {
-		http.Error(w, "name must contain only lowercase letters, digits, and underscores", http.StatusBadRequest)
+	if len(invalid) > 0 {
+		writeValidationError(w, r, invalid)
 		return
 	}
 
@@ -76,15 +97,16 @@ func (a *App) HandleCreateEventType(w http.ResponseWriter, r *http.Request) {
 		Order:  order,
 	}
 
-	if err := a.eventTypeStore.CreateEventType(et); err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			http.Error(w, err.Error(), http.StatusConflict)
+	if err := a.eventTypeStore.CreateEventType(r.Context(), et); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			writeError(w, r, http.StatusConflict, codeEventTypeExists, fmt.Sprintf("Event type %q already exists", et.Name))
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to create event type: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to create event type")
 		return
 	}
 
+	setETag(w, et.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(et)
@@ -94,15 +116,25 @@ func (a *App) HandleCreateEventType(w http.ResponseWriter, r *http.Request) {
 func (a *App) HandleUpdateEventType(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	et, err := a.eventTypeStore.GetEventType(id)
+	et, err := a.eventTypeStore.GetEventType(r.Context(), id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Event type not found: %v", err), http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventTypeNotFound, "Event type not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event type")
+		return
+	}
+
+	// Reject edits based on an outdated copy of the event type
+	if !ifMatch(r, et.Version) {
+		writePreconditionFailed(w, r, et, et.Version)
 		return
 	}
 
 	var req storage.UpdateEventTypeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
@@ -116,11 +148,18 @@ func (a *App) HandleUpdateEventType(w http.ResponseWriter, r *http.Request) {
 		et.Order = *req.Order
 	}
 
-	if err := a.eventTypeStore.UpdateEventType(et); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update event type: %v", err), http.StatusInternalServerError)
+	if err := a.eventTypeStore.UpdateEventType(r.Context(), et); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			if current, err := a.eventTypeStore.GetEventType(r.Context(), id); err == nil {
+				writePreconditionFailed(w, r, current, current.Version)
+				return
+			}
+		}
+		writeInternalError(w, r, err, "Failed to update event type")
 		return
 	}
 
+	setETag(w, et.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(et)
 }
@@ -129,8 +168,28 @@ func (a *App) HandleUpdateEventType(w http.ResponseWriter, r *http.Request) {
 func (a *App) HandleDeleteEventType(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	if err := a.eventTypeStore.DeleteEventType(id); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete event type: %v", err), http.StatusNotFound)
+	if r.Header.Get("If-Match") != "" {
+		et, err := a.eventTypeStore.GetEventType(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeError(w, r, http.StatusNotFound, codeEventTypeNotFound, "Event type not found")
+				return
+			}
+			writeInternalError(w, r, err, "Failed to get event type")
+			return
+		}
+		if !ifMatch(r, et.Version) {
+			writePreconditionFailed(w, r, et, et.Version)
+			return
+		}
+	}
+
+	if err := a.eventTypeStore.DeleteEventType(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventTypeNotFound, "Event type not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to delete event type")
 		return
 	}
 
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -16,19 +17,19 @@ import (
 func (a *App) HandleCreateEvent(w http.ResponseWriter, r *http.Request) {
 	var req storage.CreateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	// Validate date
 	if req.Date == "" {
-		http.Error(w, "Date is required", http.StatusBadRequest)
+		writeFieldError(w, r, codeValidationFailed, "date", "Date is required")
 		return
 	}
 
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
-		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		writeFieldError(w, r, codeInvalidDate, "date", "Invalid date format, use YYYY-MM-DD")
 		return
 	}
 
@@ -36,9 +37,13 @@ func (a *App) HandleCreateEvent(w http.ResponseWriter, r *http.Request) {
 	if req.Type == "" {
 		req.Type = "morning_lesson" // Default
 	}
-	eventTypeDef, err := a.eventTypeStore.GetEventTypeByName(req.Type)
+	eventTypeDef, err := a.eventTypeStore.GetEventTypeByName(r.Context(), req.Type)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeInternalError(w, r, err, "Failed to get event type")
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid event type: %s", req.Type), http.StatusBadRequest)
+		writeFieldError(w, r, codeUnknownEventType, "type", fmt.Sprintf("Invalid event type: %s", req.Type))
 		return
 	}
 
@@ -84,13 +89,15 @@ func (a *App) HandleCreateEvent(w http.ResponseWriter, r *http.Request) {
 		Order:     order,
 		Titles:    titles,
 		Public:    isPublic,
+		UpdatedBy: actorFromRequest(r),
 	}
 
-	if err := a.eventStore.SaveEvent(event); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to save event: %v", err), http.StatusInternalServerError)
+	if err := a.eventStore.SaveEvent(r.Context(), event); err != nil {
+		writeInternalError(w, r, err, "Failed to save event")
 		return
 	}
 
+	setETag(w, event.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(event)
@@ -101,12 +108,19 @@ func (a *App) HandleGetEvent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	event, err := a.eventStore.GetEvent(id)
+	event, err := a.eventStore.GetEvent(r.Context(), id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Event not found: %v", err), http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
 		return
 	}
 
+	if a.notModified(w, r, versionETag(event.Version), event.LastModified()) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(event)
 }
@@ -179,12 +193,19 @@ func (a *App) HandleListEvents(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Use filtered query
-	events, total, err := a.eventStore.ListEventsFiltered(filter, limit, offset)
+	events, total, err := a.eventStore.ListEventsFiltered(r.Context(), filter, limit, offset)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list events: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to list events")
 		return
 	}
 
+	etag := newListETag(strconv.Itoa(total))
+	for _, e := range events {
+		etag.add("event", e.ID, e.Version)
+	}
+	if a.notModified(w, r, etag.String(), time.Time{}) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"events":   events,
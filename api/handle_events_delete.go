@@ -1,49 +1,74 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
 )
 
-// HandleDeleteEvent deletes an event and all its parts (all languages)
+// HandleDeleteEvent moves an event and all its parts (all languages) to the trash
+// The event and its parts are trashed in one transaction with the same deletion stamp,
+// so they can be restored together.
 func (a *App) HandleDeleteEvent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	eventID := vars["id"]
 
 	// Verify event exists
-	_, err := a.eventStore.GetEvent(eventID)
+	event, err := a.eventStore.GetEvent(r.Context(), eventID)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
 		return
 	}
 
-	// Get all parts for this event
-	allParts, err := a.store.ListParts()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list parts: %v", err), http.StatusInternalServerError)
+	// Reject deletes based on an outdated copy of the event
+	if !ifMatch(r, event.Version) {
+		writePreconditionFailed(w, r, event, event.Version)
 		return
 	}
 
-	// Delete all parts associated with this event
+	deletion := storage.NewDeletion(actorFromRequest(r))
 	deletedParts := 0
-	for _, part := range allParts {
-		if part.EventID == eventID {
-			if err := a.store.DeletePart(part.ID); err != nil {
-				fmt.Printf("Warning: Failed to delete part %s: %v\n", part.ID, err)
-			} else {
-				deletedParts++
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		deletedParts = 0
+
+		if err := checkEventVersion(ctx, tx, r, eventID); err != nil {
+			return err
+		}
+
+		// Get all parts for this event
+		eventParts, _, err := tx.Parts.ListPartsFiltered(ctx, storage.PartQuery{EventID: eventID})
+		if err != nil {
+			return err
+		}
+
+		// Trash all parts associated with this event
+		for _, part := range eventParts {
+			if err := tx.Parts.TrashPart(ctx, part.ID, deletion); err != nil {
+				return err
 			}
+			deletedParts++
 		}
-	}
 
-	// Delete the event
-	if err := a.eventStore.DeleteEvent(eventID); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete event: %v", err), http.StatusInternalServerError)
+		// Trash the event
+		return tx.Events.TrashEvent(ctx, eventID, deletion)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeEventConflict(w, r, eventID)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to delete event, nothing was deleted")
 		return
 	}
 
-	fmt.Printf("Deleted event %s and %d parts\n", eventID, deletedParts)
+	fmt.Printf("Trashed event %s and %d parts\n", eventID, deletedParts)
 	w.WriteHeader(http.StatusNoContent)
 }
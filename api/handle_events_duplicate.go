@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -23,52 +25,74 @@ func (a *App) HandleDuplicateEvent(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req DuplicateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	// Parse new date
 	newDate, err := time.Parse("2006-01-02", req.NewDate)
 	if err != nil {
-		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		writeFieldError(w, r, codeInvalidDate, "new_date", "Invalid date format, use YYYY-MM-DD")
 		return
 	}
 
 	// Get original event
-	originalEvent, err := a.eventStore.GetEvent(eventID)
+	originalEvent, err := a.eventStore.GetEvent(r.Context(), eventID)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
-		return
-	}
-
-	// Create new event with the new date
-	newEvent := &storage.Event{
-		Date:      newDate,
-		StartTime: originalEvent.StartTime, // Copy start time
-		EndTime:   originalEvent.EndTime,   // Copy end time
-		Type:      originalEvent.Type,
-		Number:    originalEvent.Number,
-		Order:     originalEvent.Order,  // Copy order
-		Titles:    originalEvent.Titles, // Copy titles
-		Public:    originalEvent.Public,
-	}
-
-	if err := a.eventStore.SaveEvent(newEvent); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create duplicate event: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
 		return
 	}
 
 	// Get all parts for the original event
-	allParts, err := a.store.ListParts()
+	originalParts, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{EventID: eventID})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list parts: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to list parts")
 		return
 	}
 
-	// Duplicate all parts (all languages)
+	// Create the new event and copy all parts (all languages) in one transaction,
+	// so a failure never leaves a half-copied event behind
+	// Copies of the same translation group form a new group in the new event
+	groupKey := func(part *storage.LessonPart) string {
+		if part.TranslationGroupID != "" {
+			return part.TranslationGroupID
+		}
+		return fmt.Sprintf("order-%d", part.Order)
+	}
+
+	var newEvent *storage.Event
 	duplicatedParts := 0
-	for _, originalPart := range allParts {
-		if originalPart.EventID == eventID {
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		duplicatedParts = 0
+		newGroups := make(map[string]string)
+
+		// Create new event with the new date
+		newEvent = &storage.Event{
+			Date:      newDate,
+			StartTime: originalEvent.StartTime, // Copy start time
+			EndTime:   originalEvent.EndTime,   // Copy end time
+			Type:      originalEvent.Type,
+			Number:    originalEvent.Number,
+			Order:     originalEvent.Order,  // Copy order
+			Titles:    originalEvent.Titles, // Copy titles
+			Public:    originalEvent.Public,
+			UpdatedBy: actorFromRequest(r),
+		}
+
+		if err := tx.Events.SaveEvent(ctx, newEvent); err != nil {
+			return err
+		}
+
+		for _, originalPart := range originalParts {
+			key := groupKey(originalPart)
+			if newGroups[key] == "" {
+				newGroups[key] = storage.NewTranslationGroupID()
+			}
+
 			// Create a copy with new event ID
 			newPart := &storage.LessonPart{
 				Title:                  originalPart.Title,
@@ -78,6 +102,8 @@ func (a *App) HandleDuplicateEvent(w http.ResponseWriter, r *http.Request) {
 				Language:               originalPart.Language,
 				EventID:                newEvent.ID,
 				Order:                  originalPart.Order,
+				TranslationGroupID:     newGroups[key],
+				TranslationStub:        originalPart.TranslationStub,
 				ExcerptsLink:           originalPart.ExcerptsLink,
 				TranscriptLink:         originalPart.TranscriptLink,
 				LessonLink:             originalPart.LessonLink,
@@ -87,14 +113,19 @@ func (a *App) HandleDuplicateEvent(w http.ResponseWriter, r *http.Request) {
 				RecordedLessonDate:     originalPart.RecordedLessonDate,
 				Sources:                originalPart.Sources,
 				CustomLinks:            originalPart.CustomLinks,
+				UpdatedBy:              newEvent.UpdatedBy,
 			}
 
-			if err := a.store.SavePart(newPart); err != nil {
-				fmt.Printf("Warning: Failed to duplicate part %s: %v\n", originalPart.ID, err)
-			} else {
-				duplicatedParts++
+			if err := tx.Parts.SavePart(ctx, newPart); err != nil {
+				return fmt.Errorf("failed to duplicate part %s: %w", originalPart.ID, err)
 			}
+			duplicatedParts++
 		}
+		return nil
+	})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to duplicate event, nothing was created")
+		return
 	}
 
 	fmt.Printf("Duplicated event %s to %s with %d parts\n", eventID, newEvent.ID, duplicatedParts)
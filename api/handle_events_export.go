@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/backup"
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// bundleStores returns the stores event bundles are exported from and imported to
+func (a *App) bundleStores() backup.Stores {
+	return backup.Stores{
+		Parts:      a.store,
+		Events:     a.eventStore,
+		EventTypes: a.eventTypeStore,
+		Templates:  a.templateStore,
+		Transactor: a.transactor,
+	}
+}
+
+// HandleExportEvent returns an event with all its parts, its event type and the
+// templates its parts use as a bundle for POST /api/events/import
+func (a *App) HandleExportEvent(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Draft events are only exported for trusted requests
+	event, err := a.eventStore.GetEvent(r.Context(), id)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+	if err != nil || (!event.Public && !a.isTrustedRequest(r)) {
+		writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+		return
+	}
+
+	bundle, err := backup.ExportEvent(r.Context(), a.bundleStores(), id)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to export event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%s.json"`, id))
+	json.NewEncoder(w).Encode(bundle)
+}
+
+// HandleImportEvent recreates an exported event bundle with fresh IDs
+// Query parameters:
+//   - date (string): move the event and its parts to this date (YYYY-MM-DD)
+//   - event_type (string): import as this existing event type instead of the bundle's
+//   - missing_event_type (string): "fail" (default) answers 409 if the event type
+//     doesn't exist, "create" creates it from the bundle
+func (a *App) HandleImportEvent(w http.ResponseWriter, r *http.Request) {
+	var bundle backup.EventBundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+	if err := backup.ValidateBundle(&bundle); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidBundle, fmt.Sprintf("Invalid bundle: %v", err))
+		return
+	}
+
+	opts := backup.ImportOptions{
+		EventType: r.URL.Query().Get("event_type"),
+		UpdatedBy: actorFromRequest(r),
+	}
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "date", "Invalid date format, use YYYY-MM-DD")
+			return
+		}
+		opts.Date = &date
+	}
+	policy, err := backup.ParseMissingEventType(r.URL.Query().Get("missing_event_type"))
+	if err != nil {
+		writeFieldError(w, r, codeInvalidParameter, "missing_event_type", err.Error())
+		return
+	}
+	opts.MissingEventType = policy
+
+	result, err := backup.ImportEvent(r.Context(), a.bundleStores(), &bundle, opts)
+	if err != nil {
+		if errors.Is(err, backup.ErrEventTypeMissing) {
+			writeError(w, r, http.StatusConflict, codeUnknownEventType, fmt.Sprintf("%v; pass missing_event_type=create or event_type=<existing type>", err))
+			return
+		}
+		writeInternalError(w, r, err, "Failed to import event")
+		return
+	}
+
+	// Pick up templates the import added
+	if len(result.TemplatesAdded) > 0 {
+		if config, err := a.templateStore.GetConfig(r.Context()); err == nil {
+			a.templateConfig = config
+		}
+	}
+
+	fmt.Printf("Imported event %s as %s with %d parts\n", bundle.Event.ID, result.Event.ID, result.PartsCreated)
+
+	setETag(w, result.Event.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
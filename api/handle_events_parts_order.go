@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// ReorderPartsRequest is the new sequence of an event's parts
+type ReorderPartsRequest struct {
+	Parts []string `json:"parts"` // Part or translation group IDs, first to last
+}
+
+// PartOrderResponse lists an event's parts in order with the order of each language
+type PartOrderResponse struct {
+	EventID string            `json:"event_id"`
+	Groups  []*PartOrderGroup `json:"groups"`
+	Drifted bool              `json:"drifted"`           // Some languages are out of order
+	Updated int               `json:"updated,omitempty"` // Parts changed by a reorder or repair
+}
+
+// PartOrderGroup is one part of the event in all its languages
+type PartOrderGroup struct {
+	TranslationGroupID string         `json:"translation_group_id,omitempty"`
+	PartID             string         `json:"part_id"` // The Hebrew part, or the first language there is
+	Title              string         `json:"title"`
+	Order              int            `json:"order"`
+	Drifted            bool           `json:"drifted"`
+	Languages          map[string]int `json:"languages"` // Order of each language version
+}
+
+// HandleGetEventPartOrder lists an event's parts in order and reports languages
+// that have drifted out of it
+func (a *App) HandleGetEventPartOrder(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["id"]
+	if _, err := a.eventStore.GetEvent(r.Context(), eventID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+
+	parts, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{EventID: eventID})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list parts")
+		return
+	}
+
+	writePartOrder(w, eventID, storage.GroupEventParts(parts), 0)
+}
+
+// HandleReorderEventParts sets the order of an event's parts in every language
+// in one transaction. The body lists every part except the preparation part,
+// which keeps order 0, by part or translation group ID.
+func (a *App) HandleReorderEventParts(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["id"]
+
+	var req ReorderPartsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+
+	a.updatePartOrder(w, r, eventID, func(ctx context.Context, tx storage.Tx) ([]*storage.PartGroup, int, error) {
+		return storage.ReorderEventParts(ctx, tx, eventID, req.Parts, actorFromRequest(r))
+	})
+}
+
+// HandleRepairEventPartOrder moves every language version of an event's parts to
+// the order of the Hebrew version
+func (a *App) HandleRepairEventPartOrder(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["id"]
+
+	a.updatePartOrder(w, r, eventID, func(ctx context.Context, tx storage.Tx) ([]*storage.PartGroup, int, error) {
+		return storage.RepairEventPartOrder(ctx, tx, eventID, actorFromRequest(r))
+	})
+}
+
+// updatePartOrder runs a reorder of the event's parts in a transaction and writes the result
+func (a *App) updatePartOrder(w http.ResponseWriter, r *http.Request, eventID string, reorder func(ctx context.Context, tx storage.Tx) ([]*storage.PartGroup, int, error)) {
+	if _, err := a.eventStore.GetEvent(r.Context(), eventID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+
+	var groups []*storage.PartGroup
+	var updated int
+	var invalid error
+	err := a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		var err error
+		groups, updated, err = reorder(ctx, tx)
+		if errors.Is(err, storage.ErrInvalidPartOrder) {
+			invalid = err
+		}
+		return err
+	})
+	if err != nil {
+		if invalid != nil {
+			writeError(w, r, http.StatusBadRequest, codeInvalidPartOrder, invalid.Error())
+			return
+		}
+		if errors.Is(err, storage.ErrVersionConflict) {
+			writeError(w, r, http.StatusPreconditionFailed, codeVersionConflict, "A part was changed by someone else, try again")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to reorder parts, nothing was changed")
+		return
+	}
+
+	writePartOrder(w, eventID, groups, updated)
+}
+
+func writePartOrder(w http.ResponseWriter, eventID string, groups []*storage.PartGroup, updated int) {
+	response := PartOrderResponse{
+		EventID: eventID,
+		Groups:  make([]*PartOrderGroup, 0, len(groups)),
+		Updated: updated,
+	}
+	for _, g := range groups {
+		reference := g.Reference()
+		group := &PartOrderGroup{
+			TranslationGroupID: reference.TranslationGroupID,
+			PartID:             reference.ID,
+			Title:              reference.Title,
+			Order:              g.Order,
+			Drifted:            g.Drifted(),
+			Languages:          make(map[string]int, len(g.Parts)),
+		}
+		for _, p := range g.Parts {
+			group.Languages[p.Language] = p.Order
+		}
+		response.Drifted = response.Drifted || group.Drifted
+		response.Groups = append(response.Groups, group)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/Bnei-Baruch/study-material-service/storage/memory"
+)
+
+// newOrderStore returns a store with an event whose preparation part and two
+// parts are in Hebrew and English; the English version of p1 drifted to order 2
+func newOrderStore(t *testing.T) *memory.Store {
+	t.Helper()
+	s := newTestStore(t)
+	date := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	s.Seed([]*storage.LessonPart{
+		{ID: "prep", EventID: "e1", Language: "he", Order: 0, Date: date, Title: "Preparation", PartType: "live_lesson", TranslationGroupID: "g0", Version: 1},
+		{ID: "he1", EventID: "e1", Language: "he", Order: 1, Date: date, Title: "First", PartType: "live_lesson", TranslationGroupID: "g1", Version: 1},
+		{ID: "en1", EventID: "e1", Language: "en", Order: 2, Date: date, Title: "First", PartType: "live_lesson", TranslationGroupID: "g1", Version: 1},
+		{ID: "he2", EventID: "e1", Language: "he", Order: 2, Date: date, Title: "Second", PartType: "live_lesson", TranslationGroupID: "g2", Version: 1},
+		{ID: "en2", EventID: "e1", Language: "en", Order: 2, Date: date, Title: "Second", PartType: "live_lesson", TranslationGroupID: "g2", Version: 1},
+	}, []*storage.Event{{ID: "e1", Date: date, Type: "morning_lesson", Number: 1}}, nil, nil, nil)
+	return s
+}
+
+func partOrders(t *testing.T, s *memory.Store) map[string]int {
+	t.Helper()
+	parts, _, err := s.ListPartsFiltered(context.Background(), storage.PartQuery{EventID: "e1"})
+	if err != nil {
+		t.Fatalf("ListPartsFiltered: %v", err)
+	}
+	orders := make(map[string]int, len(parts))
+	for _, p := range parts {
+		orders[p.ID] = p.Order
+	}
+	return orders
+}
+
+func TestReorderEventParts(t *testing.T) {
+	s := newOrderStore(t)
+	h := serveStore(s, s)
+	rec := serve(t, h, http.MethodPut, "/api/events/e1/parts/order", `{"parts":["g2","he1"]}`, map[string]string{"X-API-Key": testAPIKey})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp PartOrderResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Drifted || resp.Updated != 3 {
+		t.Errorf("drifted %v, updated %d, want false and 3", resp.Drifted, resp.Updated)
+	}
+	want := map[string]int{"prep": 0, "he2": 1, "en2": 1, "he1": 2, "en1": 2}
+	for id, order := range partOrders(t, s) {
+		if order != want[id] {
+			t.Errorf("%s at order %d, want %d", id, order, want[id])
+		}
+	}
+}
+
+func TestReorderEventPartsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"duplicate", `{"parts":["he1","en1","he2"]}`},
+		{"missing", `{"parts":["he1"]}`},
+		{"unknown", `{"parts":["he1","he2","other"]}`},
+		{"preparation not first", `{"parts":["he1","prep","he2"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newOrderStore(t)
+			rec := serve(t, serveStore(s, s), http.MethodPut, "/api/events/e1/parts/order", tt.body, map[string]string{"X-API-Key": testAPIKey})
+			if rec.Code != http.StatusBadRequest || errorCode(t, rec) != codeInvalidPartOrder {
+				t.Errorf("status %d, body %s", rec.Code, rec.Body.String())
+			}
+			if orders := partOrders(t, s); orders["he1"] != 1 || orders["en1"] != 2 {
+				t.Errorf("orders changed by a rejected reorder: %v", orders)
+			}
+		})
+	}
+}
+
+// stalePartStore reads every part one version behind, as if someone saved it
+// again right after it was read
+type stalePartStore struct {
+	storage.PartStore
+}
+
+func (s stalePartStore) ListPartsFiltered(ctx context.Context, query storage.PartQuery) ([]*storage.LessonPart, int, error) {
+	parts, total, err := s.PartStore.ListPartsFiltered(ctx, query)
+	for _, p := range parts {
+		p.Version--
+	}
+	return parts, total, err
+}
+
+type staleTransactor struct {
+	*memory.Store
+}
+
+func (t staleTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context, tx storage.Tx) error) error {
+	return t.Store.RunInTransaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		return fn(ctx, storage.Tx{Parts: stalePartStore{tx.Parts}, Events: tx.Events})
+	})
+}
+
+func TestReorderEventPartsConflict(t *testing.T) {
+	s := newOrderStore(t)
+	rec := serve(t, serveStore(s, staleTransactor{s}), http.MethodPut, "/api/events/e1/parts/order", `{"parts":["he2","he1"]}`, map[string]string{"X-API-Key": testAPIKey})
+	if rec.Code != http.StatusPreconditionFailed || errorCode(t, rec) != codeVersionConflict {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if orders := partOrders(t, s); orders["he1"] != 1 || orders["he2"] != 2 {
+		t.Errorf("orders changed by a conflicting reorder: %v", orders)
+	}
+}
+
+func TestRepairEventPartOrder(t *testing.T) {
+	s := newOrderStore(t)
+	rec := serve(t, serveStore(s, s), http.MethodPost, "/api/events/e1/parts/order/repair", "", map[string]string{"X-API-Key": testAPIKey})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp PartOrderResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Drifted || resp.Updated != 1 {
+		t.Errorf("drifted %v, updated %d, want false and 1", resp.Drifted, resp.Updated)
+	}
+	if orders := partOrders(t, s); orders["en1"] != 1 {
+		t.Errorf("en1 at order %d after repair, want 1", orders["en1"])
+	}
+}
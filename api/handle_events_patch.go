@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// eventImmutableFields can't be changed by a PATCH
+var eventImmutableFields = []string{"id", "type", "created_at"}
+
+// HandlePatchEvent applies a JSON Merge Patch or JSON Patch to an event, so
+// clients only send the fields they change
+func (a *App) HandlePatchEvent(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID := vars["id"]
+
+	existingEvent, err := a.eventStore.GetEvent(r.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+
+	// Reject edits based on an outdated copy of the event
+	if !ifMatch(r, existingEvent.Version) {
+		writePreconditionFailed(w, r, existingEvent, existingEvent.Version)
+		return
+	}
+
+	var event storage.Event
+	if err := applyPatch(r, existingEvent, &event, eventImmutableFields); err != nil {
+		writePatchError(w, r, err)
+		return
+	}
+
+	if event.Number < 1 {
+		writeFieldError(w, r, codeValidationFailed, "number", "Number must be at least 1")
+		return
+	}
+
+	// Fields the server maintains aren't patchable
+	event.Version = existingEvent.Version
+	event.EmailSentAt = existingEvent.EmailSentAt
+	event.DeletedAt = existingEvent.DeletedAt
+	event.DeletedBy = existingEvent.DeletedBy
+	event.UpdatedBy = actorFromRequest(r)
+
+	if err := a.eventStore.SaveEvent(r.Context(), &event); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeEventConflict(w, r, eventID)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to update event")
+		return
+	}
+
+	setETag(w, event.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&event)
+}
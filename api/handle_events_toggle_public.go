@@ -2,9 +2,10 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
+	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -19,29 +20,44 @@ func (a *App) HandleToggleEventPublic(w http.ResponseWriter, r *http.Request) {
 	eventId := vars["id"]
 
 	// Get existing event
-	event, err := a.eventStore.GetEvent(eventId)
+	event, err := a.eventStore.GetEvent(r.Context(), eventId)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+
+	if !ifMatch(r, event.Version) {
+		writePreconditionFailed(w, r, event, event.Version)
 		return
 	}
 
 	// Parse request body
 	var req TogglePublicRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	// Update public status
 	event.Public = req.Public
+	event.UpdatedBy = actorFromRequest(r)
 
 	// Save updated event
-	if err := a.eventStore.SaveEvent(event); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update event: %v", err), http.StatusInternalServerError)
+	if err := a.eventStore.SaveEvent(r.Context(), event); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeEventConflict(w, r, eventId)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to update event")
 		return
 	}
 
 	// Return updated event
+	setETag(w, event.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(event)
 }
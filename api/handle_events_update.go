@@ -2,10 +2,11 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -25,16 +26,26 @@ func (a *App) HandleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 	eventId := vars["id"]
 
 	// Get existing event
-	event, err := a.eventStore.GetEvent(eventId)
+	event, err := a.eventStore.GetEvent(r.Context(), eventId)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+
+	// Reject edits based on an outdated copy of the event
+	if !ifMatch(r, event.Version) {
+		writePreconditionFailed(w, r, event, event.Version)
 		return
 	}
 
 	// Parse request body
 	var req UpdateEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
@@ -57,7 +68,7 @@ func (a *App) HandleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 	if req.Date != nil {
 		parsedDate, err := time.Parse("2006-01-02", *req.Date)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Invalid date format: %v", err), http.StatusBadRequest)
+			writeFieldError(w, r, codeInvalidDate, "date", "Invalid date format, use YYYY-MM-DD")
 			return
 		}
 		event.Date = parsedDate
@@ -83,13 +94,20 @@ func (a *App) HandleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 		event.Public = *req.Public
 	}
 
+	event.UpdatedBy = actorFromRequest(r)
+
 	// Save updated event
-	if err := a.eventStore.SaveEvent(event); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update event: %v", err), http.StatusInternalServerError)
+	if err := a.eventStore.SaveEvent(r.Context(), event); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeEventConflict(w, r, eventId)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to update event")
 		return
 	}
 
 	// Return updated event
+	setETag(w, event.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(event)
 }
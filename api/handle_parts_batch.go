@@ -0,0 +1,369 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// maxBatchOperations bounds the number of operations in one batch request
+const maxBatchOperations = 500
+
+// BatchRequest is an ordered list of part operations
+type BatchRequest struct {
+	Operations      []BatchOperation `json:"operations"`
+	ContinueOnError bool             `json:"continue_on_error"` // Apply the operations that succeed instead of none
+}
+
+// BatchOperation creates, updates or deletes one part
+type BatchOperation struct {
+	Op      string                     `json:"op"`                // "create", "update" or "delete"
+	ID      string                     `json:"id,omitempty"`      // Part to update or delete
+	Version *int                       `json:"version,omitempty"` // Optional: only apply to this version of the part, like If-Match
+	Part    *storage.CreatePartRequest `json:"part,omitempty"`    // Body of a create, or all fields of an update like PUT
+}
+
+// BatchResult is the outcome of one operation
+type BatchResult struct {
+	Index          int                 `json:"index"`
+	Op             string              `json:"op"`
+	Status         int                 `json:"status"` // Status the single-part endpoint would have answered
+	ID             string              `json:"id,omitempty"`
+	Part           *storage.LessonPart `json:"part,omitempty"`            // The created or updated part
+	TranslationIDs []string            `json:"translation_ids,omitempty"` // Created stubs, or trashed translations
+	Error          *ErrorBody          `json:"error,omitempty"`
+}
+
+// BatchResponse lists the result of every operation in request order
+type BatchResponse struct {
+	Results   []*BatchResult `json:"results"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+}
+
+// batchError is an operation failure with the status and code it is reported with
+type batchError struct {
+	status  int
+	code    string
+	msg     string
+	details []FieldError
+}
+
+func (e *batchError) Error() string {
+	return e.msg
+}
+
+func newBatchError(status int, code, format string, args ...interface{}) error {
+	return &batchError{status: status, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// batchStep is a validated operation ready to run
+type batchStep struct {
+	op     BatchOperation
+	result *BatchResult
+	part   *storage.LessonPart   // create: the new part
+	stubs  []*storage.LessonPart // create: its translation stubs
+}
+
+// HandleBatchParts creates, updates and deletes many parts in one request.
+// Every operation is validated before anything is written. By default the
+// operations run in one transaction and the first failure rolls all of them
+// back; with continue_on_error each operation runs in its own transaction and
+// the ones that succeed are kept.
+func (a *App) HandleBatchParts(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeFieldError(w, r, codeValidationFailed, "operations", "At least one operation is required")
+		return
+	}
+	if len(req.Operations) > maxBatchOperations {
+		writeFieldError(w, r, codeValidationFailed, "operations", fmt.Sprintf("Too many operations, the maximum is %d", maxBatchOperations))
+		return
+	}
+
+	actor := actorFromRequest(r)
+	results := make([]*BatchResult, len(req.Operations))
+	var steps []*batchStep
+	events := make(map[string]error)
+	for i, op := range req.Operations {
+		results[i] = &BatchResult{Index: i, Op: op.Op, ID: op.ID}
+		step := &batchStep{op: op, result: results[i]}
+		if err := a.validateBatchOperation(r.Context(), step, actor, events); err != nil {
+			failBatchResult(r, results[i], err)
+			continue
+		}
+		steps = append(steps, step)
+	}
+
+	failed := firstFailure(results)
+	if failed != nil && !req.ContinueOnError {
+		skipBatchResults(results, failed)
+		writeBatchResponse(w, failed.Status, results)
+		return
+	}
+
+	// Look up the translated source titles before any transaction is opened,
+	// once for every source the new parts share
+	titles := a.newSourceTitles()
+	for _, step := range steps {
+		if step.op.Op == "create" {
+			step.stubs = a.translationStubs(r.Context(), step.part, step.op.Part.TemplateID, titles)
+		}
+	}
+
+	if req.ContinueOnError {
+		for _, step := range steps {
+			err := a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+				return runBatchStep(ctx, tx, step, actor)
+			})
+			if err != nil {
+				clearBatchResult(step)
+				failBatchResult(r, step.result, err)
+			}
+		}
+		status := http.StatusOK
+		if firstFailure(results) != nil {
+			status = http.StatusMultiStatus
+		}
+		writeBatchResponse(w, status, results)
+		return
+	}
+
+	var failedStep *batchStep
+	err := a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		failedStep = nil
+		for _, step := range steps {
+			if err := runBatchStep(ctx, tx, step, actor); err != nil {
+				failedStep = step
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Nothing was written: report the failure on its operation, or on the
+		// batch as a whole if the transaction itself failed to commit
+		for _, step := range steps {
+			clearBatchResult(step)
+		}
+		if failedStep == nil {
+			writeInternalError(w, r, err, "Failed to apply batch, nothing was changed")
+			return
+		}
+		failBatchResult(r, failedStep.result, err)
+		skipBatchResults(results, failedStep.result)
+		writeBatchResponse(w, failedStep.result.Status, results)
+		return
+	}
+
+	writeBatchResponse(w, http.StatusOK, results)
+}
+
+// validateBatchOperation checks an operation against the current data before
+// anything is written. events caches which event IDs exist.
+func (a *App) validateBatchOperation(ctx context.Context, step *batchStep, actor string, events map[string]error) error {
+	op := step.op
+	switch op.Op {
+	case "create":
+		if op.Part == nil {
+			return newBatchError(http.StatusBadRequest, codeValidationFailed, "part is required")
+		}
+		part, invalid := newPartFromRequest(op.Part, actor)
+		if invalid != nil {
+			return &batchError{status: http.StatusBadRequest, code: codeValidationFailed, msg: invalid.Error(), details: invalid}
+		}
+		if part.EventID != "" {
+			err, checked := events[part.EventID]
+			if !checked {
+				_, err = a.eventStore.GetEvent(ctx, part.EventID)
+				events[part.EventID] = err
+			}
+			if errors.Is(err, storage.ErrNotFound) {
+				return newBatchError(http.StatusNotFound, codeEventNotFound, "Event not found")
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get event: %w", err)
+			}
+		}
+		step.part = part
+		return nil
+
+	case "update", "delete":
+		if op.ID == "" {
+			return newBatchError(http.StatusBadRequest, codeValidationFailed, "id is required")
+		}
+		if op.Op == "update" {
+			if op.Part == nil {
+				return newBatchError(http.StatusBadRequest, codeValidationFailed, "part is required")
+			}
+			if op.Part.Title == "" {
+				return newBatchError(http.StatusBadRequest, codeValidationFailed, "Title is required")
+			}
+			if op.Part.Date != "" {
+				if _, err := time.Parse("2006-01-02", op.Part.Date); err != nil {
+					return newBatchError(http.StatusBadRequest, codeInvalidDate, "Invalid date format, use YYYY-MM-DD")
+				}
+			}
+		}
+		existing, err := a.store.GetPart(ctx, op.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return newBatchError(http.StatusNotFound, codePartNotFound, "Part not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get part: %w", err)
+		}
+		if op.Version != nil && *op.Version != existing.Version {
+			return newBatchError(http.StatusPreconditionFailed, codeVersionConflict, "The part is at version %d", existing.Version)
+		}
+		return nil
+
+	default:
+		return newBatchError(http.StatusBadRequest, codeValidationFailed, "Invalid op %q, must be 'create', 'update' or 'delete'", op.Op)
+	}
+}
+
+// runBatchStep applies one validated operation inside a transaction. Parts are
+// read again so earlier operations of the batch are taken into account.
+func runBatchStep(ctx context.Context, tx storage.Tx, step *batchStep, actor string) error {
+	op := step.op
+	step.result.TranslationIDs = nil
+	if op.Op == "create" {
+		// Save copies, so an attempt that is rolled back and run again starts
+		// from the validated part instead of what the failed attempt stored
+		part := *step.part
+		if err := tx.Parts.SavePart(ctx, &part); err != nil {
+			return err
+		}
+		for _, s := range step.stubs {
+			stub := *s
+			if err := tx.Parts.SavePart(ctx, &stub); err != nil {
+				return fmt.Errorf("failed to create %s translation stub: %w", stub.Language, err)
+			}
+			step.result.TranslationIDs = append(step.result.TranslationIDs, stub.ID)
+		}
+		step.result.Status = http.StatusCreated
+		step.result.ID = part.ID
+		step.result.Part = &part
+		return nil
+	}
+
+	part, err := tx.Parts.GetPart(ctx, op.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return newBatchError(http.StatusNotFound, codePartNotFound, "Part not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get part: %w", err)
+	}
+	if op.Version != nil && *op.Version != part.Version {
+		return fmt.Errorf("part %s: %w", op.ID, storage.ErrVersionConflict)
+	}
+
+	if op.Op == "update" {
+		orderChanged := applyPartUpdate(part, op.Part, actor)
+		if err := tx.Parts.SavePart(ctx, part); err != nil {
+			return err
+		}
+		if orderChanged && part.TranslationGroupID != "" {
+			if err := syncTranslationOrder(ctx, tx, part); err != nil {
+				return err
+			}
+		}
+		step.result.Status = http.StatusOK
+		step.result.Part = part
+		return nil
+	}
+
+	// Like DELETE /api/parts/{id}, a Hebrew part takes its translations with it
+	targets := []*storage.LessonPart{part}
+	if part.Language == "he" && (part.TranslationGroupID != "" || part.EventID != "") {
+		targets, _, err = tx.Parts.ListPartsFiltered(ctx, storage.TranslationsQuery(part))
+		if err != nil {
+			return err
+		}
+	}
+	deletion := storage.NewDeletion(actor)
+	for _, p := range targets {
+		if err := tx.Parts.TrashPart(ctx, p.ID, deletion); err != nil {
+			return err
+		}
+		if p.ID != part.ID {
+			step.result.TranslationIDs = append(step.result.TranslationIDs, p.ID)
+		}
+	}
+	step.result.Status = http.StatusNoContent
+	return nil
+}
+
+// failBatchResult records an operation's error with the status it maps to.
+// Internal errors are logged, and reported without their details.
+func failBatchResult(r *http.Request, result *BatchResult, err error) {
+	var be *batchError
+	switch {
+	case errors.As(err, &be):
+		result.Status = be.status
+		result.Error = &ErrorBody{Code: be.code, Message: be.msg, Details: be.details}
+	case errors.Is(err, storage.ErrVersionConflict):
+		result.Status = http.StatusPreconditionFailed
+		result.Error = &ErrorBody{Code: codeVersionConflict, Message: "The part was changed by someone else"}
+	default:
+		log.Printf("[API] %s %s %s: operation %d failed: %v", requestID(r), r.Method, r.URL.Path, result.Index, err)
+		result.Status = http.StatusInternalServerError
+		result.Error = &ErrorBody{Code: codeInternal, Message: fmt.Sprintf("Failed to %s part", result.Op)}
+	}
+}
+
+// clearBatchResult forgets what a rolled back operation wrote
+func clearBatchResult(step *batchStep) {
+	step.result.Status = 0
+	step.result.Part = nil
+	step.result.TranslationIDs = nil
+	if step.op.Op == "create" {
+		step.result.ID = ""
+	}
+}
+
+// skipBatchResults marks every operation but the failed one as not applied
+func skipBatchResults(results []*BatchResult, failed *BatchResult) {
+	for _, result := range results {
+		if result == failed || result.Error != nil {
+			continue
+		}
+		result.Status = http.StatusFailedDependency
+		result.Error = &ErrorBody{Code: codeNotApplied, Message: fmt.Sprintf("Not applied because operation %d failed", failed.Index)}
+	}
+}
+
+// firstFailure returns the first failed result, or nil
+func firstFailure(results []*BatchResult) *BatchResult {
+	for _, result := range results {
+		if result.Error != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+func writeBatchResponse(w http.ResponseWriter, status int, results []*BatchResult) {
+	response := BatchResponse{Results: results}
+	for _, result := range results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/Bnei-Baruch/study-material-service/storage/memory"
+)
+
+// newBatchStore returns a store with one event and its Hebrew part p1
+func newBatchStore(t *testing.T) *memory.Store {
+	t.Helper()
+	s := newTestStore(t)
+	date := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	s.Seed([]*storage.LessonPart{
+		{ID: "p1", EventID: "e1", Language: "he", Order: 1, Date: date, Title: "Part", PartType: "live_lesson", Version: 1},
+	}, []*storage.Event{{ID: "e1", Date: date, Type: "morning_lesson", Number: 1}}, nil, nil, nil)
+	return s
+}
+
+const batchCreate = `{"op":"create","part":{"title":"New","part_type":"live_lesson","language":"he","event_id":"e1","date":"2026-03-01","order":2}}`
+
+func postBatch(t *testing.T, h http.Handler, body string) (int, BatchResponse) {
+	t.Helper()
+	rec := serve(t, h, http.MethodPost, "/api/parts/batch", body, map[string]string{"X-API-Key": testAPIKey})
+	// A request rejected as a whole answers a plain error, which has no results
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding batch response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+// batchOutcome is the status and error code of one result
+type batchOutcome struct {
+	status int
+	code   string
+}
+
+func resultStatuses(resp BatchResponse) []batchOutcome {
+	var got []batchOutcome
+	for _, result := range resp.Results {
+		outcome := batchOutcome{status: result.Status}
+		if result.Error != nil {
+			outcome.code = result.Error.Code
+		}
+		got = append(got, outcome)
+	}
+	return got
+}
+
+func countParts(t *testing.T, s *memory.Store) int {
+	t.Helper()
+	_, total, err := s.ListPartsFiltered(context.Background(), storage.PartQuery{EventID: "e1"})
+	if err != nil {
+		t.Fatalf("ListPartsFiltered: %v", err)
+	}
+	return total
+}
+
+func TestBatchValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   []batchOutcome
+	}{
+		{"no operations", `{"operations":[]}`, http.StatusBadRequest, nil},
+		{"unknown op", `{"operations":[` + batchCreate + `,{"op":"rename","id":"p1"}]}`, http.StatusBadRequest,
+			[]batchOutcome{{http.StatusFailedDependency, codeNotApplied}, {http.StatusBadRequest, codeValidationFailed}}},
+		{"missing part", `{"operations":[{"op":"create"},` + batchCreate + `]}`, http.StatusBadRequest,
+			[]batchOutcome{{http.StatusBadRequest, codeValidationFailed}, {http.StatusFailedDependency, codeNotApplied}}},
+		{"unknown part", `{"operations":[` + batchCreate + `,{"op":"delete","id":"missing"}]}`, http.StatusNotFound,
+			[]batchOutcome{{http.StatusFailedDependency, codeNotApplied}, {http.StatusNotFound, codePartNotFound}}},
+		{"outdated version", `{"operations":[{"op":"delete","id":"p1","version":7}]}`, http.StatusPreconditionFailed,
+			[]batchOutcome{{http.StatusPreconditionFailed, codeVersionConflict}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newBatchStore(t)
+			status, resp := postBatch(t, serveStore(s, s), tt.body)
+			if status != tt.status {
+				t.Errorf("status %d, want %d", status, tt.status)
+			}
+			if got := resultStatuses(resp); tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results %v, want %v", got, tt.want)
+			}
+			if n := countParts(t, s); n != 1 {
+				t.Errorf("%d parts after a rejected batch, want 1", n)
+			}
+		})
+	}
+}
+
+// TestBatchRollback fails the last operation only once it runs: the part was
+// trashed by the operation before it. Nothing of the batch may be kept.
+func TestBatchRollback(t *testing.T) {
+	s := newBatchStore(t)
+	body := `{"operations":[` + batchCreate + `,{"op":"delete","id":"p1"},{"op":"update","id":"p1","part":{"title":"Changed","part_type":"live_lesson","language":"he","date":"2026-03-01","order":1}}]}`
+	status, resp := postBatch(t, serveStore(s, s), body)
+	if status != http.StatusNotFound {
+		t.Errorf("status %d, want %d", status, http.StatusNotFound)
+	}
+	want := []batchOutcome{{http.StatusFailedDependency, codeNotApplied}, {http.StatusFailedDependency, codeNotApplied}, {http.StatusNotFound, codePartNotFound}}
+	if got := resultStatuses(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("results %v, want %v", got, want)
+	}
+	if resp.Results[0].ID != "" || resp.Results[0].Part != nil {
+		t.Errorf("rolled back create reports part %q", resp.Results[0].ID)
+	}
+	if n := countParts(t, s); n != 1 {
+		t.Errorf("%d parts after a rolled back batch, want 1", n)
+	}
+	if _, err := s.GetPart(context.Background(), "p1"); err != nil {
+		t.Errorf("p1 after a rolled back delete: %v", err)
+	}
+}
+
+func TestBatchContinueOnError(t *testing.T) {
+	s := newBatchStore(t)
+	body := `{"continue_on_error":true,"operations":[` + batchCreate + `,{"op":"delete","id":"missing"},{"op":"update","id":"p1","version":1,"part":{"title":"Changed","part_type":"live_lesson","language":"he","date":"2026-03-01","order":1}}]}`
+	status, resp := postBatch(t, serveStore(s, s), body)
+	if status != http.StatusMultiStatus {
+		t.Errorf("status %d, want %d", status, http.StatusMultiStatus)
+	}
+	want := []batchOutcome{{http.StatusCreated, ""}, {http.StatusNotFound, codePartNotFound}, {http.StatusOK, ""}}
+	if got := resultStatuses(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("results %v, want %v", got, want)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 1 {
+		t.Errorf("%d succeeded, %d failed, want 2 and 1", resp.Succeeded, resp.Failed)
+	}
+	if n := countParts(t, s); n != 2 {
+		t.Errorf("%d parts, want 2", n)
+	}
+	part, err := s.GetPart(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if part.Title != "Changed" || part.Version != 2 {
+		t.Errorf("p1 %q at version %d, want \"Changed\" at version 2", part.Title, part.Version)
+	}
+}
+
+// TestBatchRetried runs the batch's unit of work twice: the created part must
+// be inserted once, at version 1
+func TestBatchRetried(t *testing.T) {
+	s := newBatchStore(t)
+	status, resp := postBatch(t, serveStore(s, retryingTransactor{s}), `{"operations":[`+batchCreate+`]}`)
+	if status != http.StatusOK {
+		t.Fatalf("status %d, want %d: %v", status, http.StatusOK, resultStatuses(resp))
+	}
+	created := resp.Results[0].Part
+	if created == nil || created.Version != 1 {
+		t.Fatalf("created part %+v, want version 1", created)
+	}
+	if n := countParts(t, s); n != 2 {
+		t.Errorf("%d parts, want 2", n)
+	}
+	stored, err := s.GetPart(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if stored.Version != 1 {
+		t.Errorf("stored part at version %d, want 1", stored.Version)
+	}
+	if _, err := s.GetRevision(context.Background(), storage.RevisionTypePart, created.ID, 2); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("created part has a second revision: %v", err)
+	}
+}
@@ -1,52 +1,85 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
 )
 
-// HandleDeletePart deletes a lesson part by ID
-// If the part is Hebrew (he), it deletes all translations (all languages with same event_id and order)
+// HandleDeletePart moves a lesson part to the trash
+// If the part is Hebrew (he), it trashes all translations (every part in its translation group)
+// in one transaction with the same deletion stamp, so they can be restored together.
 func (a *App) HandleDeletePart(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Get the part to check its language, event_id, and order
-	part, err := a.store.GetPart(id)
+	// Get the part to check its language and translation group
+	part, err := a.store.GetPart(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Part not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
 		return
 	}
 
-	// If it's Hebrew, delete all translations (same event_id and order)
-	if part.Language == "he" && part.EventID != "" {
-		// Get all parts for this event
-		allParts, err := a.store.ListParts()
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to list parts: %v", err), http.StatusInternalServerError)
-			return
+	// Reject deletes based on an outdated copy of the part
+	if !ifMatch(r, part.Version) {
+		writePreconditionFailed(w, r, part, part.Version)
+		return
+	}
+
+	deletion := storage.NewDeletion(actorFromRequest(r))
+
+	// If it's Hebrew, trash all translations
+	cascade := part.Language == "he" && (part.TranslationGroupID != "" || part.EventID != "")
+
+	deletedCount := 0
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		deletedCount = 0
+
+		if err := checkPartVersion(ctx, tx, r, id); err != nil {
+			return err
 		}
 
-		// Find and delete all parts with same event_id and order
-		deletedCount := 0
-		for _, p := range allParts {
-			if p.EventID == part.EventID && p.Order == part.Order {
-				if err := a.store.DeletePart(p.ID); err != nil {
-					fmt.Printf("Warning: Failed to delete part %s: %v\n", p.ID, err)
-				} else {
-					deletedCount++
-				}
+		targets := []*storage.LessonPart{part}
+		if cascade {
+			// Find all parts in the same translation group
+			translations, _, err := tx.Parts.ListPartsFiltered(ctx, storage.TranslationsQuery(part))
+			if err != nil {
+				return err
 			}
+			targets = translations
 		}
-		fmt.Printf("Deleted Hebrew part and %d translations (total %d parts)\n", deletedCount-1, deletedCount)
-	} else {
-		// For non-Hebrew parts, just delete the single part
-		if err := a.store.DeletePart(id); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to delete part: %v", err), http.StatusInternalServerError)
+
+		for _, p := range targets {
+			if err := tx.Parts.TrashPart(ctx, p.ID, deletion); err != nil {
+				return err
+			}
+			deletedCount++
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writePartConflict(w, r, id)
 			return
 		}
+		if cascade {
+			writeInternalError(w, r, err, "Failed to delete part and its translations, nothing was deleted")
+		} else {
+			writeInternalError(w, r, err, "Failed to delete part")
+		}
+		return
+	}
+
+	if cascade {
+		fmt.Printf("Trashed Hebrew part and %d translations (total %d parts)\n", deletedCount-1, deletedCount)
 	}
 
 	w.WriteHeader(http.StatusNoContent)
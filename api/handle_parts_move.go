@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// MovePartRequest is where a part is moved or copied to
+type MovePartRequest struct {
+	EventID string `json:"event_id"`
+	Order   *int   `json:"order,omitempty"` // Optional: defaults to after the event's last part
+}
+
+// MovePartResponse is the moved or copied part with all its language versions
+type MovePartResponse struct {
+	Part    *storage.LessonPart   `json:"part"`    // The version in the requested part's language
+	Parts   []*storage.LessonPart `json:"parts"`   // Every language version
+	Shifted int                   `json:"shifted"` // Parts of the target event moved down to make room
+}
+
+// HandleMovePart moves a part with all its translations to another event.
+// The parts keep their IDs and take the target event's date; parts of the target
+// event at or after the new order move down by one.
+func (a *App) HandleMovePart(w http.ResponseWriter, r *http.Request) {
+	a.relocatePart(w, r, false)
+}
+
+// HandleCopyPart copies a part with all its translations to another event (or the
+// same one) as a new translation group, like HandleMovePart otherwise
+func (a *App) HandleCopyPart(w http.ResponseWriter, r *http.Request) {
+	a.relocatePart(w, r, true)
+}
+
+func (a *App) relocatePart(w http.ResponseWriter, r *http.Request, copyPart bool) {
+	id := mux.Vars(r)["id"]
+
+	var req MovePartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+		return
+	}
+	if req.EventID == "" {
+		writeFieldError(w, r, codeValidationFailed, "event_id", "event_id is required")
+		return
+	}
+
+	part, err := a.store.GetPart(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+	if !copyPart {
+		if !ifMatch(r, part.Version) {
+			writePreconditionFailed(w, r, part, part.Version)
+			return
+		}
+		if part.EventID == req.EventID {
+			writeFieldError(w, r, codeValidationFailed, "event_id", "The part is already in this event, use PUT /api/events/{id}/parts/order to reorder it")
+			return
+		}
+	}
+
+	target, err := a.eventStore.GetEvent(r.Context(), req.EventID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Target event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+	if part.EventID != "" && part.EventID != target.ID {
+		source, err := a.eventStore.GetEvent(r.Context(), part.EventID)
+		if err != nil {
+			writeInternalError(w, r, err, "Failed to get the part's event")
+			return
+		}
+		if !a.compatibleEventTypes(source.Type, target.Type) {
+			writeError(w, r, http.StatusConflict, codeIncompatibleEventType, fmt.Sprintf("Parts of %s events can't be moved or copied to %s events", source.Type, target.Type))
+			return
+		}
+	}
+
+	targetParts, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{EventID: target.ID})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list the target event's parts")
+		return
+	}
+
+	// The preparation part stays at order 0, and an event has only one
+	order := 1
+	for _, p := range targetParts {
+		order = max(order, p.Order+1)
+	}
+	if part.Order == 0 {
+		if req.Order != nil && *req.Order != 0 {
+			writeFieldError(w, r, codeValidationFailed, "order", "The preparation part keeps order 0")
+			return
+		}
+		for _, p := range targetParts {
+			if p.Order == 0 {
+				writeError(w, r, http.StatusConflict, codePreparationPartExists, "The target event already has a preparation part")
+				return
+			}
+		}
+		order = 0
+	} else if req.Order != nil {
+		if *req.Order < 1 {
+			writeFieldError(w, r, codeValidationFailed, "order", "Invalid order, order 0 is reserved for the preparation part")
+			return
+		}
+		order = *req.Order
+	}
+
+	actor := actorFromRequest(r)
+	var result []*storage.LessonPart
+	shifted := 0
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		result = nil
+		shifted = 0
+
+		if !copyPart {
+			if err := checkPartVersion(ctx, tx, r, id); err != nil {
+				return err
+			}
+		}
+		translations, _, err := tx.Parts.ListPartsFiltered(ctx, storage.TranslationsQuery(part))
+		if err != nil {
+			return err
+		}
+
+		// Make room in the target event
+		if order > 0 {
+			current, _, err := tx.Parts.ListPartsFiltered(ctx, storage.PartQuery{EventID: target.ID})
+			if err != nil {
+				return err
+			}
+			for _, p := range current {
+				if p.Order < order {
+					continue
+				}
+				p.Order++
+				p.UpdatedBy = actor
+				if err := tx.Parts.SavePart(ctx, p); err != nil {
+					return fmt.Errorf("failed to shift %s part %s: %w", p.Language, p.ID, err)
+				}
+				shifted++
+			}
+		}
+
+		groupID := storage.NewTranslationGroupID()
+		for _, t := range translations {
+			moved := t
+			if copyPart {
+				moved = copyPartTo(t, groupID)
+			}
+			moved.EventID = target.ID
+			moved.Date = target.Date
+			moved.Order = order
+			moved.UpdatedBy = actor
+			if err := tx.Parts.SavePart(ctx, moved); err != nil {
+				return fmt.Errorf("failed to save %s part: %w", t.Language, err)
+			}
+			result = append(result, moved)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writePartConflict(w, r, id)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to relocate part, nothing was changed")
+		return
+	}
+
+	response := MovePartResponse{Parts: result, Shifted: shifted}
+	for _, p := range result {
+		if p.Language == part.Language {
+			response.Part = p
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if copyPart {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		setETag(w, response.Part.Version)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// copyPartTo returns a new part with the content of part in the given translation group
+func copyPartTo(part *storage.LessonPart, translationGroupID string) *storage.LessonPart {
+	return &storage.LessonPart{
+		Title:                  part.Title,
+		Description:            part.Description,
+		PartType:               part.PartType,
+		Language:               part.Language,
+		TranslationGroupID:     translationGroupID,
+		TranslationStub:        part.TranslationStub,
+		ExcerptsLink:           part.ExcerptsLink,
+		TranscriptLink:         part.TranscriptLink,
+		LessonLink:             part.LessonLink,
+		ProgramLink:            part.ProgramLink,
+		ReadingBeforeSleepLink: part.ReadingBeforeSleepLink,
+		LessonPreparationLink:  part.LessonPreparationLink,
+		LineupForHostsLink:     part.LineupForHostsLink,
+		RecordedLessonDate:     part.RecordedLessonDate,
+		Sources:                part.Sources,
+		CustomLinks:            part.CustomLinks,
+	}
+}
+
+// compatibleEventTypes reports whether parts may be moved or copied from events of
+// one type to events of another. Without configured groups every move is allowed.
+func (a *App) compatibleEventTypes(from, to string) bool {
+	if len(a.eventTypeGroups) == 0 || from == to {
+		return true
+	}
+	for _, group := range a.eventTypeGroups {
+		if containsID(group, from) && containsID(group, to) {
+			return true
+		}
+	}
+	return false
+}
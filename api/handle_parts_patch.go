@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// partImmutableFields can't be changed by a PATCH
+var partImmutableFields = []string{"id", "language", "event_id", "translation_group_id", "created_at"}
+
+// HandlePatchPart applies a JSON Merge Patch or JSON Patch to a lesson part, so
+// clients only send the fields they change
+func (a *App) HandlePatchPart(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	partID := vars["id"]
+
+	existingPart, err := a.store.GetPart(r.Context(), partID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+
+	// Reject edits based on an outdated copy of the part
+	if !ifMatch(r, existingPart.Version) {
+		writePreconditionFailed(w, r, existingPart, existingPart.Version)
+		return
+	}
+
+	var part storage.LessonPart
+	if err := applyPatch(r, existingPart, &part, partImmutableFields); err != nil {
+		writePatchError(w, r, err)
+		return
+	}
+
+	if part.Title == "" {
+		writeFieldError(w, r, codeValidationFailed, "title", "Title is required")
+		return
+	}
+	if part.PartType != "live_lesson" && part.PartType != "recorded_lesson" {
+		writeFieldError(w, r, codeValidationFailed, "part_type", "Invalid part_type, must be 'live_lesson' or 'recorded_lesson'")
+		return
+	}
+
+	// Fields the server maintains aren't patchable
+	part.Version = existingPart.Version
+	part.DeletedAt = existingPart.DeletedAt
+	part.DeletedBy = existingPart.DeletedBy
+	part.TranslationStub = existingPart.TranslationStub &&
+		part.Title == existingPart.Title && part.Description == existingPart.Description
+	part.UpdatedBy = actorFromRequest(r)
+
+	// A new order applies to every translation, so the languages stay aligned
+	orderChanged := part.Order != existingPart.Order
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		if err := tx.Parts.SavePart(ctx, &part); err != nil {
+			return err
+		}
+		if !orderChanged || part.TranslationGroupID == "" {
+			return nil
+		}
+		return syncTranslationOrder(ctx, tx, &part)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writePartConflict(w, r, partID)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to update part")
+		return
+	}
+
+	setETag(w, part.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&part)
+}
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sort"
+	"strconv"
 	"time"
 
+	"github.com/Bnei-Baruch/study-material-service/integrations/kabbalahmedia"
 	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
 )
@@ -15,36 +18,83 @@ import (
 func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 	var req storage.CreatePartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
-	// Validate
-	if req.Title == "" {
-		http.Error(w, "Title is required", http.StatusBadRequest)
+	part, invalid := newPartFromRequest(&req, actorFromRequest(r))
+	if invalid != nil {
+		writeValidationError(w, r, invalid)
 		return
 	}
 
-	if req.Date == "" {
-		http.Error(w, "Date is required", http.StatusBadRequest)
-		return
+	// If event_id is provided, verify event exists
+	if req.EventID != "" {
+		_, err := a.eventStore.GetEvent(r.Context(), req.EventID)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+				return
+			}
+			writeInternalError(w, r, err, "Failed to get event")
+			return
+		}
 	}
 
-	// Parse date
-	date, err := time.Parse("2006-01-02", req.Date)
+	// Auto-create translation stubs for other languages
+	translationStubs := a.translationStubs(r.Context(), part, req.TemplateID, a.newSourceTitles())
+
+	// Save the part and its translation stubs together: either all languages are created or none
+	err := a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		if err := tx.Parts.SavePart(ctx, part); err != nil {
+			return err
+		}
+		for _, stub := range translationStubs {
+			if err := tx.Parts.SavePart(ctx, stub); err != nil {
+				return fmt.Errorf("failed to create %s translation stub: %w", stub.Language, err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		http.Error(w, "Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+		writeInternalError(w, r, err, "Failed to save part, nothing was created")
 		return
 	}
 
+	// Return created part
+	setETag(w, part.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(part)
+}
+
+// newPartFromRequest validates a create request and builds the part it describes.
+// The error lists every invalid field, with messages meant for the client.
+func newPartFromRequest(req *storage.CreatePartRequest, actor string) (*storage.LessonPart, validationError) {
+	var invalid validationError
+
+	// Validate
+	if req.Title == "" {
+		invalid.add("title", "Title is required")
+	}
+
+	// Parse date
+	var date time.Time
+	if req.Date == "" {
+		invalid.add("date", "Date is required")
+	} else if parsed, err := time.Parse("2006-01-02", req.Date); err != nil {
+		invalid.add("date", "Invalid date format, use YYYY-MM-DD")
+	} else {
+		date = parsed
+	}
+
 	// Default and validate part_type
 	partType := req.PartType
 	if partType == "" {
 		partType = "live_lesson" // Default to live_lesson
 	}
 	if partType != "live_lesson" && partType != "recorded_lesson" {
-		http.Error(w, "Invalid part_type, must be 'live_lesson' or 'recorded_lesson'", http.StatusBadRequest)
-		return
+		invalid.add("part_type", "Invalid part_type, must be 'live_lesson' or 'recorded_lesson'")
 	}
 
 	// Default and validate language
@@ -53,21 +103,14 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 		language = "he" // Default to Hebrew
 	}
 	if len(language) != 2 {
-		http.Error(w, "Invalid language code, must be 2-letter ISO 639-1 code", http.StatusBadRequest)
-		return
+		invalid.add("language", "Invalid language code, must be 2-letter ISO 639-1 code")
 	}
 
-	// If event_id is provided, verify event exists
-	if req.EventID != "" {
-		_, err := a.eventStore.GetEvent(req.EventID)
-		if err != nil {
-			http.Error(w, "Event not found", http.StatusNotFound)
-			return
-		}
+	if len(invalid) > 0 {
+		return nil, invalid
 	}
 
-	// Create part
-	part := &storage.LessonPart{
+	return &storage.LessonPart{
 		Title:                  req.Title,
 		Description:            req.Description,
 		Date:                   date,
@@ -85,14 +128,49 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 		RecordedLessonDate:     req.RecordedLessonDate,
 		Sources:                req.Sources,
 		CustomLinks:            req.CustomLinks,
+		TranslationGroupID:     storage.NewTranslationGroupID(),
+		UpdatedBy:              actor,
+	}, nil
+}
+
+// sourceTitles looks up kabbalahmedia source titles, remembering each answer so
+// parts that share sources don't fetch them again
+type sourceTitles struct {
+	client *kabbalahmedia.Client
+	titles map[string]string
+	errs   map[string]error
+}
+
+// newSourceTitles returns an empty source title cache
+func (a *App) newSourceTitles() *sourceTitles {
+	return &sourceTitles{
+		client: a.kabbalahmediaClient,
+		titles: make(map[string]string),
+		errs:   make(map[string]error),
 	}
+}
 
-	if err := a.store.SavePart(part); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to save part: %v", err), http.StatusInternalServerError)
-		return
+// get returns the title of a source in a language
+func (s *sourceTitles) get(ctx context.Context, sourceID, language string) (string, error) {
+	key := sourceID + "/" + language
+	if title, ok := s.titles[key]; ok {
+		return title, nil
 	}
+	if err, ok := s.errs[key]; ok {
+		return "", err
+	}
+	title, err := s.client.GetSourceTitle(ctx, sourceID, language)
+	if err != nil {
+		s.errs[key] = err
+		return "", err
+	}
+	s.titles[key] = title
+	return title, nil
+}
 
-	// Auto-create translation stubs for other languages
+// translationStubs builds the stubs for every other configured language of a new part.
+// Stub titles come from the template config, source titles from kabbalahmedia.
+func (a *App) translationStubs(ctx context.Context, part *storage.LessonPart, templateID string, titles *sourceTitles) []*storage.LessonPart {
 	// Use languages from template config
 	supportedLanguages := a.templateConfig.Languages
 
@@ -102,6 +180,7 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 		templateMap[tmpl.ID] = tmpl.Translations
 	}
 
+	var translationStubs []*storage.LessonPart
 	for _, lang := range supportedLanguages {
 		if lang == part.Language {
 			continue // Skip the language we just created
@@ -114,9 +193,9 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 			if translatedTitle, ok := a.templateConfig.Preparation[lang]; ok {
 				stubTitle = translatedTitle
 			}
-		} else if req.TemplateID != "" {
+		} else if templateID != "" {
 			// If a template was used, use its translation from config
-			if template, ok := templateMap[req.TemplateID]; ok {
+			if template, ok := templateMap[templateID]; ok {
 				if translatedTitle, ok := template[lang]; ok {
 					stubTitle = translatedTitle
 				}
@@ -127,7 +206,7 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 		translatedSources := make([]storage.Source, len(part.Sources))
 		for i, source := range part.Sources {
 			// Fetch the source title in the target language
-			sourceTitle, err := a.kabbalahmediaClient.GetSourceTitle(source.SourceID, lang)
+			sourceTitle, err := titles.get(ctx, source.SourceID, lang)
 			if err != nil {
 				// If fetch fails, use the original title
 				fmt.Printf("Warning: Failed to get source title for %s in %s: %v\n", source.SourceID, lang, err)
@@ -152,6 +231,9 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 		Language:    lang,
 		EventID:     part.EventID,
 		Order:       part.Order,
+		// The stub is a translation of the part
+		TranslationGroupID: part.TranslationGroupID,
+		TranslationStub:    true,
 		// Copy shared links (same across languages)
 		ExcerptsLink:           part.ExcerptsLink,
 		TranscriptLink:         part.TranscriptLink,
@@ -162,19 +244,13 @@ func (a *App) HandleCreatePart(w http.ResponseWriter, r *http.Request) {
 		LineupForHostsLink:     part.LineupForHostsLink, // Copy lineup link to translations
 		RecordedLessonDate:     part.RecordedLessonDate,  // Copy recorded lesson date
 		// Use translated sources (same IDs, different titles)
-		Sources: translatedSources,
+		Sources:   translatedSources,
+		UpdatedBy: part.UpdatedBy,
 	}
 
-		if err := a.store.SavePart(translationStub); err != nil {
-			// Log error but don't fail the request
-			fmt.Printf("Warning: Failed to create %s translation stub: %v\n", lang, err)
-		}
+		translationStubs = append(translationStubs, translationStub)
 	}
-
-	// Return created part
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(part)
+	return translationStubs
 }
 
 // HandleGetPart retrieves a lesson part by ID (POC)
@@ -182,12 +258,19 @@ func (a *App) HandleGetPart(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	part, err := a.store.GetPart(id)
+	part, err := a.store.GetPart(r.Context(), id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Part not found: %v", err), http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
 		return
 	}
 
+	if a.notModified(w, r, versionETag(part.Version), part.LastModified()) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(part)
 }
@@ -198,70 +281,200 @@ func (a *App) HandleUpdatePart(w http.ResponseWriter, r *http.Request) {
 	partID := vars["id"]
 
 	// Get existing part
-	existingPart, err := a.store.GetPart(partID)
+	existingPart, err := a.store.GetPart(r.Context(), partID)
 	if err != nil {
-		http.Error(w, "Part not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+
+	// Reject edits based on an outdated copy of the part
+	if !ifMatch(r, existingPart.Version) {
+		writePreconditionFailed(w, r, existingPart, existingPart.Version)
 		return
 	}
 
 	// Parse update request
 	var req storage.CreatePartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
-	// Update all editable fields
-	existingPart.Title = req.Title
-	existingPart.Description = req.Description
-	existingPart.Order = req.Order
-	existingPart.Sources = req.Sources
-	existingPart.ExcerptsLink = req.ExcerptsLink
-	existingPart.TranscriptLink = req.TranscriptLink
-	existingPart.LessonLink = req.LessonLink
-	existingPart.ProgramLink = req.ProgramLink
-	existingPart.ReadingBeforeSleepLink = req.ReadingBeforeSleepLink
-	existingPart.LessonPreparationLink = req.LessonPreparationLink
-	existingPart.LineupForHostsLink = req.LineupForHostsLink
-	existingPart.RecordedLessonDate = req.RecordedLessonDate
-	existingPart.CustomLinks = req.CustomLinks
-	existingPart.ShowUpdatedBadge = req.ShowUpdatedBadge
+	orderChanged := applyPartUpdate(existingPart, &req, actorFromRequest(r))
+
+	// A new order applies to every translation, so the languages stay aligned
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		if err := tx.Parts.SavePart(ctx, existingPart); err != nil {
+			return err
+		}
+		if !orderChanged || existingPart.TranslationGroupID == "" {
+			return nil
+		}
+		return syncTranslationOrder(ctx, tx, existingPart)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writePartConflict(w, r, partID)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to update part")
+		return
+	}
+
+	setETag(w, existingPart.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existingPart)
+}
+
+// applyPartUpdate sets every editable field of a part from an update request and
+// reports whether the order changed
+func applyPartUpdate(part *storage.LessonPart, req *storage.CreatePartRequest, actor string) bool {
+	orderChanged := part.Order != req.Order
+	if req.Title != part.Title || req.Description != part.Description {
+		part.TranslationStub = false // Someone has translated the stub
+	}
+	part.Title = req.Title
+	part.Description = req.Description
+	part.Order = req.Order
+	part.Sources = req.Sources
+	part.ExcerptsLink = req.ExcerptsLink
+	part.TranscriptLink = req.TranscriptLink
+	part.LessonLink = req.LessonLink
+	part.ProgramLink = req.ProgramLink
+	part.ReadingBeforeSleepLink = req.ReadingBeforeSleepLink
+	part.LessonPreparationLink = req.LessonPreparationLink
+	part.LineupForHostsLink = req.LineupForHostsLink
+	part.RecordedLessonDate = req.RecordedLessonDate
+	part.CustomLinks = req.CustomLinks
+	part.ShowUpdatedBadge = req.ShowUpdatedBadge
 
 	// Parse and update date if provided
 	if req.Date != "" {
 		date, err := time.Parse("2006-01-02", req.Date)
 		if err == nil {
-			existingPart.Date = date
+			part.Date = date
 		}
 	}
 
-	// Don't change: ID, language, event_id, created_at
-
-	if err := a.store.SavePart(existingPart); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update part: %v", err), http.StatusInternalServerError)
-		return
-	}
+	// Don't change: ID, language, event_id, translation_group_id, created_at
+	part.UpdatedBy = actor
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(existingPart)
+	return orderChanged
 }
 
-// HandleListParts lists all lesson parts (POC)
+// HandleListParts lists lesson parts with optional filtering, sorting and pagination
+// Query parameters:
+//   - event_id (string): only parts of this event
+//   - language (string): only parts in this language
+//   - part_type (string): only parts of this type (live_lesson, recorded_lesson)
+//   - from_date, to_date (string): only parts dated within this range (YYYY-MM-DD)
+//   - source_id (string): only parts that reference this kabbalahmedia source
+//   - has_translation_stub (bool): only untranslated stubs (true) or only real content (false)
+//   - sort (string): comma-separated fields, "-" for descending (e.g. "-date,order")
+//   - limit (int), offset (int): pagination
+//   - public_only (bool): only parts of public events. Always on for requests from
+//     outside the internal network without an API key.
 func (a *App) HandleListParts(w http.ResponseWriter, r *http.Request) {
-	parts, err := a.store.ListParts()
+	queryParams := r.URL.Query()
+
+	query := storage.PartQuery{
+		EventID:  queryParams.Get("event_id"),
+		Language: queryParams.Get("language"),
+		PartType: queryParams.Get("part_type"),
+		SourceID: queryParams.Get("source_id"),
+	}
+
+	if fromDate := queryParams.Get("from_date"); fromDate != "" {
+		date, err := time.Parse("2006-01-02", fromDate)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "from_date", "Invalid from_date, use YYYY-MM-DD")
+			return
+		}
+		query.FromDate = &date
+	}
+	if toDate := queryParams.Get("to_date"); toDate != "" {
+		date, err := time.Parse("2006-01-02", toDate)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "to_date", "Invalid to_date, use YYYY-MM-DD")
+			return
+		}
+		// Include the whole end day
+		endOfDay := date.AddDate(0, 0, 1).Add(-time.Millisecond)
+		query.ToDate = &endOfDay
+	}
+
+	if stubStr := queryParams.Get("has_translation_stub"); stubStr != "" {
+		stub, err := strconv.ParseBool(stubStr)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidParameter, "has_translation_stub", "Invalid has_translation_stub, use true or false")
+			return
+		}
+		query.TranslationStub = &stub
+	}
+
+	if sortStr := queryParams.Get("sort"); sortStr != "" {
+		sortFields, err := storage.ParseSort(sortStr)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidParameter, "sort", err.Error())
+			return
+		}
+		query.Sort = sortFields
+	}
+
+	if limitStr := queryParams.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			writeFieldError(w, r, codeInvalidParameter, "limit", "Invalid limit")
+			return
+		}
+		query.Limit = limit
+	}
+	if offsetStr := queryParams.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			writeFieldError(w, r, codeInvalidParameter, "offset", "Invalid offset")
+			return
+		}
+		query.Offset = offset
+	}
+
+	// Parts of draft events are only listed for the admin UI and API key holders
+	publicOnly := queryParams.Get("public_only") == "true" || !a.isTrustedRequest(r)
+	query.PublicEventsOnly = publicOnly
+
+	parts, total, err := a.store.ListPartsFiltered(r.Context(), query)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list parts: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to list parts")
 		return
 	}
+	if parts == nil {
+		parts = []*storage.LessonPart{}
+	}
 
+	etag := newListETag(strconv.FormatBool(publicOnly), strconv.Itoa(total))
+	for _, p := range parts {
+		etag.add("part", p.ID, p.Version)
+	}
+	if a.notModified(w, r, etag.String(), time.Time{}) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"parts": parts,
-		"total": len(parts),
+		"parts":    parts,
+		"total":    total,
+		"returned": len(parts),
+		"limit":    query.Limit,
+		"offset":   query.Offset,
 	})
 }
 
-// HandleGetEventParts retrieves all parts for a specific event
+// HandleGetEventParts retrieves all parts for a specific event.
+// Its ETag and Last-Modified cover the event's parts in every language, so they
+// change whenever any translation does, whichever language was requested.
 func (a *App) HandleGetEventParts(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	eventID := vars["event_id"]
@@ -270,36 +483,45 @@ func (a *App) HandleGetEventParts(w http.ResponseWriter, r *http.Request) {
 	languageFilter := r.URL.Query().Get("language")
 
 	// Verify event exists
-	_, err := a.eventStore.GetEvent(eventID)
+	_, err := a.eventStore.GetEvent(r.Context(), eventID)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
 		return
 	}
 
-	// Get all parts and filter by event_id
-	allParts, err := a.store.ListParts()
+	// Get the event's parts in all languages, sorted by order, then by language for
+	// consistent ordering. Trashed parts count towards Last-Modified, so removing a
+	// part moves it forward.
+	allParts, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{
+		EventID: eventID,
+		Trashed: storage.IncludeTrashed,
+		Sort:    storage.DefaultPartSort,
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list parts: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to list parts")
 		return
 	}
 
-	var eventParts []*storage.LessonPart
-	for _, part := range allParts {
-		if part.EventID == eventID {
-			// Apply language filter if provided
-			if languageFilter == "" || part.Language == languageFilter {
-				eventParts = append(eventParts, part)
-			}
+	eventParts := []*storage.LessonPart{}
+	etag := newListETag()
+	var lastModified time.Time
+	for _, p := range allParts {
+		lastModified = latest(lastModified, p.LastModified())
+		if p.DeletedAt != nil {
+			continue
 		}
-	}
-
-	// Sort by order, then by language for consistent ordering
-	sort.Slice(eventParts, func(i, j int) bool {
-		if eventParts[i].Order != eventParts[j].Order {
-			return eventParts[i].Order < eventParts[j].Order
+		etag.add("part", p.ID, p.Version)
+		if languageFilter == "" || p.Language == languageFilter {
+			eventParts = append(eventParts, p)
 		}
-		return eventParts[i].Language < eventParts[j].Language
-	})
+	}
+	if a.notModified(w, r, etag.String(), lastModified) {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
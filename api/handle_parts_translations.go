@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// TranslationsResponse lists every language version of a part
+type TranslationsResponse struct {
+	TranslationGroupID string                `json:"translation_group_id,omitempty"`
+	Parts              []*storage.LessonPart `json:"parts"`
+	Total              int                   `json:"total"`
+}
+
+// HandleGetPartTranslations lists every part in the part's translation group, the part included
+func (a *App) HandleGetPartTranslations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	part, err := a.store.GetPart(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+
+	translations, _, err := a.store.ListPartsFiltered(r.Context(), storage.TranslationsQuery(part))
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list translations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TranslationsResponse{
+		TranslationGroupID: part.TranslationGroupID,
+		Parts:              translations,
+		Total:              len(translations),
+	})
+}
+
+// syncTranslationOrder moves the other parts in the part's translation group to its order
+func syncTranslationOrder(ctx context.Context, tx storage.Tx, part *storage.LessonPart) error {
+	translations, _, err := tx.Parts.ListPartsFiltered(ctx, storage.PartQuery{TranslationGroupID: part.TranslationGroupID})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range translations {
+		if t.ID == part.ID || t.Order == part.Order {
+			continue
+		}
+		t.Order = part.Order
+		t.UpdatedBy = part.UpdatedBy
+		if err := tx.Parts.SavePart(ctx, t); err != nil {
+			return fmt.Errorf("failed to reorder %s translation: %w", t.Language, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+const (
+	// defaultPublicEventsLimit and maxPublicEventsLimit bound the number of events returned
+	defaultPublicEventsLimit = 10
+	maxPublicEventsLimit     = 100
+)
+
+// publicFallbackLanguages are tried in order when something isn't translated to
+// the requested language, like the widget does
+var publicFallbackLanguages = []string{"he", "en"}
+
+// PublicEventsResponse lists public events ready to render in one language
+type PublicEventsResponse struct {
+	Language string         `json:"language"`
+	Events   []*PublicEvent `json:"events"`
+}
+
+// PublicEvent is an event with its localised title, type and parts
+type PublicEvent struct {
+	ID        string          `json:"id"`
+	Date      string          `json:"date"` // YYYY-MM-DD
+	StartTime string          `json:"start_time,omitempty"`
+	EndTime   string          `json:"end_time,omitempty"`
+	Number    int             `json:"number"`
+	Title     string          `json:"title"`
+	Type      PublicEventType `json:"type"`
+	Parts     []*PublicPart   `json:"parts"`
+}
+
+// PublicEventType is the localised title and color of an event's type
+type PublicEventType struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Color string `json:"color,omitempty"`
+}
+
+// PublicPart is a part in the requested language, or in a fallback language if
+// it hasn't been translated yet
+type PublicPart struct {
+	ID                     string               `json:"id"`
+	Order                  int                  `json:"order"`
+	Language               string               `json:"language"`
+	Fallback               bool                 `json:"fallback"` // Shown in another language than requested
+	Title                  string               `json:"title"`
+	Description            string               `json:"description,omitempty"`
+	PartType               string               `json:"part_type"`
+	RecordedLessonDate     string               `json:"recorded_lesson_date,omitempty"`
+	ShowUpdatedBadge       bool                 `json:"show_updated_badge,omitempty"`
+	ExcerptsLink           string               `json:"excerpts_link,omitempty"`
+	TranscriptLink         string               `json:"transcript_link,omitempty"`
+	LessonLink             string               `json:"lesson_link,omitempty"`
+	ProgramLink            string               `json:"program_link,omitempty"`
+	ReadingBeforeSleepLink string               `json:"reading_before_sleep_link,omitempty"`
+	LessonPreparationLink  string               `json:"lesson_preparation_link,omitempty"`
+	LineupForHostsLink     string               `json:"lineup_for_hosts_link,omitempty"`
+	Sources                []storage.Source     `json:"sources"`
+	CustomLinks            []storage.CustomLink `json:"custom_links,omitempty"`
+}
+
+// HandleListPublicEvents returns public events with their parts in one language,
+// shaped for the widget and the public page
+// Query parameters:
+//   - language (string): language to render (default "he"); missing translations
+//     fall back to Hebrew, then English
+//   - limit (int): maximum number of events (default 10, max 100)
+//   - from (string): only events on or after this date (YYYY-MM-DD)
+//   - types (string): comma-separated event types, e.g. "morning_lesson,evening_lesson"
+func (a *App) HandleListPublicEvents(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	language := params.Get("language")
+	if language == "" {
+		language = "he"
+	}
+	languages := []string{language}
+	for _, fallback := range publicFallbackLanguages {
+		if !containsID(languages, fallback) {
+			languages = append(languages, fallback)
+		}
+	}
+
+	query := storage.PublicEventsQuery{Languages: languages, Limit: defaultPublicEventsLimit}
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			writeFieldError(w, r, codeInvalidParameter, "limit", "Invalid limit")
+			return
+		}
+		query.Limit = min(limit, maxPublicEventsLimit)
+	}
+	if from := params.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "from", "Invalid from date, use YYYY-MM-DD")
+			return
+		}
+		query.FromDate = &date
+	}
+	if types := params.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				query.Types = append(query.Types, t)
+			}
+		}
+	}
+
+	events, err := a.eventStore.ListPublicEvents(r.Context(), query)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list events")
+		return
+	}
+
+	etag := newListETag(strings.Join(languages, ","))
+	for _, e := range events {
+		etag.add("event", e.Event.ID, e.Event.Version)
+		if e.EventType != nil {
+			etag.add("event_type", e.EventType.ID, e.EventType.Version)
+		}
+		for _, p := range e.Parts {
+			etag.add("part", p.ID, p.Version)
+		}
+	}
+	if a.notModified(w, r, etag.String(), time.Time{}) {
+		return
+	}
+
+	response := PublicEventsResponse{
+		Language: language,
+		Events:   make([]*PublicEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		response.Events = append(response.Events, newPublicEvent(e, languages))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// newPublicEvent renders an event in the first of languages that has a translation
+func newPublicEvent(e *storage.EventWithParts, languages []string) *PublicEvent {
+	event := &PublicEvent{
+		ID:        e.Event.ID,
+		Date:      e.Event.Date.Format("2006-01-02"),
+		StartTime: e.Event.StartTime,
+		EndTime:   e.Event.EndTime,
+		Number:    e.Event.Number,
+		Title:     localized(e.Event.Titles, languages),
+		Type:      PublicEventType{Name: e.Event.Type, Title: e.Event.Type},
+		Parts:     []*PublicPart{},
+	}
+	if e.EventType != nil {
+		if title := localized(e.EventType.Titles, languages); title != "" {
+			event.Type.Title = title
+		}
+		event.Type.Color = e.EventType.Color
+	}
+	if event.Title == "" {
+		event.Title = event.Type.Title
+	}
+
+	for _, group := range storage.GroupEventParts(e.Parts) {
+		part := pickTranslation(group.Parts, languages)
+		sources := part.Sources
+		if sources == nil {
+			sources = []storage.Source{}
+		}
+		event.Parts = append(event.Parts, &PublicPart{
+			ID:                     part.ID,
+			Order:                  group.Order,
+			Language:               part.Language,
+			Fallback:               part.Language != languages[0],
+			Title:                  part.Title,
+			Description:            part.Description,
+			PartType:               part.PartType,
+			RecordedLessonDate:     part.RecordedLessonDate,
+			ShowUpdatedBadge:       part.ShowUpdatedBadge,
+			ExcerptsLink:           part.ExcerptsLink,
+			TranscriptLink:         part.TranscriptLink,
+			LessonLink:             part.LessonLink,
+			ProgramLink:            part.ProgramLink,
+			ReadingBeforeSleepLink: part.ReadingBeforeSleepLink,
+			LessonPreparationLink:  part.LessonPreparationLink,
+			LineupForHostsLink:     part.LineupForHostsLink,
+			Sources:                sources,
+			CustomLinks:            part.CustomLinks,
+		})
+	}
+	return event
+}
+
+// localized returns the first non-empty translation in languages
+func localized(translations map[string]string, languages []string) string {
+	for _, lang := range languages {
+		if t := translations[lang]; t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// pickTranslation returns the version of a part in the first of languages that
+// has been translated. Untranslated stubs are only used if there is nothing else.
+func pickTranslation(parts []*storage.LessonPart, languages []string) *storage.LessonPart {
+	var stub *storage.LessonPart
+	for _, lang := range languages {
+		for _, p := range parts {
+			if p.Language != lang {
+				continue
+			}
+			if !p.TranslationStub {
+				return p
+			}
+			if stub == nil {
+				stub = p
+			}
+		}
+	}
+	if stub != nil {
+		return stub
+	}
+	return parts[0]
+}
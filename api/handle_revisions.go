@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// RevisionSummary describes one revision in a document's history
+type RevisionSummary struct {
+	Rev           int       `json:"rev"`
+	Author        string    `json:"author,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	ChangedFields []string  `json:"changed_fields"` // Fields changed since the previous revision
+}
+
+// RevisionDiffResponse lists the fields that changed between two revisions
+type RevisionDiffResponse struct {
+	From    int                   `json:"from"`
+	To      int                   `json:"to"`
+	Changes []storage.FieldChange `json:"changes"`
+}
+
+// HandleListPartRevisions lists the revision history of a part, oldest first
+func (a *App) HandleListPartRevisions(w http.ResponseWriter, r *http.Request) {
+	a.listRevisions(w, r, storage.RevisionTypePart)
+}
+
+// HandleGetPartRevision returns a part as it was at the given revision
+func (a *App) HandleGetPartRevision(w http.ResponseWriter, r *http.Request) {
+	a.getRevision(w, r, storage.RevisionTypePart)
+}
+
+// HandleDiffPartRevisions compares two revisions of a part (?from=N&to=M)
+func (a *App) HandleDiffPartRevisions(w http.ResponseWriter, r *http.Request) {
+	a.diffRevisions(w, r, storage.RevisionTypePart)
+}
+
+// HandleRestorePartRevision saves a part's content from an earlier revision as a new revision
+func (a *App) HandleRestorePartRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	current, err := a.store.GetPart(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+	if !ifMatch(r, current.Version) {
+		writePreconditionFailed(w, r, current, current.Version)
+		return
+	}
+
+	revision, ok := a.lookupRevision(w, r, storage.RevisionTypePart)
+	if !ok {
+		return
+	}
+
+	// Restore the content, keeping what identifies the part. The order is shared
+	// with the part's translations and stays, so the group isn't pulled apart.
+	restored := *revision.Part
+	restored.ID = current.ID
+	restored.Language = current.Language
+	restored.EventID = current.EventID
+	restored.TranslationGroupID = current.TranslationGroupID
+	restored.Order = current.Order
+	restored.TranslationStub = current.TranslationStub
+	restored.CreatedAt = current.CreatedAt
+	restored.Version = current.Version
+	restored.DeletedAt = nil
+	restored.DeletedBy = ""
+	restored.UpdatedBy = actorFromRequest(r)
+
+	if err := a.store.SavePart(r.Context(), &restored); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writePartConflict(w, r, id)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to restore part")
+		return
+	}
+
+	setETag(w, restored.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
+
+// HandleListEventRevisions lists the revision history of an event, oldest first
+func (a *App) HandleListEventRevisions(w http.ResponseWriter, r *http.Request) {
+	a.listRevisions(w, r, storage.RevisionTypeEvent)
+}
+
+// HandleGetEventRevision returns an event as it was at the given revision
+func (a *App) HandleGetEventRevision(w http.ResponseWriter, r *http.Request) {
+	a.getRevision(w, r, storage.RevisionTypeEvent)
+}
+
+// HandleDiffEventRevisions compares two revisions of an event (?from=N&to=M)
+func (a *App) HandleDiffEventRevisions(w http.ResponseWriter, r *http.Request) {
+	a.diffRevisions(w, r, storage.RevisionTypeEvent)
+}
+
+// HandleRestoreEventRevision saves an event's content from an earlier revision as a new revision
+func (a *App) HandleRestoreEventRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	current, err := a.eventStore.GetEvent(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+	if !ifMatch(r, current.Version) {
+		writePreconditionFailed(w, r, current, current.Version)
+		return
+	}
+
+	revision, ok := a.lookupRevision(w, r, storage.RevisionTypeEvent)
+	if !ok {
+		return
+	}
+
+	// Restore the content, keeping what identifies the event and when its email went out
+	restored := *revision.Event
+	restored.ID = current.ID
+	restored.CreatedAt = current.CreatedAt
+	restored.EmailSentAt = current.EmailSentAt
+	restored.Version = current.Version
+	restored.DeletedAt = nil
+	restored.DeletedBy = ""
+	restored.UpdatedBy = actorFromRequest(r)
+
+	if err := a.eventStore.SaveEvent(r.Context(), &restored); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeEventConflict(w, r, id)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to restore event")
+		return
+	}
+
+	setETag(w, restored.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
+
+// listRevisions writes the summaries of a document's revisions. Revisions hold
+// drafts, so they are only served to trusted requests, as are single revisions and diffs.
+func (a *App) listRevisions(w http.ResponseWriter, r *http.Request, documentType string) {
+	if !a.isTrustedRequest(r) {
+		writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	revisions, err := a.revisionStore.ListRevisions(r.Context(), documentType, id)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list revisions")
+		return
+	}
+	if len(revisions) == 0 {
+		writeError(w, r, http.StatusNotFound, codeRevisionNotFound, "No revisions found")
+		return
+	}
+
+	summaries := make([]RevisionSummary, len(revisions))
+	for i, revision := range revisions {
+		summaries[i] = RevisionSummary{
+			Rev:           revision.Rev,
+			Author:        revision.Author,
+			CreatedAt:     revision.CreatedAt,
+			ChangedFields: []string{},
+		}
+		if i == 0 {
+			continue
+		}
+		changes, err := storage.DiffRevisions(revisions[i-1], revision)
+		if err != nil {
+			writeInternalError(w, r, err, "Failed to compare revisions")
+			return
+		}
+		for _, change := range changes {
+			summaries[i].ChangedFields = append(summaries[i].ChangedFields, change.Field)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// getRevision writes a single revision including its snapshot
+func (a *App) getRevision(w http.ResponseWriter, r *http.Request, documentType string) {
+	if !a.isTrustedRequest(r) {
+		writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+	revision, ok := a.lookupRevision(w, r, documentType)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revision)
+}
+
+// diffRevisions writes the changes between the revisions given by the from and to
+// query parameters. to defaults to the latest revision and from to the one before to.
+func (a *App) diffRevisions(w http.ResponseWriter, r *http.Request, documentType string) {
+	if !a.isTrustedRequest(r) {
+		writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	revisions, err := a.revisionStore.ListRevisions(r.Context(), documentType, id)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list revisions")
+		return
+	}
+	if len(revisions) == 0 {
+		writeError(w, r, http.StatusNotFound, codeRevisionNotFound, "No revisions found")
+		return
+	}
+
+	to := revisions[len(revisions)-1].Rev
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = strconv.Atoi(toStr); err != nil {
+			writeFieldError(w, r, codeInvalidParameter, "to", "Invalid to revision")
+			return
+		}
+	}
+	from := to - 1
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if from, err = strconv.Atoi(fromStr); err != nil {
+			writeFieldError(w, r, codeInvalidParameter, "from", "Invalid from revision")
+			return
+		}
+	}
+
+	byRev := make(map[int]*storage.Revision, len(revisions))
+	for _, revision := range revisions {
+		byRev[revision.Rev] = revision
+	}
+	fromRevision, toRevision := byRev[from], byRev[to]
+	if fromRevision == nil || toRevision == nil {
+		writeError(w, r, http.StatusNotFound, codeRevisionNotFound, fmt.Sprintf("Revision not found: from=%d to=%d", from, to))
+		return
+	}
+
+	changes, err := storage.DiffRevisions(fromRevision, toRevision)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to compare revisions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RevisionDiffResponse{From: from, To: to, Changes: changes})
+}
+
+// lookupRevision loads the revision named by the id and rev route variables,
+// writing an error response and returning false if it can't
+func (a *App) lookupRevision(w http.ResponseWriter, r *http.Request, documentType string) (*storage.Revision, bool) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	rev, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		writeFieldError(w, r, codeInvalidParameter, "rev", "Invalid revision number")
+		return nil, false
+	}
+
+	revision, err := a.revisionStore.GetRevision(r.Context(), documentType, id, rev)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeRevisionNotFound, "Revision not found")
+			return nil, false
+		}
+		writeInternalError(w, r, err, "Failed to get revision")
+		return nil, false
+	}
+	return revision, true
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// defaultSearchLimit and maxSearchLimit bound the number of event groups returned
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// searchCandidates is how many parts and events are ranked before grouping
+	searchCandidates = 500
+	// searchExcerptLength is the length of description excerpts in highlights
+	searchExcerptLength = 200
+)
+
+// SearchResponse is the result of a full-text search, grouped by event
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []*SearchGroup `json:"results"`
+	Total   int            `json:"total"` // Number of groups before the limit
+}
+
+// SearchGroup is an event with its matching parts. Parts without an event form
+// a group of their own with no event.
+type SearchGroup struct {
+	Event      *storage.Event    `json:"event,omitempty"`
+	Score      float64           `json:"score"`                // Best score of the event and its parts
+	Highlights map[string]string `json:"highlights,omitempty"` // Matching event titles by language
+	Parts      []*SearchPartHit  `json:"parts"`
+}
+
+// SearchPartHit is a matching part with its highlighted fields
+type SearchPartHit struct {
+	Part       *storage.LessonPart `json:"part"`
+	Score      float64             `json:"score"`
+	Highlights PartHighlights      `json:"highlights"`
+}
+
+// PartHighlights holds the matching fields of a part with matches wrapped in <mark> tags
+type PartHighlights struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"` // Excerpt around the first match
+	Sources     []string `json:"sources,omitempty"`     // Matching source titles
+}
+
+// HandleSearch searches part titles, descriptions, source titles and event titles
+// Query parameters:
+//   - q (string): search text (required)
+//   - language (string): only parts in this language, stemmed by its rules
+//   - from, to (string): only events and parts dated within this range (YYYY-MM-DD)
+//   - type (string): only events of this type
+//   - limit (int): maximum number of event groups (default 20, max 100)
+//
+// Draft events are only searched for trusted requests.
+func (a *App) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	a.search(w, r, !a.isTrustedRequest(r))
+}
+
+// HandlePublicSearch searches like HandleSearch but only within public events
+func (a *App) HandlePublicSearch(w http.ResponseWriter, r *http.Request) {
+	a.search(w, r, true)
+}
+
+func (a *App) search(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	params := r.URL.Query()
+
+	text := strings.TrimSpace(params.Get("q"))
+	terms := storage.SearchTerms(text)
+	if len(terms) == 0 {
+		writeFieldError(w, r, codeInvalidParameter, "q", "Query parameter q is required")
+		return
+	}
+
+	query := storage.SearchQuery{
+		Text:       text,
+		Language:   params.Get("language"),
+		EventType:  params.Get("type"),
+		PublicOnly: publicOnly,
+		Limit:      searchCandidates,
+	}
+	if from := params.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "from", "Invalid from date, use YYYY-MM-DD")
+			return
+		}
+		query.FromDate = &date
+	}
+	if to := params.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "to", "Invalid to date, use YYYY-MM-DD")
+			return
+		}
+		// Include the whole end day
+		endOfDay := date.AddDate(0, 0, 1).Add(-time.Millisecond)
+		query.ToDate = &endOfDay
+	}
+	limit := defaultSearchLimit
+	if limitStr := params.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			writeFieldError(w, r, codeInvalidParameter, "limit", "Invalid limit")
+			return
+		}
+		limit = min(parsed, maxSearchLimit)
+	}
+
+	eventHits, err := a.eventStore.SearchEvents(r.Context(), query)
+	if err != nil {
+		writeInternalError(w, r, err, "Search failed")
+		return
+	}
+	partHits, err := a.store.SearchParts(r.Context(), query)
+	if err != nil {
+		writeInternalError(w, r, err, "Search failed")
+		return
+	}
+
+	groups := make(map[string]*SearchGroup)
+	for _, hit := range eventHits {
+		groups[hit.Event.ID] = &SearchGroup{
+			Event:      hit.Event,
+			Score:      hit.Score,
+			Highlights: eventHighlights(hit.Event, terms),
+			Parts:      []*SearchPartHit{},
+		}
+	}
+
+	// Load the events of matching parts that didn't match themselves, with the
+	// same type, public and date constraints
+	var missing []string
+	for _, hit := range partHits {
+		if hit.Part.EventID != "" && groups[hit.Part.EventID] == nil && !containsID(missing, hit.Part.EventID) {
+			missing = append(missing, hit.Part.EventID)
+		}
+	}
+	events := make(map[string]*storage.Event)
+	if len(missing) > 0 {
+		filter := query.EventFilter()
+		filter["_id"] = bson.M{"$in": missing}
+		found, _, err := a.eventStore.ListEventsFiltered(r.Context(), filter, 0, 0)
+		if err != nil {
+			writeInternalError(w, r, err, "Search failed")
+			return
+		}
+		for i := range found {
+			events[found[i].ID] = &found[i]
+		}
+	}
+
+	for _, hit := range partHits {
+		key := hit.Part.EventID
+		group := groups[key]
+		if key == "" {
+			// Parts without an event have no type and are never public
+			if query.EventType != "" || publicOnly {
+				continue
+			}
+			key = "part:" + hit.Part.ID
+			group = &SearchGroup{}
+			groups[key] = group
+		} else if group == nil {
+			event, ok := events[key]
+			if !ok {
+				continue
+			}
+			group = &SearchGroup{Event: event}
+			groups[key] = group
+		}
+
+		group.Parts = append(group.Parts, &SearchPartHit{
+			Part:       hit.Part,
+			Score:      hit.Score,
+			Highlights: partHighlights(hit.Part, terms),
+		})
+		group.Score = max(group.Score, hit.Score)
+	}
+
+	results := make([]*SearchGroup, 0, len(groups))
+	for _, group := range groups {
+		results = append(results, group)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return groupDate(results[i]).After(groupDate(results[j]))
+	})
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SearchResponse{
+		Query:   text,
+		Results: results,
+		Total:   total,
+	})
+}
+
+// eventHighlights returns the event's titles that match, by language
+func eventHighlights(event *storage.Event, terms []string) map[string]string {
+	highlights := make(map[string]string)
+	for lang, title := range event.Titles {
+		if h := storage.Highlight(title, terms, 0); h != "" {
+			highlights[lang] = h
+		}
+	}
+	return highlights
+}
+
+// partHighlights returns the part's fields that match
+func partHighlights(part *storage.LessonPart, terms []string) PartHighlights {
+	highlights := PartHighlights{
+		Title:       storage.Highlight(part.Title, terms, 0),
+		Description: storage.Highlight(part.Description, terms, searchExcerptLength),
+	}
+	for _, source := range part.Sources {
+		if h := storage.Highlight(source.SourceTitle, terms, 0); h != "" {
+			highlights.Sources = append(highlights.Sources, h)
+		}
+	}
+	return highlights
+}
+
+// groupDate returns the date a search group is ordered by when scores tie
+func groupDate(group *SearchGroup) time.Time {
+	if group.Event != nil {
+		return group.Event.Date
+	}
+	if len(group.Parts) > 0 {
+		return group.Parts[0].Part.Date
+	}
+	return time.Time{}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
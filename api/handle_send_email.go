@@ -2,11 +2,12 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -27,9 +28,13 @@ func (a *App) HandleSendEventEmail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get event from database
-	event, err := a.eventStore.GetEvent(eventID)
+	event, err := a.eventStore.GetEvent(r.Context(), eventID)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+			return
+		}
+		writeInternalError(w, r, err, "Failed to get event")
 		return
 	}
 
@@ -58,7 +63,7 @@ func (a *App) HandleSendEventEmail(w http.ResponseWriter, r *http.Request) {
 	err = a.emailService.SendEventEmail(eventID, titleHe, titleEn, event.Date, req.IsUpdate)
 	if err != nil {
 		log.Printf("Failed to send email for event %s: %v", eventID, err)
-		http.Error(w, fmt.Sprintf("Failed to send email: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to send email")
 		return
 	}
 
@@ -66,9 +71,10 @@ func (a *App) HandleSendEventEmail(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	if event.EmailSentAt == nil {
 		event.EmailSentAt = &now
+		event.UpdatedBy = actorFromRequest(r)
 
 		// Save updated event
-		err = a.eventStore.SaveEvent(event)
+		err = a.eventStore.SaveEvent(r.Context(), event)
 		if err != nil {
 			log.Printf("Failed to update event after sending email: %v", err)
 			// Email was sent, so still return success
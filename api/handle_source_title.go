@@ -12,7 +12,7 @@ func (a *App) HandleGetSourceTitle(w http.ResponseWriter, r *http.Request) {
 	language := r.URL.Query().Get("language")
 
 	if sourceID == "" {
-		http.Error(w, "Source ID is required", http.StatusBadRequest)
+		writeFieldError(w, r, codeInvalidParameter, "id", "Source ID is required")
 		return
 	}
 
@@ -21,9 +21,9 @@ func (a *App) HandleGetSourceTitle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the source title in the requested language
-	title, err := a.kabbalahmediaClient.GetSourceTitle(sourceID, language)
+	title, err := a.kabbalahmediaClient.GetSourceTitle(r.Context(), sourceID, language)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get source title: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Failed to get source title")
 		return
 	}
 
@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
@@ -19,9 +18,9 @@ func (a *App) HandleSearchSources(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Search using kabbalahmedia client
-	sources, err := a.kabbalahmediaClient.SearchSources(query)
+	sources, err := a.kabbalahmediaClient.SearchSources(r.Context(), query)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Search failed: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Search failed")
 		return
 	}
 
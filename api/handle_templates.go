@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Bnei-Baruch/study-material-service/storage"
 	"github.com/gorilla/mux"
@@ -12,6 +14,9 @@ import (
 
 // HandleGetTemplates returns the template configuration
 func (a *App) HandleGetTemplates(w http.ResponseWriter, r *http.Request) {
+	if a.notModified(w, r, versionETag(a.templateConfig.Version), time.Time{}) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(a.templateConfig)
 }
@@ -25,6 +30,12 @@ func (a *App) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
+	// Reject changes based on an outdated copy of the template configuration
+	if !ifMatch(r, a.templateConfig.Version) {
+		writePreconditionFailed(w, r, a.templateConfig, a.templateConfig.Version)
+		return
+	}
+
 	var req struct {
 		ID           string            `json:"id"`
 		Translations map[string]string `json:"translations"`
@@ -32,15 +43,13 @@ func (a *App) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
 	// Validate
 	if req.ID == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Template ID is required"})
+		writeFieldError(w, r, codeValidationFailed, "id", "Template ID is required")
 		return
 	}
 
@@ -49,19 +58,15 @@ func (a *App) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
 	// Check for duplicate ID
 	for _, t := range a.templateConfig.Templates {
 		if t.ID == req.ID {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Template ID already exists"})
+			writeError(w, r, http.StatusBadRequest, codeTemplateExists, "Template ID already exists")
 			return
 		}
 	}
 
 	// Validate all languages have translations
-	for _, lang := range a.templateConfig.Languages {
-		if translation, ok := req.Translations[lang]; !ok || strings.TrimSpace(translation) == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Missing translation for language: " + lang})
-			return
-		}
+	if invalid := a.missingTranslations(req.Translations); invalid != nil {
+		writeValidationError(w, r, invalid)
+		return
 	}
 
 	// Create template
@@ -74,13 +79,16 @@ func (a *App) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
 	a.templateConfig.Templates = append(a.templateConfig.Templates, newTemplate)
 
 	// Save to MongoDB
-	if err := a.templateStore.SaveConfig(a.templateConfig); err != nil {
-		log.Printf("Error saving templates to MongoDB: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save template"})
+	if err := a.templateStore.SaveConfig(r.Context(), a.templateConfig); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeTemplateConflict(w, r)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to save template")
 		return
 	}
 
+	setETag(w, a.templateConfig.Version)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(newTemplate)
 }
@@ -94,6 +102,12 @@ func (a *App) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
+	// Reject changes based on an outdated copy of the template configuration
+	if !ifMatch(r, a.templateConfig.Version) {
+		writePreconditionFailed(w, r, a.templateConfig, a.templateConfig.Version)
+		return
+	}
+
 	vars := mux.Vars(r)
 	templateID := vars["id"]
 
@@ -103,8 +117,7 @@ func (a *App) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
 		return
 	}
 
@@ -113,12 +126,9 @@ func (a *App) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
 	for i, t := range a.templateConfig.Templates {
 		if t.ID == templateID {
 			// Validate all languages have translations
-			for _, lang := range a.templateConfig.Languages {
-				if translation, ok := req.Translations[lang]; !ok || strings.TrimSpace(translation) == "" {
-					w.WriteHeader(http.StatusBadRequest)
-					json.NewEncoder(w).Encode(map[string]string{"error": "Missing translation for language: " + lang})
-					return
-				}
+			if invalid := a.missingTranslations(req.Translations); invalid != nil {
+				writeValidationError(w, r, invalid)
+				return
 			}
 
 			a.templateConfig.Templates[i].Translations = req.Translations
@@ -130,20 +140,22 @@ func (a *App) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !found {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Template not found"})
+		writeError(w, r, http.StatusNotFound, codeTemplateNotFound, "Template not found")
 		return
 	}
 
 	// Save to MongoDB
-	if err := a.templateStore.SaveConfig(a.templateConfig); err != nil {
-		log.Printf("Error saving templates to MongoDB: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save template"})
+	if err := a.templateStore.SaveConfig(r.Context(), a.templateConfig); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeTemplateConflict(w, r)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to save template")
 		return
 	}
 
 	// Return updated template
+	setETag(w, a.templateConfig.Version)
 	for _, t := range a.templateConfig.Templates {
 		if t.ID == templateID {
 			json.NewEncoder(w).Encode(t)
@@ -161,6 +173,12 @@ func (a *App) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
+	// Reject changes based on an outdated copy of the template configuration
+	if !ifMatch(r, a.templateConfig.Version) {
+		writePreconditionFailed(w, r, a.templateConfig, a.templateConfig.Version)
+		return
+	}
+
 	vars := mux.Vars(r)
 	templateID := vars["id"]
 
@@ -175,19 +193,21 @@ func (a *App) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !found {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Template not found"})
+		writeError(w, r, http.StatusNotFound, codeTemplateNotFound, "Template not found")
 		return
 	}
 
 	// Save to MongoDB
-	if err := a.templateStore.SaveConfig(a.templateConfig); err != nil {
-		log.Printf("Error saving templates to MongoDB: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save template"})
+	if err := a.templateStore.SaveConfig(r.Context(), a.templateConfig); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeTemplateConflict(w, r)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to save template")
 		return
 	}
 
+	setETag(w, a.templateConfig.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Template deleted successfully"})
 }
@@ -205,14 +225,12 @@ func (a *App) HandleSyncTemplates(w http.ResponseWriter, r *http.Request) {
 	// Load fresh templates from JSON file
 	jsonConfig, err := storage.LoadTemplates("templates.json")
 	if err != nil {
-		log.Printf("Error loading templates from JSON: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to load templates.json"})
+		writeInternalError(w, r, err, "Failed to load templates.json")
 		return
 	}
 
 	// Get current config from database
-	currentConfig, err := a.templateStore.GetConfig()
+	currentConfig, err := a.templateStore.GetConfig(r.Context())
 	if err != nil {
 		log.Printf("Error getting current config: %v", err)
 		// If no config exists, use the JSON config
@@ -258,16 +276,19 @@ func (a *App) HandleSyncTemplates(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save merged config back to database
-	if err := a.templateStore.SaveConfig(currentConfig); err != nil {
-		log.Printf("Error saving synced config: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save synced templates"})
+	if err := a.templateStore.SaveConfig(r.Context(), currentConfig); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			a.writeTemplateConflict(w, r)
+			return
+		}
+		writeInternalError(w, r, err, "Failed to save synced templates")
 		return
 	}
 
 	// Update in-memory config
 	a.templateConfig = currentConfig
 
+	setETag(w, currentConfig.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message":       "Templates synced successfully",
@@ -275,3 +296,26 @@ func (a *App) HandleSyncTemplates(w http.ResponseWriter, r *http.Request) {
 		"languages":     currentConfig.Languages,
 	})
 }
+
+// missingTranslations lists the configured languages a template has no translation for
+func (a *App) missingTranslations(translations map[string]string) validationError {
+	var invalid validationError
+	for _, lang := range a.templateConfig.Languages {
+		if translation, ok := translations[lang]; !ok || strings.TrimSpace(translation) == "" {
+			invalid.add("translations."+lang, "Missing translation for language: "+lang)
+		}
+	}
+	return invalid
+}
+
+// writeTemplateConflict reloads the template configuration after a save lost a race
+// with another writer and responds 412 with the stored configuration
+func (a *App) writeTemplateConflict(w http.ResponseWriter, r *http.Request) {
+	current, err := a.templateStore.GetConfig(r.Context())
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to load templates")
+		return
+	}
+	a.templateConfig = current
+	writePreconditionFailed(w, r, current, current.Version)
+}
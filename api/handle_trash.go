@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TrashedEvent is an event in the trash along with the parts trashed with it
+type TrashedEvent struct {
+	storage.Event
+	PartsCount int       `json:"parts_count"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// TrashedPart is a part that was trashed on its own (not together with its event)
+type TrashedPart struct {
+	storage.LessonPart
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TrashResponse lists the contents of the trash
+type TrashResponse struct {
+	Events        []TrashedEvent `json:"events"`
+	Parts         []TrashedPart  `json:"parts"`
+	RetentionDays int            `json:"retention_days"`
+}
+
+// HandleListTrash lists trashed events and individually trashed parts, most recent first
+// Parts trashed together with their event are counted under the event instead of listed.
+func (a *App) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	if !a.isTrustedRequest(r) {
+		writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+
+	events, _, err := a.eventStore.ListEventsFiltered(r.Context(), bson.M{"deleted_at": bson.M{"$exists": true}}, 0, 0)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list trashed events")
+		return
+	}
+
+	parts, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{
+		Trashed: storage.OnlyTrashed,
+		Sort:    []storage.SortField{{Field: "deleted_at", Desc: true}, {Field: "order"}, {Field: "language"}},
+	})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list trashed parts")
+		return
+	}
+
+	response := TrashResponse{
+		Events:        []TrashedEvent{},
+		Parts:         []TrashedPart{},
+		RetentionDays: int(a.trashRetention.Hours() / 24),
+	}
+
+	for _, event := range events {
+		response.Events = append(response.Events, TrashedEvent{
+			Event:     event,
+			ExpiresAt: event.DeletedAt.Add(a.trashRetention),
+		})
+	}
+	sort.SliceStable(response.Events, func(i, j int) bool {
+		return response.Events[i].DeletedAt.After(*response.Events[j].DeletedAt)
+	})
+	eventIndex := make(map[string]int, len(response.Events))
+	for i, event := range response.Events {
+		eventIndex[event.ID] = i
+	}
+
+	for _, part := range parts {
+		if i, ok := eventIndex[part.EventID]; ok {
+			trashedEvent := &response.Events[i]
+			if trashedEvent.DeletedAt.Equal(*part.DeletedAt) {
+				trashedEvent.PartsCount++
+				continue
+			}
+		}
+		response.Parts = append(response.Parts, TrashedPart{
+			LessonPart: *part,
+			ExpiresAt:  part.DeletedAt.Add(a.trashRetention),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleRestoreEvent restores a trashed event together with the parts that were trashed with it
+// Parts that had been trashed separately before the event stay in the trash.
+func (a *App) HandleRestoreEvent(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID := vars["id"]
+
+	event, err := a.getTrashedEvent(r.Context(), eventID)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+	if event == nil {
+		writeError(w, r, http.StatusNotFound, codeEventNotFound, "Trashed event not found")
+		return
+	}
+	deletion := storage.Deletion{At: *event.DeletedAt, By: event.DeletedBy}
+
+	restoredParts := 0
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		restoredParts = 0
+
+		parts, _, err := tx.Parts.ListPartsFiltered(ctx, storage.PartQuery{EventID: eventID, Trashed: storage.OnlyTrashed})
+		if err != nil {
+			return err
+		}
+
+		for _, part := range parts {
+			if !deletion.Matches(part.DeletedAt) {
+				continue
+			}
+			if err := tx.Parts.RestorePart(ctx, part.ID); err != nil {
+				return err
+			}
+			restoredParts++
+		}
+
+		return tx.Events.RestoreEvent(ctx, eventID)
+	})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to restore event, nothing was restored")
+		return
+	}
+
+	fmt.Printf("Restored event %s and %d parts\n", eventID, restoredParts)
+
+	// Restoring changed the event's version, answer with it as stored now
+	restoredEvent, err := a.eventStore.GetEvent(r.Context(), eventID)
+	if err != nil {
+		writeInternalError(w, r, err, "Event restored but failed to read it back")
+		return
+	}
+
+	setETag(w, restoredEvent.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"event":          restoredEvent,
+		"restored_parts": restoredParts,
+	})
+}
+
+// HandleRestorePart restores a trashed part together with the translations trashed with it
+// (the same translation group and deletion time). Parts trashed together with their event can only
+// be restored by restoring the event.
+func (a *App) HandleRestorePart(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	found, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{IDs: []string{id}, Trashed: storage.OnlyTrashed})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+	if len(found) == 0 {
+		writeError(w, r, http.StatusNotFound, codePartNotFound, "Trashed part not found")
+		return
+	}
+	part := found[0]
+	deletion := storage.Deletion{At: *part.DeletedAt, By: part.DeletedBy}
+
+	if part.EventID != "" {
+		event, err := a.getTrashedEvent(r.Context(), part.EventID)
+		if err != nil {
+			writeInternalError(w, r, err, "Failed to get event")
+			return
+		}
+		if event != nil {
+			writeError(w, r, http.StatusConflict, codeEventTrashed, fmt.Sprintf("The part's event %s is in the trash, restore the event first", part.EventID))
+			return
+		}
+	}
+
+	var restoredIDs []string
+	err = a.transactor.RunInTransaction(r.Context(), func(ctx context.Context, tx storage.Tx) error {
+		restoredIDs = nil
+
+		query := storage.TranslationsQuery(part)
+		query.Trashed = storage.OnlyTrashed
+		siblings, _, err := tx.Parts.ListPartsFiltered(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range siblings {
+			if !deletion.Matches(p.DeletedAt) {
+				continue
+			}
+			if err := tx.Parts.RestorePart(ctx, p.ID); err != nil {
+				return err
+			}
+			restoredIDs = append(restoredIDs, p.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to restore part, nothing was restored")
+		return
+	}
+
+	fmt.Printf("Restored part %s and %d translations\n", id, len(restoredIDs)-1)
+
+	// Restoring changed the parts' versions, answer with them as stored now
+	restored, _, err := a.store.ListPartsFiltered(r.Context(), storage.PartQuery{IDs: restoredIDs})
+	if err != nil {
+		writeInternalError(w, r, err, "Parts restored but failed to read them back")
+		return
+	}
+	for _, p := range restored {
+		if p.ID == id {
+			setETag(w, p.Version)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"parts":          restored,
+		"restored_parts": len(restored),
+	})
+}
+
+// getTrashedEvent returns the event if it is in the trash, or nil if it isn't
+func (a *App) getTrashedEvent(ctx context.Context, id string) (*storage.Event, error) {
+	events, _, err := a.eventStore.ListEventsFiltered(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
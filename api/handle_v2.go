@@ -0,0 +1,401 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// defaultV2EventsLimit and maxV2EventsLimit bound the number of events in a page
+	defaultV2EventsLimit = 50
+	maxV2EventsLimit     = 200
+)
+
+// V2EventList is a page of events
+type V2EventList struct {
+	Events   []*V2Event `json:"events"`
+	Total    int        `json:"total"`
+	Returned int        `json:"returned"`
+	Limit    int        `json:"limit"`
+	Offset   int        `json:"offset"`
+}
+
+// V2PartList is the parts of an event, in order
+type V2PartList struct {
+	EventID string    `json:"event_id"`
+	Parts   []*V2Part `json:"parts"`
+}
+
+// v2Params are the query parameters every v2 read accepts
+type v2Params struct {
+	expand    map[string]bool
+	fields    fieldSet
+	languages []string
+}
+
+// parseV2Params reads ?expand=, ?fields= and ?language=, checking the fields
+// against resource, the type of the returned resources. It responds 400 and
+// returns false if they are invalid.
+func parseV2Params(w http.ResponseWriter, r *http.Request, resource reflect.Type, expandable ...string) (v2Params, bool) {
+	query := r.URL.Query()
+	var params v2Params
+	var err error
+
+	if params.expand, err = parseExpand(query.Get("expand"), expandable...); err != nil {
+		writeFieldError(w, r, codeInvalidParameter, "expand", err.Error())
+		return params, false
+	}
+	if params.fields, err = parseFields(query.Get("fields")); err == nil {
+		err = params.fields.validate(resource, "")
+	}
+	if err != nil {
+		writeFieldError(w, r, codeInvalidParameter, "fields", err.Error())
+		return params, false
+	}
+	params.languages = parseLanguages(query.Get("language"))
+	return params, true
+}
+
+// v2PartSet is the parts of some events
+type v2PartSet struct {
+	groups       map[string][]*storage.PartGroup // Live parts by event ID, in order
+	parts        []*storage.LessonPart           // Live parts
+	lastModified time.Time                       // Latest change to any part, moving one to the trash included
+}
+
+// loadV2Parts loads the parts of events in every language
+func (a *App) loadV2Parts(ctx context.Context, eventIDs []string) (*v2PartSet, error) {
+	set := &v2PartSet{groups: make(map[string][]*storage.PartGroup)}
+	if len(eventIDs) == 0 {
+		return set, nil
+	}
+
+	parts, _, err := a.store.ListPartsFiltered(ctx, storage.PartQuery{EventIDs: eventIDs, Trashed: storage.IncludeTrashed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+	byEvent := make(map[string][]*storage.LessonPart)
+	for _, p := range parts {
+		set.lastModified = latest(set.lastModified, p.LastModified())
+		if p.DeletedAt != nil {
+			continue
+		}
+		set.parts = append(set.parts, p)
+		byEvent[p.EventID] = append(byEvent[p.EventID], p)
+	}
+	for eventID, eventParts := range byEvent {
+		set.groups[eventID] = storage.GroupEventParts(eventParts)
+	}
+	return set, nil
+}
+
+// add records the parts in an ETag
+func (s *v2PartSet) add(etag *listETag) {
+	for _, p := range s.parts {
+		etag.add("part", p.ID, p.Version)
+	}
+}
+
+// getV2Event returns an event, or responds 404 if it doesn't exist or, for
+// untrusted requests, isn't public
+func (a *App) getV2Event(w http.ResponseWriter, r *http.Request, id string) (*storage.Event, bool) {
+	event, err := a.eventStore.GetEvent(r.Context(), id)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		writeInternalError(w, r, err, "Failed to get event")
+		return nil, false
+	}
+	if err != nil || (!event.Public && !a.isTrustedRequest(r)) {
+		writeError(w, r, http.StatusNotFound, codeEventNotFound, "Event not found")
+		return nil, false
+	}
+	return event, true
+}
+
+// HandleV2ListEvents lists events, newest first within the same order
+// Query parameters:
+//   - public (bool): only public or only draft events; untrusted requests only get public ones
+//   - type (string): only events of this type
+//   - from, to (string): only events on or between these dates (YYYY-MM-DD)
+//   - limit (int): maximum number of events (default 50, max 200)
+//   - offset (int): number of events to skip
+//   - expand (string): "parts" to include each event's parts
+//   - fields (string): comma-separated event fields to return, e.g. "date,parts.content.title"
+//   - language (string): comma-separated languages of part content to return
+func (a *App) HandleV2ListEvents(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseV2Params(w, r, reflect.TypeOf(V2Event{}), "parts")
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+
+	filter := bson.M{}
+	switch public := query.Get("public"); public {
+	case "":
+	case "true", "false":
+		filter["public"] = public == "true"
+	default:
+		writeFieldError(w, r, codeInvalidParameter, "public", "Invalid public, use true or false")
+		return
+	}
+	trusted := a.isTrustedRequest(r)
+	if !trusted {
+		filter["public"] = true
+	}
+	if eventType := query.Get("type"); eventType != "" {
+		filter["type"] = eventType
+	}
+
+	dateFilter := bson.M{}
+	if from := query.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "from", "Invalid from date, use YYYY-MM-DD")
+			return
+		}
+		dateFilter["$gte"] = date
+	}
+	if to := query.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			writeFieldError(w, r, codeInvalidDate, "to", "Invalid to date, use YYYY-MM-DD")
+			return
+		}
+		dateFilter["$lt"] = date.AddDate(0, 0, 1)
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	limit := defaultV2EventsLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			writeFieldError(w, r, codeInvalidParameter, "limit", "Invalid limit")
+			return
+		}
+		limit = min(parsed, maxV2EventsLimit)
+	}
+	offset := 0
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		parsed, err := strconv.Atoi(offsetStr)
+		if err != nil || parsed < 0 {
+			writeFieldError(w, r, codeInvalidParameter, "offset", "Invalid offset")
+			return
+		}
+		offset = parsed
+	}
+
+	events, total, err := a.eventStore.ListEventsFiltered(r.Context(), filter, limit, offset)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list events")
+		return
+	}
+	eventIDs := make([]string, len(events))
+	for i, e := range events {
+		eventIDs[i] = e.ID
+	}
+	parts, err := a.loadV2Parts(r.Context(), eventIDs)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list parts")
+		return
+	}
+
+	etag := newListETag(r.URL.RawQuery, strconv.FormatBool(trusted), strconv.Itoa(total))
+	for _, e := range events {
+		etag.add("event", e.ID, e.Version)
+	}
+	parts.add(etag)
+	if a.notModified(w, r, etag.String(), time.Time{}) {
+		return
+	}
+
+	response := V2EventList{
+		Events:   make([]*V2Event, 0, len(events)),
+		Total:    total,
+		Returned: len(events),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for i := range events {
+		response.Events = append(response.Events, newV2Event(&events[i], parts.groups[events[i].ID], params.expand["parts"], params.languages))
+	}
+	writeV2(w, r, response, params.fields.within(reflect.TypeOf(response), "events"))
+}
+
+// HandleV2GetEvent returns an event
+// Query parameters:
+//   - expand (string): "parts" to include the event's parts
+//   - fields (string): comma-separated fields to return
+//   - language (string): comma-separated languages of part content to return
+func (a *App) HandleV2GetEvent(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseV2Params(w, r, reflect.TypeOf(V2Event{}), "parts")
+	if !ok {
+		return
+	}
+	event, ok := a.getV2Event(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	parts, err := a.loadV2Parts(r.Context(), []string{event.ID})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list parts")
+		return
+	}
+
+	etag := newListETag(r.URL.RawQuery)
+	etag.add("event", event.ID, event.Version)
+	parts.add(etag)
+	if a.notModified(w, r, etag.String(), latest(event.LastModified(), parts.lastModified)) {
+		return
+	}
+	writeV2(w, r, newV2Event(event, parts.groups[event.ID], params.expand["parts"], params.languages), params.fields)
+}
+
+// HandleV2ListEventParts returns the parts of an event, in order
+// Query parameters:
+//   - fields (string): comma-separated part fields to return
+//   - language (string): comma-separated languages of content to return
+func (a *App) HandleV2ListEventParts(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseV2Params(w, r, reflect.TypeOf(V2Part{}))
+	if !ok {
+		return
+	}
+	event, ok := a.getV2Event(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	parts, err := a.loadV2Parts(r.Context(), []string{event.ID})
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to list parts")
+		return
+	}
+
+	etag := newListETag(r.URL.RawQuery)
+	parts.add(etag)
+	if a.notModified(w, r, etag.String(), parts.lastModified) {
+		return
+	}
+
+	groups := parts.groups[event.ID]
+	response := V2PartList{EventID: event.ID, Parts: make([]*V2Part, 0, len(groups))}
+	for _, group := range groups {
+		response.Parts = append(response.Parts, newV2Part(group, params.languages))
+	}
+	writeV2(w, r, response, params.fields.within(reflect.TypeOf(response), "parts"))
+}
+
+// HandleV2GetPart returns a part with its content in every language. The ID is
+// a v2 part ID, or the v1 ID of any of its language versions.
+// Query parameters:
+//   - expand (string): "event" to include the part's event
+//   - fields (string): comma-separated fields to return
+//   - language (string): comma-separated languages of content to return
+func (a *App) HandleV2GetPart(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseV2Params(w, r, reflect.TypeOf(V2Part{}), "event")
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	group, err := a.findV2Part(r.Context(), id)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+	if group == nil {
+		writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+		return
+	}
+
+	// Untrusted requests only see parts of public events
+	reference := group.Reference()
+	var event *storage.Event
+	if reference.EventID != "" {
+		if event, err = a.eventStore.GetEvent(r.Context(), reference.EventID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			writeInternalError(w, r, err, "Failed to get event")
+			return
+		}
+	}
+	if (event == nil || !event.Public) && !a.isTrustedRequest(r) {
+		writeError(w, r, http.StatusNotFound, codePartNotFound, "Part not found")
+		return
+	}
+
+	etag := newListETag(r.URL.RawQuery)
+	var lastModified time.Time
+	for _, p := range group.Parts {
+		etag.add("part", p.ID, p.Version)
+		lastModified = latest(lastModified, p.LastModified())
+	}
+	var eventParts *v2PartSet
+	if params.expand["event"] && event != nil {
+		if eventParts, err = a.loadV2Parts(r.Context(), []string{event.ID}); err != nil {
+			writeInternalError(w, r, err, "Failed to list parts")
+			return
+		}
+		etag.add("event", event.ID, event.Version)
+		eventParts.add(etag)
+		lastModified = latest(lastModified, event.LastModified(), eventParts.lastModified)
+	}
+	if a.notModified(w, r, etag.String(), lastModified) {
+		return
+	}
+
+	part := newV2Part(group, params.languages)
+	if eventParts != nil {
+		part.Event = newV2Event(event, eventParts.groups[event.ID], false, nil)
+	}
+	writeV2(w, r, part, params.fields)
+}
+
+// findV2Part returns the live part group with a v2 part ID or a v1 part ID,
+// or nil if there is none
+func (a *App) findV2Part(ctx context.Context, id string) (*storage.PartGroup, error) {
+	parts, _, err := a.store.ListPartsFiltered(ctx, storage.PartQuery{TranslationGroupID: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list translations: %w", err)
+	}
+	if len(parts) > 0 {
+		return storage.GroupEventParts(parts)[0], nil
+	}
+
+	part, err := a.store.GetPart(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get part: %w", err)
+	}
+	if parts, _, err = a.store.ListPartsFiltered(ctx, storage.TranslationsQuery(part)); err != nil {
+		return nil, fmt.Errorf("failed to list translations: %w", err)
+	}
+	for _, group := range storage.GroupEventParts(parts) {
+		for _, p := range group.Parts {
+			if p.ID == part.ID {
+				return group, nil
+			}
+		}
+	}
+	return &storage.PartGroup{Key: part.ID, Order: part.Order, Parts: []*storage.LessonPart{part}}, nil
+}
+
+// writeV2 writes a v2 response with only the selected fields
+func writeV2(w http.ResponseWriter, r *http.Request, body interface{}, fields fieldSet) {
+	selected, err := selectFields(body, fields)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to select fields")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(selected)
+}
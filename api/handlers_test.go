@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/Bnei-Baruch/study-material-service/storage/memory"
+)
+
+const testAPIKey = "secret"
+
+// newTestStore returns an in-memory store with the morning_lesson event type
+func newTestStore(t *testing.T) *memory.Store {
+	t.Helper()
+	s := memory.NewStore()
+	if err := s.CreateEventType(context.Background(), &storage.EventType{Name: "morning_lesson"}); err != nil {
+		t.Fatalf("CreateEventType: %v", err)
+	}
+	return s
+}
+
+// newTestServer serves the API over a new in-memory store. Requests made with
+// httptest come from 192.0.2.1, which is not trusted without the API key.
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	s := newTestStore(t)
+	return serveStore(s, s)
+}
+
+// serveStore serves the API over s, running units of work with transactor
+func serveStore(s *memory.Store, transactor storage.Transactor) http.Handler {
+	app := NewApp(s, s, s, s, s, transactor, nil, &storage.TemplateConfig{}, testAPIKey, 30*24*time.Hour, nil, CacheControl{}, time.Time{}, GraphQLLimits{})
+	app.initRouters()
+	return requestIDMiddleware(app.apiKeyMiddleware(app.router))
+}
+
+var errTransient = errors.New("transient error")
+
+// retryingTransactor aborts the first attempt of every unit of work once it has
+// succeeded and runs it again, like the MongoDB driver after a transient error
+// on commit
+type retryingTransactor struct {
+	*memory.Store
+}
+
+func (t retryingTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context, tx storage.Tx) error) error {
+	err := t.Store.RunInTransaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) {
+		return err
+	}
+	return t.Store.RunInTransaction(ctx, fn)
+}
+
+func serve(t *testing.T, h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding error response %q: %v", rec.Body.String(), err)
+	}
+	return resp.Error.Code
+}
+
+func TestEventLifecycle(t *testing.T) {
+	h := newTestServer(t)
+	withKey := map[string]string{"X-API-Key": testAPIKey}
+
+	rec := serve(t, h, http.MethodPost, "/api/events", `{"date":"2026-03-01","type":"morning_lesson","number":1}`, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("create without key: status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = serve(t, h, http.MethodPost, "/api/events", `{"date":"2026-03-01","type":"morning_lesson","number":1}`, withKey)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var event storage.Event
+	if err := json.Unmarshal(rec.Body.Bytes(), &event); err != nil {
+		t.Fatalf("decoding event: %v", err)
+	}
+
+	rec = serve(t, h, http.MethodGet, "/api/events/"+event.ID, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("get: no ETag")
+	}
+
+	// A weak tag must not satisfy If-Match
+	header := map[string]string{"X-API-Key": testAPIKey, "If-Match": "W/" + etag}
+	rec = serve(t, h, http.MethodPut, "/api/events/"+event.ID, `{"public":true}`, header)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("update with weak If-Match: status %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+
+	header["If-Match"] = etag
+	rec = serve(t, h, http.MethodPut, "/api/events/"+event.ID, `{"public":true}`, header)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	rec = serve(t, h, http.MethodDelete, "/api/events/"+event.ID, "", withKey)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(t, h, http.MethodGet, "/api/events/"+event.ID, "", withKey)
+	if rec.Code != http.StatusNotFound || errorCode(t, rec) != codeEventNotFound {
+		t.Errorf("get trashed event: status %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminReadsNeedTrust(t *testing.T) {
+	h := newTestServer(t)
+	for _, target := range []string{"/api/trash", "/api/events/e1/revisions"} {
+		rec := serve(t, h, http.MethodGet, target, "", nil)
+		if rec.Code != http.StatusUnauthorized || errorCode(t, rec) != codeUnauthorized {
+			t.Errorf("GET %s without key: status %d, body %s", target, rec.Code, rec.Body.String())
+		}
+	}
+	rec := serve(t, h, http.MethodGet, "/api/trash", "", map[string]string{"X-API-Key": testAPIKey})
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /api/trash with key: status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMissingDocuments(t *testing.T) {
+	h := newTestServer(t)
+	tests := []struct {
+		target string
+		code   string
+	}{
+		{"/api/events/missing", codeEventNotFound},
+		{"/api/parts/missing", codePartNotFound},
+	}
+	for _, tt := range tests {
+		rec := serve(t, h, http.MethodGet, tt.target, "", nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want %d", tt.target, rec.Code, http.StatusNotFound)
+			continue
+		}
+		if code := errorCode(t, rec); code != tt.code {
+			t.Errorf("GET %s: code %q, want %q", tt.target, code, tt.code)
+		}
+	}
+}
+
+// TestRetriedUnitsOfWork runs every unit of work twice, so writes of an aborted
+// first attempt must not leak into the second
+func TestRetriedUnitsOfWork(t *testing.T) {
+	s := newTestStore(t)
+	date := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	s.Seed([]*storage.LessonPart{
+		{ID: "he1", EventID: "e1", Language: "he", Order: 1, Date: date, Title: "Part", PartType: "live_lesson", TranslationGroupID: "g1", Version: 3},
+		{ID: "en1", EventID: "e1", Language: "en", Order: 1, Date: date, Title: "Part", PartType: "live_lesson", TranslationGroupID: "g1", Version: 5},
+	}, []*storage.Event{{ID: "e1", Date: date, Type: "morning_lesson", Number: 1}}, nil, nil, nil)
+	h := serveStore(s, retryingTransactor{s})
+	withKey := map[string]string{"X-API-Key": testAPIKey}
+
+	// Moving the Hebrew part moves its translation in the same unit of work
+	rec := serve(t, h, http.MethodPatch, "/api/parts/he1", `{"order":2}`, withKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"4"` {
+		t.Errorf("patch: ETag %s, want \"4\"", etag)
+	}
+	translation, err := s.GetPart(context.Background(), "en1")
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if translation.Order != 2 || translation.Version != 6 {
+		t.Errorf("translation at order %d, version %d, want order 2, version 6", translation.Order, translation.Version)
+	}
+
+	rec = serve(t, h, http.MethodPost, "/api/parts", `{"title":"New","part_type":"live_lesson","language":"he","event_id":"e1","date":"2026-03-01","order":3}`, withKey)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var created storage.LessonPart
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decoding part: %v", err)
+	}
+	if created.Version != 1 {
+		t.Errorf("created part at version %d, want 1", created.Version)
+	}
+	parts, total, err := s.ListPartsFiltered(context.Background(), storage.PartQuery{EventID: "e1"})
+	if err != nil {
+		t.Fatalf("ListPartsFiltered: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("%d parts in the event, want 3: %v", total, parts)
+	}
+}
+
+// TestRestoreAnswersStoredVersion restores a trashed event and part: the
+// responses carry the versions the restore saved
+func TestRestoreAnswersStoredVersion(t *testing.T) {
+	s := newTestStore(t)
+	date := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	s.Seed([]*storage.LessonPart{
+		{ID: "p1", EventID: "e1", Language: "he", Order: 1, Date: date, Title: "Part", PartType: "live_lesson", Version: 1},
+	}, []*storage.Event{{ID: "e1", Date: date, Type: "morning_lesson", Number: 1, Version: 1}}, nil, nil, nil)
+	h := serveStore(s, s)
+	withKey := map[string]string{"X-API-Key": testAPIKey}
+
+	if rec := serve(t, h, http.MethodDelete, "/api/parts/p1", "", withKey); rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+		t.Fatalf("delete part: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := serve(t, h, http.MethodPost, "/api/parts/p1/restore", "", withKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore part: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var partResp struct {
+		Parts []storage.LessonPart `json:"parts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &partResp); err != nil {
+		t.Fatalf("decoding restore response: %v", err)
+	}
+	part, err := s.GetPart(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if len(partResp.Parts) != 1 || partResp.Parts[0].Version != part.Version || partResp.Parts[0].DeletedAt != nil {
+		t.Errorf("restore answered %+v, stored part at version %d", partResp.Parts, part.Version)
+	}
+	if etag := rec.Header().Get("ETag"); etag != versionETag(part.Version) {
+		t.Errorf("restore part: ETag %s, want %s", etag, versionETag(part.Version))
+	}
+
+	if rec := serve(t, h, http.MethodDelete, "/api/events/e1", "", withKey); rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+		t.Fatalf("delete event: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(t, h, http.MethodPost, "/api/events/e1/restore", "", withKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore event: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var eventResp struct {
+		Event storage.Event `json:"event"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &eventResp); err != nil {
+		t.Fatalf("decoding restore response: %v", err)
+	}
+	event, err := s.GetEvent(context.Background(), "e1")
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if eventResp.Event.Version != event.Version || eventResp.Event.Version < 3 {
+		t.Errorf("restore answered event version %d, stored %d", eventResp.Event.Version, event.Version)
+	}
+	if etag := rec.Header().Get("ETag"); etag != versionETag(event.Version) {
+		t.Errorf("restore event: ETag %s, want %s", etag, versionETag(event.Version))
+	}
+}
+
+func TestConditionalReads(t *testing.T) {
+	h := newTestServer(t)
+	for _, target := range []string{"/api/event-types", "/api/templates"} {
+		rec := serve(t, h, http.MethodGet, target, "", nil)
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("GET %s: status %d, ETag %q", target, rec.Code, etag)
+		}
+		rec = serve(t, h, http.MethodGet, target, "", map[string]string{"If-None-Match": etag})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("GET %s with If-None-Match: status %d, %d bytes", target, rec.Code, rec.Body.Len())
+		}
+	}
+
+	rec := serve(t, h, http.MethodGet, "/api/event-types", "", nil)
+	etag := rec.Header().Get("ETag")
+	rec = serve(t, h, http.MethodPost, "/api/event-types", `{"name":"evening_lesson"}`, map[string]string{"X-API-Key": testAPIKey})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create event type: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(t, h, http.MethodGet, "/api/event-types", "", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("event types after a create: status %d, ETag %s unchanged", rec.Code, etag)
+	}
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// openAPIVersion is the version of the API described by the OpenAPI document
+const openAPIVersion = "2.0.0"
+
+//go:embed openapi.html
+var apiDocsPage []byte
+
+// apiOperation describes one route for the OpenAPI document
+type apiOperation struct {
+	method      string
+	path        string // Route template as registered in initRouters
+	tag         string
+	summary     string
+	description string
+	query       []apiParam
+	body        interface{} // Zero value of the request body type, nil if there is none
+	patch       bool        // The body is a JSON Merge Patch or JSON Patch of body
+	status      int         // Success status, 200 if zero
+	response    interface{} // Zero value of the response type, nil for no body
+}
+
+// apiParam is a query parameter of an operation
+type apiParam struct {
+	name        string
+	typ         string // OpenAPI type: "string", "integer" or "boolean"
+	description string
+	required    bool
+}
+
+// apiObject describes a JSON object response that has no Go type of its own,
+// by the zero value of each of its fields
+type apiObject map[string]interface{}
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument map[string]interface{}
+)
+
+// OpenAPIDocument returns the OpenAPI 3 document describing the API
+func OpenAPIDocument() map[string]interface{} {
+	openAPIOnce.Do(func() {
+		openAPIDocument = buildOpenAPIDocument(apiOperations)
+	})
+	return openAPIDocument
+}
+
+// HandleOpenAPI serves the OpenAPI document
+func (a *App) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OpenAPIDocument())
+}
+
+// HandleAPIDocs serves a page that renders the OpenAPI document
+func (a *App) HandleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(apiDocsPage)
+}
+
+// UndescribedRoutes compares the registered routes with the OpenAPI document. It
+// returns the routes missing from the document and the documented operations
+// that aren't registered, each as "METHOD /path".
+func UndescribedRoutes() (undescribed, unregistered []string, err error) {
+	a := &App{}
+	a.initRouters()
+
+	registered := map[string]bool{}
+	err = a.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // Not an endpoint
+		}
+		for _, method := range methods {
+			if method != http.MethodOptions {
+				registered[method+" "+path] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk routes: %w", err)
+	}
+
+	described := map[string]bool{}
+	for _, op := range apiOperations {
+		key := op.method + " " + op.path
+		described[key] = true
+		if !registered[key] {
+			unregistered = append(unregistered, key)
+		}
+	}
+	for key := range registered {
+		if !described[key] {
+			undescribed = append(undescribed, key)
+		}
+	}
+	sort.Strings(undescribed)
+	sort.Strings(unregistered)
+	return undescribed, unregistered, nil
+}
+
+// routeParamPattern matches a mux route variable, with an optional pattern
+var routeParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+func buildOpenAPIDocument(operations []apiOperation) map[string]interface{} {
+	schemas := newSchemaRegistry()
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range operations {
+		path := routeParamPattern.ReplaceAllString(op.path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+
+		var params []map[string]interface{}
+		for _, m := range routeParamPattern.FindAllStringSubmatch(op.path, -1) {
+			typ := "string"
+			if m[2] == ":[0-9]+" {
+				typ = "integer"
+			}
+			params = append(params, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": typ},
+			})
+		}
+		for _, p := range op.query {
+			param := map[string]interface{}{
+				"name":   p.name,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": p.typ},
+			}
+			if p.description != "" {
+				param["description"] = p.description
+			}
+			if p.required {
+				param["required"] = true
+			}
+			params = append(params, param)
+		}
+
+		operation := map[string]interface{}{
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"operationId": operationID(op),
+			"responses":   operationResponses(op, schemas),
+		}
+		if op.description != "" {
+			operation["description"] = op.description
+		}
+		if successor, ok := v1Successors[op.path]; ok && op.method == http.MethodGet {
+			operation["deprecated"] = true
+			operation["description"] = strings.TrimSpace(op.description + " Deprecated: use GET " + successor + ".")
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+		if op.body != nil {
+			operation["requestBody"] = requestBody(op, schemas)
+		}
+		if op.method != http.MethodGet && op.path != graphQLPath {
+			operation["security"] = []map[string][]string{{"apiKey": {}}}
+		}
+		paths[path][strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Study Material Service API",
+			"version":     openAPIVersion,
+			"description": "Events, lesson parts and their translations, event types and title templates.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-API-Key",
+					"description": "Required for write requests from outside the internal network when a secret key is configured",
+				},
+			},
+		},
+	}
+}
+
+// operationID derives a unique operation ID from the method and path, e.g.
+// "get_api_events_id_parts"
+func operationID(op apiOperation) string {
+	path := routeParamPattern.ReplaceAllString(op.path, "$1")
+	words := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' })
+	return strings.ToLower(op.method) + "_" + strings.Join(words, "_")
+}
+
+func requestBody(op apiOperation, schemas *schemaRegistry) map[string]interface{} {
+	schema := schemas.valueSchema(op.body)
+	content := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+	if op.patch {
+		// A merge patch holds only the fields to change, so none of them is required
+		mergePatch := map[string]interface{}{
+			"type":        "object",
+			"description": fmt.Sprintf("The fields of %s to change; null clears a field", reflect.TypeOf(op.body).Name()),
+		}
+		content = map[string]interface{}{
+			"application/merge-patch+json": map[string]interface{}{"schema": mergePatch},
+			"application/json-patch+json": map[string]interface{}{"schema": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"op", "path"},
+					"properties": map[string]interface{}{
+						"op":    map[string]interface{}{"type": "string", "enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+						"path":  map[string]interface{}{"type": "string"},
+						"from":  map[string]interface{}{"type": "string"},
+						"value": map[string]interface{}{},
+					},
+				},
+			}},
+		}
+	}
+	return map[string]interface{}{"required": true, "content": content}
+}
+
+func operationResponses(op apiOperation, schemas *schemaRegistry) map[string]interface{} {
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if op.response != nil {
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schemas.valueSchema(op.response)},
+		}
+	}
+	return map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.valueSchema(ErrorResponse{})},
+			},
+		},
+	}
+}
+
+// schemaRegistry turns Go types into JSON schemas, collecting named structs as
+// components so they are described once and referenced everywhere else
+type schemaRegistry struct {
+	schemas map[string]interface{}
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]interface{}{}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// valueSchema returns the schema of a zero value from the operation table
+func (s *schemaRegistry) valueSchema(v interface{}) map[string]interface{} {
+	if obj, ok := v.(apiObject); ok {
+		return s.objectSchema(obj)
+	}
+	return s.schemaFor(reflect.TypeOf(v))
+}
+
+// schemaFor returns the schema of values of type t as encoding/json writes them
+func (s *schemaRegistry) schemaFor(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, ok := s.schemas[t.Name()]; !ok {
+			s.schemas[t.Name()] = map[string]interface{}{} // Placeholder for recursive types
+			s.schemas[t.Name()] = s.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{} // interface{}: any value
+	}
+}
+
+// objectSchema describes an apiObject by the types of its fields
+func (s *schemaRegistry) objectSchema(obj apiObject) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for name, value := range obj {
+		properties[name] = s.valueSchema(value)
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+}
+
+// structSchema describes the JSON object encoding/json writes for a struct.
+// Fields without omitempty are always present and so listed as required.
+func (s *schemaRegistry) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	s.addFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *schemaRegistry) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name have their fields promoted
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = s.schemaFor(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/backup"
+	"github.com/Bnei-Baruch/study-material-service/integrations/kabbalahmedia"
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// Query parameters shared by several operations
+var (
+	languageParam = apiParam{name: "language", typ: "string", description: "Language code, e.g. \"he\" or \"en\""}
+	limitParam    = apiParam{name: "limit", typ: "integer", description: "Maximum number of results"}
+	offsetParam   = apiParam{name: "offset", typ: "integer", description: "Number of results to skip"}
+
+	v2ExpandPartsParam = apiParam{name: "expand", typ: "string", description: "\"parts\" to include the parts of events"}
+	v2FieldsParam      = apiParam{name: "fields", typ: "string", description: "Comma-separated fields to return, dotted for nested ones, e.g. \"date,parts.content.title\""}
+	v2LanguageParam    = apiParam{name: "language", typ: "string", description: "Comma-separated languages of part content to return"}
+)
+
+// apiOperations describes every route registered in initRouters. The openapi
+// check command fails when a route is missing here.
+var apiOperations = []apiOperation{
+	// Parts
+	{
+		method: http.MethodPost, path: "/api/parts", tag: "Parts",
+		summary:     "Create a part",
+		description: "Creates the part and an untranslated stub in every other language, in one transaction.",
+		body:        storage.CreatePartRequest{}, status: http.StatusCreated, response: storage.LessonPart{},
+	},
+	{
+		method: http.MethodGet, path: "/api/parts", tag: "Parts",
+		summary:     "List parts",
+		description: "Parts of draft events are only listed for the internal network and API key holders. Supports If-None-Match.",
+		query: []apiParam{
+			{name: "event_id", typ: "string", description: "Only parts of this event"},
+			languageParam,
+			{name: "part_type", typ: "string", description: "\"live_lesson\" or \"recorded_lesson\""},
+			{name: "from_date", typ: "string", description: "Only parts dated on or after this day (YYYY-MM-DD)"},
+			{name: "to_date", typ: "string", description: "Only parts dated on or before this day (YYYY-MM-DD)"},
+			{name: "source_id", typ: "string", description: "Only parts that reference this kabbalahmedia source"},
+			{name: "has_translation_stub", typ: "boolean", description: "Only untranslated stubs (true) or only real content (false)"},
+			{name: "sort", typ: "string", description: "Comma-separated fields, \"-\" for descending, e.g. \"-date,order\""},
+			limitParam,
+			offsetParam,
+			{name: "public_only", typ: "boolean", description: "Only parts of public events"},
+		},
+		response: apiObject{"parts": []storage.LessonPart{}, "total": 0, "returned": 0, "limit": 0, "offset": 0},
+	},
+	{
+		method: http.MethodPost, path: "/api/parts/batch", tag: "Parts",
+		summary:     "Create, update and delete parts",
+		description: "Runs the operations in one transaction, or one by one with continue_on_error. Answers 207 if some operations failed.",
+		body:        BatchRequest{}, response: BatchResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/api/parts/{id}", tag: "Parts",
+		summary:     "Get a part",
+		description: "The ETag is the part's version. Supports If-None-Match and If-Modified-Since.",
+		response:    storage.LessonPart{},
+	},
+	{
+		method: http.MethodPut, path: "/api/parts/{id}", tag: "Parts",
+		summary:     "Replace a part",
+		description: "Fields left out of the body are cleared. Honours If-Match. A new order is applied to every language version.",
+		body:        storage.CreatePartRequest{}, response: storage.LessonPart{},
+	},
+	{
+		method: http.MethodPatch, path: "/api/parts/{id}", tag: "Parts",
+		summary:     "Patch a part",
+		description: "Applies a JSON Merge Patch or, with Content-Type application/json-patch+json, a JSON Patch. Honours If-Match.",
+		body:        storage.LessonPart{}, patch: true, response: storage.LessonPart{},
+	},
+	{
+		method: http.MethodDelete, path: "/api/parts/{id}", tag: "Parts",
+		summary:     "Move a part to the trash",
+		description: "Trashing a Hebrew part also trashes its translations. Honours If-Match.",
+		status:      http.StatusNoContent,
+	},
+	{
+		method: http.MethodGet, path: "/api/parts/{id}/translations", tag: "Parts",
+		summary:  "List every language version of a part",
+		response: TranslationsResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/api/parts/{id}/move", tag: "Parts",
+		summary: "Move a part with all its translations to another event",
+		body:    MovePartRequest{}, response: MovePartResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/api/parts/{id}/copy", tag: "Parts",
+		summary: "Copy a part with all its translations to an event",
+		body:    MovePartRequest{}, status: http.StatusCreated, response: MovePartResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/api/parts/{id}/restore", tag: "Trash",
+		summary:     "Restore a part and the translations trashed with it",
+		description: "The ETag is the restored part's new version.",
+		response:    apiObject{"parts": []storage.LessonPart{}, "restored_parts": 0},
+	},
+
+	// Sources
+	{
+		method: http.MethodGet, path: "/api/sources/search", tag: "Sources",
+		summary:  "Search kabbalahmedia sources",
+		query:    []apiParam{{name: "q", typ: "string", description: "Search text", required: true}},
+		response: apiObject{"sources": []kabbalahmedia.SourceResult{}, "total": 0},
+	},
+	{
+		method: http.MethodGet, path: "/api/sources/title", tag: "Sources",
+		summary: "Get the title of a kabbalahmedia source",
+		query: []apiParam{
+			{name: "id", typ: "string", description: "Source ID", required: true},
+			{name: "language", typ: "string", description: "Language of the title (default \"he\")"},
+		},
+		response: apiObject{"id": "", "title": "", "url": ""},
+	},
+
+	// Events
+	{
+		method: http.MethodPost, path: "/api/events", tag: "Events",
+		summary: "Create an event",
+		body:    storage.CreateEventRequest{}, status: http.StatusCreated, response: storage.Event{},
+	},
+	{
+		method: http.MethodGet, path: "/api/events", tag: "Events",
+		summary:     "List events",
+		description: "Supports If-None-Match.",
+		query: []apiParam{
+			{name: "public", typ: "boolean", description: "Only public (true) or only draft (false) events"},
+			limitParam,
+			offsetParam,
+			{name: "from_date", typ: "string", description: "Only events on or after this day (YYYY-MM-DD)"},
+			{name: "to_date", typ: "string", description: "Only events on or before this day (YYYY-MM-DD)"},
+		},
+		response: apiObject{"events": []storage.Event{}, "total": 0, "returned": 0, "limit": 0, "offset": 0},
+	},
+	{
+		method: http.MethodPost, path: "/api/events/import", tag: "Events",
+		summary:     "Import an exported event",
+		description: "Recreates the bundle from GET /api/events/{id}/export with fresh IDs.",
+		query: []apiParam{
+			{name: "date", typ: "string", description: "Move the event and its parts to this day (YYYY-MM-DD)"},
+			{name: "event_type", typ: "string", description: "Import as this existing event type instead of the bundle's"},
+			{name: "missing_event_type", typ: "string", description: "\"fail\" (default) or \"create\""},
+		},
+		body: backup.EventBundle{}, status: http.StatusCreated, response: backup.ImportResult{},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{id}", tag: "Events",
+		summary:     "Get an event",
+		description: "The ETag is the event's version. Supports If-None-Match and If-Modified-Since.",
+		response:    storage.Event{},
+	},
+	{
+		method: http.MethodPut, path: "/api/events/{id}", tag: "Events",
+		summary:     "Update an event",
+		description: "Only the fields in the body are changed. Honours If-Match.",
+		body:        UpdateEventRequest{}, response: storage.Event{},
+	},
+	{
+		method: http.MethodPatch, path: "/api/events/{id}", tag: "Events",
+		summary:     "Patch an event",
+		description: "Applies a JSON Merge Patch or, with Content-Type application/json-patch+json, a JSON Patch. Honours If-Match.",
+		body:        storage.Event{}, patch: true, response: storage.Event{},
+	},
+	{
+		method: http.MethodDelete, path: "/api/events/{id}", tag: "Events",
+		summary:     "Move an event and its parts to the trash",
+		description: "Honours If-Match.",
+		status:      http.StatusNoContent,
+	},
+	{
+		method: http.MethodPost, path: "/api/events/{id}/duplicate", tag: "Events",
+		summary: "Duplicate an event and its parts to another date",
+		body:    DuplicateEventRequest{}, status: http.StatusCreated, response: storage.Event{},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{id}/export", tag: "Events",
+		summary:     "Export an event with its parts, event type and templates",
+		description: "Draft events are only exported for the internal network and API key holders.",
+		response:    backup.EventBundle{},
+	},
+	{
+		method: http.MethodPut, path: "/api/events/{id}/toggle-public", tag: "Events",
+		summary: "Publish or unpublish an event",
+		body:    TogglePublicRequest{}, response: storage.Event{},
+	},
+	{
+		method: http.MethodPost, path: "/api/events/{id}/send-email", tag: "Events",
+		summary:     "Email the event to the Google Group",
+		description: "Without is_update an event is only sent once; a repeated send answers already_sent.",
+		body:        SendEmailRequest{},
+		response:    apiObject{"success": false, "already_sent": false, "sent_at": time.Time{}, "is_update": false},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{event_id}/parts", tag: "Events",
+		summary:     "List an event's parts",
+		description: "The ETag and Last-Modified cover the parts in every language, whichever language is requested. Supports If-None-Match and If-Modified-Since.",
+		query:       []apiParam{languageParam},
+		response:    apiObject{"parts": []storage.LessonPart{}, "total": 0},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{id}/parts/order", tag: "Events",
+		summary:  "List an event's parts in order with the order of each language",
+		response: PartOrderResponse{},
+	},
+	{
+		method: http.MethodPut, path: "/api/events/{id}/parts/order", tag: "Events",
+		summary:     "Reorder an event's parts in every language",
+		description: "Lists every part but the preparation part first to last, by part or translation group ID.",
+		body:        ReorderPartsRequest{}, response: PartOrderResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/api/events/{id}/parts/order/repair", tag: "Events",
+		summary:  "Move every language version to the order of the Hebrew part",
+		response: PartOrderResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/api/events/{id}/restore", tag: "Trash",
+		summary:     "Restore an event and the parts trashed with it",
+		description: "The ETag is the restored event's new version.",
+		response:    apiObject{"event": storage.Event{}, "restored_parts": 0},
+	},
+
+	// Search
+	{
+		method: http.MethodGet, path: "/api/search", tag: "Search",
+		summary:     "Search parts and events",
+		description: "Draft events are only searched for the internal network and API key holders.",
+		query:       searchParams, response: SearchResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/api/public/search", tag: "Public",
+		summary: "Search parts and events of public events",
+		query:   searchParams, response: SearchResponse{},
+	},
+
+	// Public
+	{
+		method: http.MethodGet, path: "/api/public/events", tag: "Public",
+		summary:     "List public events with their parts in one language",
+		description: "Untranslated parts fall back to Hebrew, then English. Supports If-None-Match.",
+		query: []apiParam{
+			{name: "language", typ: "string", description: "Language to render (default \"he\")"},
+			{name: "limit", typ: "integer", description: "Maximum number of events (default 10, max 100)"},
+			{name: "from", typ: "string", description: "Only events on or after this day (YYYY-MM-DD)"},
+			{name: "types", typ: "string", description: "Comma-separated event types"},
+		},
+		response: PublicEventsResponse{},
+	},
+
+	// v2
+	{
+		method: http.MethodGet, path: "/api/v2/events", tag: "v2",
+		summary:     "List events",
+		description: "Untrusted requests only get public events. Supports If-None-Match.",
+		query: []apiParam{
+			{name: "public", typ: "boolean", description: "Only public (true) or only draft (false) events"},
+			{name: "type", typ: "string", description: "Only events of this type"},
+			{name: "from", typ: "string", description: "Only events on or after this day (YYYY-MM-DD)"},
+			{name: "to", typ: "string", description: "Only events on or before this day (YYYY-MM-DD)"},
+			{name: "limit", typ: "integer", description: "Maximum number of events (default 50, max 200)"},
+			offsetParam,
+			v2ExpandPartsParam,
+			v2FieldsParam,
+			v2LanguageParam,
+		},
+		response: V2EventList{},
+	},
+	{
+		method: http.MethodGet, path: "/api/v2/events/{id}", tag: "v2",
+		summary:     "Get an event",
+		description: "Supports If-None-Match and If-Modified-Since.",
+		query:       []apiParam{v2ExpandPartsParam, v2FieldsParam, v2LanguageParam},
+		response:    V2Event{},
+	},
+	{
+		method: http.MethodGet, path: "/api/v2/events/{id}/parts", tag: "v2",
+		summary:     "List an event's parts in order",
+		description: "Each part has its content in every language. Supports If-None-Match and If-Modified-Since.",
+		query:       []apiParam{v2FieldsParam, v2LanguageParam},
+		response:    V2PartList{},
+	},
+	{
+		method: http.MethodGet, path: "/api/v2/parts/{id}", tag: "v2",
+		summary:     "Get a part with its content in every language",
+		description: "The ID is a v2 part ID or the v1 ID of any language version. Supports If-None-Match and If-Modified-Since.",
+		query: []apiParam{
+			{name: "expand", typ: "string", description: "\"event\" to include the part's event"},
+			v2FieldsParam,
+			v2LanguageParam,
+		},
+		response: V2Part{},
+	},
+
+	// GraphQL
+	{
+		method: http.MethodGet, path: "/api/graphql", tag: "GraphQL",
+		summary:     "Run a GraphQL query",
+		description: "Read-only queries over events, parts, event types and templates. Errors come in the GraphQL errors list, with a code in their extensions.",
+		query: []apiParam{
+			{name: "query", typ: "string", description: "The GraphQL query", required: true},
+			{name: "variables", typ: "string", description: "Variables as a JSON object"},
+			{name: "operationName", typ: "string", description: "Operation to run when the query has several"},
+		},
+		response: GraphQLResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/api/graphql", tag: "GraphQL",
+		summary:     "Run a GraphQL query",
+		description: "Same as GET, with the query in the body. Needs no API key, since queries only read.",
+		body:        GraphQLRequest{}, response: GraphQLResponse{},
+	},
+
+	// Trash
+	{
+		method: http.MethodGet, path: "/api/trash", tag: "Trash",
+		summary:     "List trashed events and parts",
+		description: "Only for the internal network and API key holders.",
+		response:    TrashResponse{},
+	},
+
+	// Revisions
+	{
+		method: http.MethodGet, path: "/api/parts/{id}/revisions", tag: "Revisions",
+		summary:     "List a part's revisions",
+		description: "Only for the internal network and API key holders.",
+		response:    []RevisionSummary{},
+	},
+	{
+		method: http.MethodGet, path: "/api/parts/{id}/revisions/diff", tag: "Revisions",
+		summary:     "Compare two revisions of a part",
+		description: "Only for the internal network and API key holders.",
+		query:       revisionDiffParams,
+		response:    RevisionDiffResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/api/parts/{id}/revisions/{rev:[0-9]+}", tag: "Revisions",
+		summary:     "Get a revision of a part",
+		description: "Only for the internal network and API key holders.",
+		response:    storage.Revision{},
+	},
+	{
+		method: http.MethodPost, path: "/api/parts/{id}/revisions/{rev:[0-9]+}/restore", tag: "Revisions",
+		summary:  "Restore a part to a revision",
+		response: storage.LessonPart{},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{id}/revisions", tag: "Revisions",
+		summary:     "List an event's revisions",
+		description: "Only for the internal network and API key holders.",
+		response:    []RevisionSummary{},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{id}/revisions/diff", tag: "Revisions",
+		summary:     "Compare two revisions of an event",
+		description: "Only for the internal network and API key holders.",
+		query:       revisionDiffParams,
+		response:    RevisionDiffResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/api/events/{id}/revisions/{rev:[0-9]+}", tag: "Revisions",
+		summary:     "Get a revision of an event",
+		description: "Only for the internal network and API key holders.",
+		response:    storage.Revision{},
+	},
+	{
+		method: http.MethodPost, path: "/api/events/{id}/revisions/{rev:[0-9]+}/restore", tag: "Revisions",
+		summary:  "Restore an event to a revision",
+		response: storage.Event{},
+	},
+
+	// Event types
+	{
+		method: http.MethodGet, path: "/api/event-types", tag: "Event types",
+		summary:     "List event types",
+		description: "Supports If-None-Match.",
+		response:    []storage.EventType{},
+	},
+	{
+		method: http.MethodPost, path: "/api/event-types", tag: "Event types",
+		summary: "Create an event type",
+		body:    storage.CreateEventTypeRequest{}, status: http.StatusCreated, response: storage.EventType{},
+	},
+	{
+		method: http.MethodGet, path: "/api/event-types/{id}", tag: "Event types",
+		summary:     "Get an event type",
+		description: "The ETag is the event type's version. Supports If-None-Match and If-Modified-Since.",
+		response:    storage.EventType{},
+	},
+	{
+		method: http.MethodPut, path: "/api/event-types/{id}", tag: "Event types",
+		summary:     "Update an event type",
+		description: "Honours If-Match.",
+		body:        storage.UpdateEventTypeRequest{}, response: storage.EventType{},
+	},
+	{
+		method: http.MethodDelete, path: "/api/event-types/{id}", tag: "Event types",
+		summary:     "Delete an event type",
+		description: "Honours If-Match.",
+		status:      http.StatusNoContent,
+	},
+
+	// Templates
+	{
+		method: http.MethodGet, path: "/api/templates", tag: "Templates",
+		summary:     "Get the languages, preparation titles and title templates",
+		description: "The ETag is the configuration's version. Supports If-None-Match.",
+		response:    storage.TemplateConfig{},
+	},
+	{
+		method: http.MethodPost, path: "/api/templates", tag: "Templates",
+		summary:     "Create a title template",
+		description: "Every language needs a translation. Honours If-Match with the configuration's version.",
+		body:        storage.TemplateDefinition{}, status: http.StatusCreated, response: storage.TemplateDefinition{},
+	},
+	{
+		method: http.MethodPut, path: "/api/templates/{id}", tag: "Templates",
+		summary:     "Update a title template",
+		description: "The id in the body is ignored. Honours If-Match with the configuration's version.",
+		body:        storage.TemplateDefinition{}, response: storage.TemplateDefinition{},
+	},
+	{
+		method: http.MethodDelete, path: "/api/templates/{id}", tag: "Templates",
+		summary:  "Delete a title template",
+		response: apiObject{"message": ""},
+	},
+	{
+		method: http.MethodPost, path: "/api/templates/sync", tag: "Templates",
+		summary:     "Merge templates.json into the stored templates",
+		description: "Adds missing templates and languages and keeps existing translations.",
+		response:    apiObject{"message": "", "templates": 0, "languages": []string{}},
+	},
+
+	// API description
+	{
+		method: http.MethodGet, path: "/api/openapi.json", tag: "Meta",
+		summary:  "This OpenAPI document",
+		response: apiObject{},
+	},
+	{
+		method: http.MethodGet, path: "/api/docs", tag: "Meta",
+		summary: "A page rendering this OpenAPI document",
+	},
+	{
+		method: http.MethodGet, path: "/health", tag: "Meta",
+		summary:     "Health check",
+		description: "Answers \"OK\" as plain text.",
+	},
+}
+
+var searchParams = []apiParam{
+	{name: "q", typ: "string", description: "Search text", required: true},
+	{name: "language", typ: "string", description: "Only parts in this language, stemmed by its rules"},
+	{name: "from", typ: "string", description: "Only events and parts dated on or after this day (YYYY-MM-DD)"},
+	{name: "to", typ: "string", description: "Only events and parts dated on or before this day (YYYY-MM-DD)"},
+	{name: "type", typ: "string", description: "Only events of this type"},
+	{name: "limit", typ: "integer", description: "Maximum number of event groups (default 20, max 100)"},
+}
+
+var revisionDiffParams = []apiParam{
+	{name: "from", typ: "integer", description: "Older revision (default: the one before to)"},
+	{name: "to", typ: "integer", description: "Newer revision (default: the latest)"},
+}
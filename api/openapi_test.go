@@ -0,0 +1,18 @@
+package api
+
+import "testing"
+
+// TestOpenAPIDescribesEveryRoute fails when a route is registered in
+// initRouters without an entry in apiOperations, or the other way round
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	undescribed, unregistered, err := UndescribedRoutes()
+	if err != nil {
+		t.Fatalf("UndescribedRoutes: %v", err)
+	}
+	for _, route := range undescribed {
+		t.Errorf("route not described in apiOperations: %s", route)
+	}
+	for _, route := range unregistered {
+		t.Errorf("operation in apiOperations has no route: %s", route)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// patchError is a PATCH request the client has to fix, answered with status and code
+type patchError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *patchError) Error() string {
+	return e.msg
+}
+
+func newPatchError(status int, code, format string, args ...interface{}) error {
+	return &patchError{status: status, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// writePatchError answers a failed applyPatch
+func writePatchError(w http.ResponseWriter, r *http.Request, err error) {
+	var pe *patchError
+	if errors.As(err, &pe) {
+		writeError(w, r, pe.status, pe.code, pe.msg)
+		return
+	}
+	writeInternalError(w, r, err, "Failed to apply patch")
+}
+
+// applyPatch applies the request body to doc and decodes the result into patched.
+// The body is a JSON Patch (RFC 6902) when sent as application/json-patch+json,
+// and a JSON Merge Patch (RFC 7396) otherwise. Changing one of the immutable
+// fields is rejected, and a "date" may be given as YYYY-MM-DD.
+func applyPatch(r *http.Request, doc, patched interface{}, immutable []string) error {
+	contentType := mergePatchContentType
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return newPatchError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Invalid Content-Type: %v", err)
+		}
+		contentType = mediaType
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return newPatchError(http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+	}
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	var result []byte
+	switch contentType {
+	case jsonPatchContentType:
+		operations, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return newPatchError(http.StatusBadRequest, codeInvalidPatch, "Invalid JSON patch: %v", err)
+		}
+		result, err = operations.Apply(original)
+		if err != nil {
+			return newPatchError(http.StatusConflict, codePatchFailed, "Failed to apply JSON patch: %v", err)
+		}
+	default:
+		// Like the other endpoints, take any other body as JSON
+		if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+			return newPatchError(http.StatusBadRequest, codeInvalidPatch, "A merge patch must be a JSON object")
+		}
+		result, err = jsonpatch.MergePatch(original, body)
+		if err != nil {
+			return newPatchError(http.StatusBadRequest, codeInvalidPatch, "Invalid merge patch: %v", err)
+		}
+	}
+
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(original, &before); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(result, &after); err != nil {
+		return newPatchError(http.StatusBadRequest, codeInvalidPatch, "The patched document must be a JSON object")
+	}
+	for _, field := range immutable {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			return newPatchError(http.StatusBadRequest, codeImmutableField, "%s can't be changed", field)
+		}
+	}
+
+	// Accept plain dates like the PUT endpoints do
+	if date, ok := after["date"].(string); ok {
+		if parsed, err := time.Parse("2006-01-02", date); err == nil {
+			after["date"] = parsed
+		}
+	}
+
+	result, err = json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		return newPatchError(http.StatusBadRequest, codeValidationFailed, "Invalid patched document: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int // 0 if the patch applies
+		code        string
+	}{
+		{name: "merge patch of the title", contentType: mergePatchContentType, body: `{"title":"Changed"}`},
+		{name: "merge patch without a content type", body: `{"title":"Changed"}`},
+		{name: "JSON patch of the title", contentType: jsonPatchContentType, body: `[{"op":"test","path":"/title","value":"Part"},{"op":"replace","path":"/title","value":"Changed"}]`},
+		{name: "immutable event", contentType: mergePatchContentType, body: `{"event_id":"e2"}`, status: http.StatusBadRequest, code: codeImmutableField},
+		{name: "immutable event by JSON patch", contentType: jsonPatchContentType, body: `[{"op":"replace","path":"/event_id","value":"e2"}]`, status: http.StatusBadRequest, code: codeImmutableField},
+		{name: "failed test operation", contentType: jsonPatchContentType, body: `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"Changed"}]`, status: http.StatusConflict, code: codePatchFailed},
+		{name: "unknown field", contentType: mergePatchContentType, body: `{"colour":"blue"}`, status: http.StatusBadRequest, code: codeValidationFailed},
+		{name: "merge patch that isn't an object", contentType: mergePatchContentType, body: `["title"]`, status: http.StatusBadRequest, code: codeInvalidPatch},
+		{name: "invalid JSON patch", contentType: jsonPatchContentType, body: `{"op":"replace"}`, status: http.StatusBadRequest, code: codeInvalidPatch},
+		{name: "invalid content type", contentType: "text/", body: `{}`, status: http.StatusUnsupportedMediaType, code: codeUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &storage.LessonPart{ID: "p1", EventID: "e1", Language: "he", Order: 1, Title: "Part", PartType: "live_lesson",
+				Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Version: 1}
+			req := httptest.NewRequest(http.MethodPatch, "/api/parts/p1", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var patched storage.LessonPart
+			err := applyPatch(req, existing, &patched, partImmutableFields)
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatalf("applyPatch: %v", err)
+				}
+				if patched.Title != "Changed" || patched.EventID != "e1" || patched.Order != 1 || !patched.Date.Equal(existing.Date) {
+					t.Errorf("patched part %+v", patched)
+				}
+				if existing.Title != "Part" {
+					t.Errorf("applyPatch changed the existing part's title to %q", existing.Title)
+				}
+				return
+			}
+			var pe *patchError
+			if !errors.As(err, &pe) {
+				t.Fatalf("error %v, want a patch error", err)
+			}
+			if pe.status != tt.status || pe.code != tt.code {
+				t.Errorf("status %d, code %q, want %d, %q", pe.status, pe.code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+// TestPatchPartDate patches a part's date as YYYY-MM-DD through the endpoint
+func TestPatchPartDate(t *testing.T) {
+	s := newBatchStore(t)
+	header := map[string]string{"X-API-Key": testAPIKey, "Content-Type": mergePatchContentType}
+	rec := serve(t, serveStore(s, s), http.MethodPatch, "/api/parts/p1", `{"date":"2026-03-02"}`, header)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	part, err := s.GetPart(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if want := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC); !part.Date.Equal(want) || part.Title != "Part" {
+		t.Errorf("part %q on %v, want \"Part\" on %v", part.Title, part.Date, want)
+	}
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/gorilla/mux"
+)
+
+// V2Event is an event with its parts in order
+type V2Event struct {
+	ID        string            `json:"id"`
+	Date      string            `json:"date"` // YYYY-MM-DD
+	StartTime string            `json:"start_time,omitempty"`
+	EndTime   string            `json:"end_time,omitempty"`
+	Type      string            `json:"type"`
+	Number    int               `json:"number"`
+	Order     int               `json:"order"`
+	Titles    map[string]string `json:"titles"`
+	Public    bool              `json:"public"`
+	PartIDs   []string          `json:"part_ids"`        // The event's parts, in order
+	Parts     []*V2Part         `json:"parts,omitempty"` // With ?expand=parts
+	Version   int               `json:"version"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	UpdatedBy string            `json:"updated_by,omitempty"`
+}
+
+// V2Part is one part of an event with its content in every language
+type V2Part struct {
+	ID                 string                    `json:"id"` // Translation group ID, or the Hebrew part's ID for parts without one
+	EventID            string                    `json:"event_id,omitempty"`
+	Event              *V2Event                  `json:"event,omitempty"` // With ?expand=event
+	Order              int                       `json:"order"`           // 0 is the preparation part
+	Date               string                    `json:"date"`            // YYYY-MM-DD
+	PartType           string                    `json:"part_type"`
+	RecordedLessonDate string                    `json:"recorded_lesson_date,omitempty"`
+	Languages          []string                  `json:"languages"` // Every language the part has content in
+	Content            map[string]*V2PartContent `json:"content"`   // By language; only the requested ones with ?language=
+	UpdatedAt          time.Time                 `json:"updated_at"`
+}
+
+// V2PartContent is a part in one language
+type V2PartContent struct {
+	DocumentID       string               `json:"document_id"` // ID of this language version in v1
+	Title            string               `json:"title"`
+	Description      string               `json:"description"`
+	Links            V2Links              `json:"links"`
+	CustomLinks      []storage.CustomLink `json:"custom_links"`
+	Sources          []storage.Source     `json:"sources"`
+	TranslationStub  bool                 `json:"translation_stub"`
+	ShowUpdatedBadge bool                 `json:"show_updated_badge"`
+	Version          int                  `json:"version"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	UpdatedBy        string               `json:"updated_by,omitempty"`
+}
+
+// V2Links are the links of a part in one language
+type V2Links struct {
+	Lesson             string `json:"lesson,omitempty"`
+	Excerpts           string `json:"excerpts,omitempty"`
+	Transcript         string `json:"transcript,omitempty"`
+	Program            string `json:"program,omitempty"`
+	ReadingBeforeSleep string `json:"reading_before_sleep,omitempty"`
+	LessonPreparation  string `json:"lesson_preparation,omitempty"`
+	LineupForHosts     string `json:"lineup_for_hosts,omitempty"`
+}
+
+// newV2Event builds the v2 view of an event and its part groups
+func newV2Event(event *storage.Event, groups []*storage.PartGroup, expandParts bool, languages []string) *V2Event {
+	v := &V2Event{
+		ID:        event.ID,
+		Date:      event.Date.Format("2006-01-02"),
+		StartTime: event.StartTime,
+		EndTime:   event.EndTime,
+		Type:      event.Type,
+		Number:    event.Number,
+		Order:     event.Order,
+		Titles:    event.Titles,
+		Public:    event.Public,
+		PartIDs:   make([]string, 0, len(groups)),
+		Version:   event.Version,
+		CreatedAt: event.CreatedAt,
+		UpdatedAt: event.LastModified(),
+		UpdatedBy: event.UpdatedBy,
+	}
+	if v.Titles == nil {
+		v.Titles = map[string]string{}
+	}
+	if expandParts {
+		v.Parts = make([]*V2Part, 0, len(groups))
+	}
+	for _, group := range groups {
+		part := newV2Part(group, languages)
+		v.PartIDs = append(v.PartIDs, part.ID)
+		if expandParts {
+			v.Parts = append(v.Parts, part)
+		}
+	}
+	return v
+}
+
+// newV2Part builds the v2 view of a part from its language versions. Fields
+// shared by all languages come from the Hebrew version. With languages, only
+// the content in those languages is included.
+func newV2Part(group *storage.PartGroup, languages []string) *V2Part {
+	reference := group.Reference()
+	part := &V2Part{
+		ID:                 v2PartID(group),
+		EventID:            reference.EventID,
+		Order:              group.Order,
+		Date:               reference.Date.Format("2006-01-02"),
+		PartType:           reference.PartType,
+		RecordedLessonDate: reference.RecordedLessonDate,
+		Languages:          make([]string, 0, len(group.Parts)),
+		Content:            make(map[string]*V2PartContent, len(group.Parts)),
+	}
+	for _, p := range group.Parts {
+		part.Languages = append(part.Languages, p.Language)
+		part.UpdatedAt = latest(part.UpdatedAt, p.LastModified())
+		if len(languages) > 0 && !containsID(languages, p.Language) {
+			continue
+		}
+		part.Content[p.Language] = newV2PartContent(p)
+	}
+	sort.Strings(part.Languages)
+	return part
+}
+
+func newV2PartContent(p *storage.LessonPart) *V2PartContent {
+	content := &V2PartContent{
+		DocumentID:  p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		Links: V2Links{
+			Lesson:             p.LessonLink,
+			Excerpts:           p.ExcerptsLink,
+			Transcript:         p.TranscriptLink,
+			Program:            p.ProgramLink,
+			ReadingBeforeSleep: p.ReadingBeforeSleepLink,
+			LessonPreparation:  p.LessonPreparationLink,
+			LineupForHosts:     p.LineupForHostsLink,
+		},
+		CustomLinks:      p.CustomLinks,
+		Sources:          p.Sources,
+		TranslationStub:  p.TranslationStub,
+		ShowUpdatedBadge: p.ShowUpdatedBadge,
+		Version:          p.Version,
+		UpdatedAt:        p.LastModified(),
+		UpdatedBy:        p.UpdatedBy,
+	}
+	if content.CustomLinks == nil {
+		content.CustomLinks = []storage.CustomLink{}
+	}
+	if content.Sources == nil {
+		content.Sources = []storage.Source{}
+	}
+	return content
+}
+
+// v2PartID is the ID of a part group in v2: its translation group ID, or for
+// parts from before translation groups the ID of the Hebrew version
+func v2PartID(group *storage.PartGroup) string {
+	reference := group.Reference()
+	if reference.TranslationGroupID != "" {
+		return reference.TranslationGroupID
+	}
+	return reference.ID
+}
+
+// parseLanguages parses a comma-separated ?language= list
+func parseLanguages(s string) []string {
+	var languages []string
+	for _, language := range strings.Split(s, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// v1DeprecatedAt is when the v1 routes below were superseded by v2
+var v1DeprecatedAt = time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+// v1Successors maps v1 GET routes, by path template, to the v2 route that
+// replaces them. Route variables of the v1 route are filled into the v2 one.
+var v1Successors = map[string]string{
+	"/api/events":                  "/api/v2/events",
+	"/api/events/{id}":             "/api/v2/events/{id}",
+	"/api/events/{event_id}/parts": "/api/v2/events/{event_id}/parts",
+	"/api/parts/{id}":              "/api/v2/parts/{id}",
+	"/api/parts/{id}/translations": "/api/v2/parts/{id}",
+}
+
+// deprecationMiddleware marks responses of v1 routes that have a v2 successor
+// with Deprecation (RFC 9745), a Link to the successor and, once a date is
+// configured, Sunset (RFC 8594)
+func (a *App) deprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if successor := v1Successor(r); successor != "" {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(v1DeprecatedAt.Unix(), 10))
+			w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+			if !a.v1Sunset.IsZero() {
+				w.Header().Set("Sunset", a.v1Sunset.UTC().Format(http.TimeFormat))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// v1Successor returns the path of the v2 route replacing the v1 route r matched,
+// or "" if there is none
+func v1Successor(r *http.Request) string {
+	if r.Method != http.MethodGet {
+		return ""
+	}
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	successor, ok := v1Successors[template]
+	if !ok {
+		return ""
+	}
+	for name, value := range mux.Vars(r) {
+		successor = strings.ReplaceAll(successor, "{"+name+"}", url.PathEscape(value))
+	}
+	return successor
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldSet is a parsed ?fields= selection, e.g. "id,date,parts.content.title".
+// Each selected field maps to the subfields to keep of it, or to nil when the
+// field is kept whole.
+type fieldSet map[string]fieldSet
+
+// parseFields parses a comma-separated list of dotted field paths. An empty
+// list selects everything and returns nil.
+func parseFields(s string) (fieldSet, error) {
+	var fields fieldSet
+	for _, path := range strings.Split(s, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if fields == nil {
+			fields = fieldSet{}
+		}
+		node := fields
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("invalid field %q", path)
+			}
+			sub, ok := node[name]
+			if ok && sub == nil {
+				break // Already kept whole
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if !ok {
+				sub = fieldSet{}
+				node[name] = sub
+			}
+			node = sub
+		}
+	}
+	return fields, nil
+}
+
+// validate checks that every selected field exists on the values of type t
+func (fs fieldSet) validate(t reflect.Type, prefix string) error {
+	t = resourceType(t)
+	for name, sub := range fs {
+		field, ok := jsonField(t, name)
+		if !ok {
+			return fmt.Errorf("unknown field %s%s", prefix, name)
+		}
+		if sub == nil {
+			continue
+		}
+		if resourceType(field).Kind() != reflect.Struct {
+			return fmt.Errorf("%s%s has no fields to select", prefix, name)
+		}
+		if err := sub.validate(field, prefix+name+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply keeps the selected fields of v, a value of type t decoded into generic
+// JSON. Lists and maps are selected from item by item, and an object's "id" is
+// always kept.
+func (fs fieldSet) apply(v interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		items, ok := v.([]interface{})
+		if !ok {
+			return v
+		}
+		for i, item := range items {
+			items[i] = fs.apply(item, t.Elem())
+		}
+		return items
+	case reflect.Map:
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		for key, value := range values {
+			values[key] = fs.apply(value, t.Elem())
+		}
+		return values
+	case reflect.Struct:
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		selected := make(map[string]interface{}, len(fs)+1)
+		if id, ok := object["id"]; ok {
+			selected["id"] = id
+		}
+		for name, sub := range fs {
+			value, ok := object[name]
+			if !ok {
+				continue
+			}
+			if sub == nil {
+				selected[name] = value
+				continue
+			}
+			field, _ := jsonField(t, name)
+			selected[name] = sub.apply(value, field)
+		}
+		return selected
+	default:
+		return v
+	}
+}
+
+// within returns a selection for an envelope of type t that keeps every field
+// whole except field, a list of resources, from which fs is selected
+func (fs fieldSet) within(t reflect.Type, field string) fieldSet {
+	if fs == nil {
+		return nil
+	}
+	envelope := fieldSet{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			envelope[name] = nil
+		}
+	}
+	envelope[field] = fs
+	return envelope
+}
+
+// resourceType returns the struct type behind pointers, lists and maps of t
+func resourceType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// jsonField returns the type of the field of struct t that encoding/json writes as name
+func jsonField(t reflect.Type, name string) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name && tag != "-" {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// parseExpand parses a comma-separated ?expand= list, accepting only the
+// related resources in allowed
+func parseExpand(s string, allowed ...string) (map[string]bool, error) {
+	expand := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !containsID(allowed, name) {
+			return nil, fmt.Errorf("can't expand %s, use %s", name, strings.Join(allowed, " or "))
+		}
+		expand[name] = true
+	}
+	return expand, nil
+}
+
+// selectFields returns body with only the selected fields, ready to encode
+func selectFields(body interface{}, fields fieldSet) (interface{}, error) {
+	if fields == nil {
+		return body, nil
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return fields.apply(generic, reflect.TypeOf(body)), nil
+}
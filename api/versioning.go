@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// setETag sets the ETag header to a document's version
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", versionETag(version))
+}
+
+// ifMatch checks a document's version against the request's If-Match header.
+// It returns true when there is no If-Match header, when it is "*", or when one
+// of its entity tags is the document's ETag. If-Match uses the strong comparison,
+// so weak tags (W/"3") never match.
+func ifMatch(r *http.Request, version int) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		tag = strings.Trim(tag, `"`)
+		if v, err := strconv.Atoi(tag); err == nil && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// PreconditionFailedResponse is the error envelope of a 412, with the current
+// version of the document next to the error
+type PreconditionFailedResponse struct {
+	Error   ErrorBody   `json:"error"`
+	Current interface{} `json:"current"`
+}
+
+// writePreconditionFailed responds 412 with the current version of the document,
+// so the client can merge its changes and retry with the new ETag
+func writePreconditionFailed(w http.ResponseWriter, r *http.Request, current interface{}, version int) {
+	setETag(w, version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(PreconditionFailedResponse{
+		Error: ErrorBody{
+			Code:      codeVersionConflict,
+			Message:   "The document was changed by someone else",
+			RequestID: requestID(r),
+		},
+		Current: current,
+	})
+}
+
+// writePartConflict responds 412 with the part's current version after a save lost a race
+func (a *App) writePartConflict(w http.ResponseWriter, r *http.Request, id string) {
+	current, err := a.store.GetPart(r.Context(), id)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to get part")
+		return
+	}
+	writePreconditionFailed(w, r, current, current.Version)
+}
+
+// writeEventConflict responds 412 with the event's current version after a save lost a race
+func (a *App) writeEventConflict(w http.ResponseWriter, r *http.Request, id string) {
+	current, err := a.eventStore.GetEvent(r.Context(), id)
+	if err != nil {
+		writeInternalError(w, r, err, "Failed to get event")
+		return
+	}
+	writePreconditionFailed(w, r, current, current.Version)
+}
+
+// checkPartVersion fails with ErrVersionConflict if the part no longer matches the
+// request's If-Match header. Run inside a transaction, the check and the writes
+// that follow it are atomic.
+func checkPartVersion(ctx context.Context, tx storage.Tx, r *http.Request, id string) error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+	current, err := tx.Parts.GetPart(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ifMatch(r, current.Version) {
+		return fmt.Errorf("part %s: %w", id, storage.ErrVersionConflict)
+	}
+	return nil
+}
+
+// checkEventVersion fails with ErrVersionConflict if the event no longer matches the
+// request's If-Match header. Run inside a transaction, the check and the writes
+// that follow it are atomic.
+func checkEventVersion(ctx context.Context, tx storage.Tx, r *http.Request, id string) error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+	current, err := tx.Events.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ifMatch(r, current.Version) {
+		return fmt.Errorf("event %s: %w", id, storage.ErrVersionConflict)
+	}
+	return nil
+}
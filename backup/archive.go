@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// FormatVersion is the archive layout written by this version of the service.
+// Archives with a newer format are rejected on restore. Version 2 added the
+// revision history.
+const FormatVersion = 2
+
+const (
+	manifestFile   = "manifest.json"
+	eventsFile     = "events.json"
+	partsFile      = "lesson_parts.json"
+	eventTypesFile = "event_types.json"
+	templatesFile  = "templates.json"
+	revisionsFile  = "revisions.json"
+)
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	FormatVersion int         `json:"format_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	StorageType   string      `json:"storage_type,omitempty"`
+	Files         []FileEntry `json:"files"`
+}
+
+// FileEntry describes one file in a backup archive
+type FileEntry struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+}
+
+// Snapshot is the data held in a backup archive
+type Snapshot struct {
+	Events     []*storage.Event
+	Parts      []*storage.LessonPart
+	EventTypes []*storage.EventType
+	Templates  *storage.TemplateConfig // nil if no template config was stored
+	Revisions  []*storage.Revision     // nil in format 1 archives, which have no revision history
+}
+
+// WriteArchive writes the snapshot to w as a gzipped tar archive: a manifest
+// followed by one JSON file per collection
+func WriteArchive(w io.Writer, snap *Snapshot, storageType string) (*Manifest, error) {
+	type file struct {
+		name  string
+		docs  int
+		value interface{}
+	}
+	files := []file{
+		{eventsFile, len(snap.Events), snap.Events},
+		{partsFile, len(snap.Parts), snap.Parts},
+		{eventTypesFile, len(snap.EventTypes), snap.EventTypes},
+		{revisionsFile, len(snap.Revisions), snap.Revisions},
+	}
+	if snap.Templates != nil {
+		files = append(files, file{templatesFile, 1, snap.Templates})
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		StorageType:   storageType,
+	}
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		data, err := json.MarshalIndent(f.value, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", f.name, err)
+		}
+		sum := sha256.Sum256(data)
+		contents[i] = data
+		manifest.Files = append(manifest.Files, FileEntry{
+			Name:      f.name,
+			Documents: f.docs,
+			Size:      int64(len(data)),
+			SHA256:    hex.EncodeToString(sum[:]),
+		})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeTarFile(tw, manifestFile, manifestData, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		if err := writeTarFile(tw, f.name, contents[i], manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// ReadArchive reads a backup archive and verifies every file against the manifest
+func ReadArchive(r io.Reader) (*Snapshot, *Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	contents := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, tr); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+		contents[header.Name] = buf.Bytes()
+	}
+
+	manifestData, ok := contents[manifestFile]
+	if !ok {
+		return nil, nil, fmt.Errorf("archive has no %s", manifestFile)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, nil, fmt.Errorf("unsupported backup format version %d (this build reads up to %d)", manifest.FormatVersion, FormatVersion)
+	}
+
+	for _, entry := range manifest.Files {
+		data, ok := contents[entry.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("archive is missing %s", entry.Name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s: the archive is corrupt", entry.Name)
+		}
+	}
+
+	snap := &Snapshot{}
+	targets := map[string]interface{}{
+		eventsFile:     &snap.Events,
+		partsFile:      &snap.Parts,
+		eventTypesFile: &snap.EventTypes,
+		templatesFile:  &snap.Templates,
+		revisionsFile:  &snap.Revisions,
+	}
+	for _, entry := range manifest.Files {
+		target, ok := targets[entry.Name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(contents[entry.Name], target); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s: %w", entry.Name, err)
+		}
+	}
+
+	counts := map[string]int{
+		eventsFile:     len(snap.Events),
+		partsFile:      len(snap.Parts),
+		eventTypesFile: len(snap.EventTypes),
+		revisionsFile:  len(snap.Revisions),
+	}
+	for _, entry := range manifest.Files {
+		if n, ok := counts[entry.Name]; ok && n != entry.Documents {
+			return nil, nil, fmt.Errorf("%s holds %d documents, the manifest lists %d", entry.Name, n, entry.Documents)
+		}
+	}
+
+	return snap, &manifest, nil
+}
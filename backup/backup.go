@@ -0,0 +1,240 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// Stores are the storage backends a backup is taken from and restored to
+type Stores struct {
+	Parts      storage.PartStore
+	Events     storage.EventStore
+	Revisions  storage.RevisionStore
+	EventTypes storage.EventTypeStore
+	Templates  storage.TemplateStore
+	Transactor storage.Transactor
+}
+
+// Export reads every event, part (trashed ones included), revision, event type
+// and the template configuration
+func Export(ctx context.Context, st Stores) (*Snapshot, error) {
+	events, err := st.Events.ListEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export events: %w", err)
+	}
+	parts, err := st.Parts.ListParts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export parts: %w", err)
+	}
+	eventTypes, err := st.EventTypes.ListEventTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export event types: %w", err)
+	}
+
+	revisions, err := st.Revisions.ListAllRevisions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export revisions: %w", err)
+	}
+
+	snap := &Snapshot{Events: events, Parts: parts, EventTypes: eventTypes, Revisions: revisions}
+	if config, err := st.Templates.GetConfig(ctx); err == nil {
+		snap.Templates = config
+	}
+	return snap, nil
+}
+
+// Mode selects how Restore treats data that is already stored
+type Mode string
+
+const (
+	// ModeReplace deletes all stored events, parts (with their revisions) and event
+	// types and then writes the backup
+	ModeReplace Mode = "replace"
+	// ModeMerge writes only the documents whose IDs aren't stored yet
+	ModeMerge Mode = "merge"
+)
+
+// ParseMode validates a restore mode name
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case ModeReplace, ModeMerge:
+		return Mode(name), nil
+	}
+	return "", fmt.Errorf("unknown restore mode %q (use %q or %q)", name, ModeReplace, ModeMerge)
+}
+
+// RestoreOptions controls Restore
+type RestoreOptions struct {
+	Mode   Mode
+	DryRun bool // Only report what would change
+}
+
+// CollectionReport counts what Restore did (or would do) to one collection
+type CollectionReport struct {
+	Deleted int `json:"deleted"`
+	Written int `json:"written"`
+	Skipped int `json:"skipped"`
+}
+
+// RestoreReport summarizes a restore
+type RestoreReport struct {
+	Events          CollectionReport `json:"events"`
+	Parts           CollectionReport `json:"parts"`
+	Revisions       CollectionReport `json:"revisions"`
+	EventTypes      CollectionReport `json:"event_types"`
+	TemplateWritten bool             `json:"template_written"`
+	DryRun          bool             `json:"dry_run"`
+}
+
+// Restore writes a snapshot to the stores. Events and parts are written as they
+// are in the snapshot, with their versions, timestamps and revision histories.
+// Deleting and writing events and parts is one transaction where the storage
+// supports transactions, so a failed restore changes none of them; event types
+// and the template config are written once it has committed. Without
+// transactions a failed restore can be repeated with the same archive.
+func Restore(ctx context.Context, st Stores, snap *Snapshot, opts RestoreOptions) (*RestoreReport, error) {
+	if opts.Mode == ModeReplace && snap.Revisions == nil {
+		return nil, fmt.Errorf("the archive has no revision history and replacing would erase it; restore it in %s mode", ModeMerge)
+	}
+	report := &RestoreReport{DryRun: opts.DryRun}
+
+	existingEvents, err := st.Events.ListEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	existingParts, err := st.Parts.ListParts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+	existingTypes, err := st.EventTypes.ListEventTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event types: %w", err)
+	}
+	currentConfig, configErr := st.Templates.GetConfig(ctx)
+	hasConfig := configErr == nil
+
+	eventIDs := make(map[string]bool, len(existingEvents))
+	for _, e := range existingEvents {
+		eventIDs[e.ID] = true
+	}
+	partIDs := make(map[string]bool, len(existingParts))
+	for _, p := range existingParts {
+		partIDs[p.ID] = true
+	}
+	typeIDs := make(map[string]bool, len(existingTypes))
+	typeNames := make(map[string]bool, len(existingTypes))
+	for _, et := range existingTypes {
+		typeIDs[et.ID] = true
+		typeNames[et.Name] = true
+	}
+	if opts.Mode == ModeReplace {
+		report.Parts.Deleted = len(existingParts)
+		report.Events.Deleted = len(existingEvents)
+		report.EventTypes.Deleted = len(existingTypes)
+		eventIDs, partIDs, typeIDs, typeNames = nil, nil, nil, nil
+	}
+
+	var eventTypes []*storage.EventType
+	for _, et := range snap.EventTypes {
+		if typeIDs[et.ID] || typeNames[et.Name] {
+			report.EventTypes.Skipped++
+			continue
+		}
+		eventTypes = append(eventTypes, et)
+	}
+	var events []*storage.Event
+	written := make(map[string]bool)
+	for _, e := range snap.Events {
+		if eventIDs[e.ID] {
+			report.Events.Skipped++
+			continue
+		}
+		events = append(events, e)
+		written[storage.RevisionTypeEvent+"/"+e.ID] = true
+	}
+	var parts []*storage.LessonPart
+	for _, p := range snap.Parts {
+		if partIDs[p.ID] {
+			report.Parts.Skipped++
+			continue
+		}
+		parts = append(parts, p)
+		written[storage.RevisionTypePart+"/"+p.ID] = true
+	}
+	// Only the histories of the documents written are restored
+	var eventRevisions, partRevisions []*storage.Revision
+	for _, rev := range snap.Revisions {
+		if !written[rev.DocumentType+"/"+rev.DocumentID] {
+			report.Revisions.Skipped++
+			continue
+		}
+		if rev.DocumentType == storage.RevisionTypeEvent {
+			eventRevisions = append(eventRevisions, rev)
+		} else {
+			partRevisions = append(partRevisions, rev)
+		}
+	}
+
+	report.EventTypes.Written = len(eventTypes)
+	report.Events.Written = len(events)
+	report.Parts.Written = len(parts)
+	report.Revisions.Written = len(eventRevisions) + len(partRevisions)
+	report.TemplateWritten = snap.Templates != nil && (opts.Mode == ModeReplace || !hasConfig)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	err = st.Transactor.RunInTransaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		if opts.Mode == ModeReplace {
+			// Deleting a part or event deletes its revisions too
+			for _, p := range existingParts {
+				if err := tx.Parts.DeletePart(ctx, p.ID); err != nil {
+					return fmt.Errorf("failed to delete part %s: %w", p.ID, err)
+				}
+			}
+			for _, e := range existingEvents {
+				if err := tx.Events.DeleteEvent(ctx, e.ID); err != nil {
+					return fmt.Errorf("failed to delete event %s: %w", e.ID, err)
+				}
+			}
+		}
+		if err := tx.Events.ImportEvents(ctx, events, eventRevisions); err != nil {
+			return fmt.Errorf("failed to restore events: %w", err)
+		}
+		if err := tx.Parts.ImportParts(ctx, parts, partRevisions); err != nil {
+			return fmt.Errorf("failed to restore parts: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	if opts.Mode == ModeReplace {
+		for _, et := range existingTypes {
+			if err := st.EventTypes.DeleteEventType(ctx, et.ID); err != nil {
+				return report, fmt.Errorf("failed to delete event type %s: %w", et.Name, err)
+			}
+		}
+	}
+	for _, et := range eventTypes {
+		if err := st.EventTypes.CreateEventType(ctx, et); err != nil {
+			return report, fmt.Errorf("failed to restore event type %s: %w", et.Name, err)
+		}
+	}
+
+	if report.TemplateWritten {
+		config := snap.Templates
+		config.Version = 0
+		if hasConfig {
+			config.Version = currentConfig.Version
+		}
+		if err := st.Templates.SaveConfig(ctx, config); err != nil {
+			return report, fmt.Errorf("failed to restore template config: %w", err)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/Bnei-Baruch/study-material-service/storage/memory"
+)
+
+func memoryStores(s *memory.Store) Stores {
+	return Stores{Parts: s, Events: s, Revisions: s, EventTypes: s, Templates: s, Transactor: s}
+}
+
+// TestRestoreReproducesBackup backs up a store, changes it and restores the
+// backup in replace mode: versions, timestamps and revisions must come back
+// as they were
+func TestRestoreReproducesBackup(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	event := &storage.Event{Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Type: "morning_lesson", Number: 1}
+	if err := s.SaveEvent(ctx, event); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+	part := &storage.LessonPart{EventID: event.ID, Language: "he", Order: 1, Date: event.Date, Title: "First"}
+	if err := s.SavePart(ctx, part); err != nil {
+		t.Fatalf("SavePart: %v", err)
+	}
+	part.Title = "Second"
+	if err := s.SavePart(ctx, part); err != nil {
+		t.Fatalf("SavePart: %v", err)
+	}
+
+	snap, err := Export(ctx, memoryStores(s))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := WriteArchive(&buf, snap, "memory"); err != nil {
+		t.Fatalf("WriteArchive: %v", err)
+	}
+	backedUp, err := s.GetPart(ctx, part.ID)
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	history, err := s.ListRevisions(ctx, storage.RevisionTypePart, part.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+
+	// Change the data after the backup
+	part.Title = "Third"
+	if err := s.SavePart(ctx, part); err != nil {
+		t.Fatalf("SavePart: %v", err)
+	}
+	extra := &storage.LessonPart{EventID: event.ID, Language: "en", Order: 1, Date: event.Date, Title: "Extra"}
+	if err := s.SavePart(ctx, extra); err != nil {
+		t.Fatalf("SavePart: %v", err)
+	}
+
+	restored, _, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatalf("ReadArchive: %v", err)
+	}
+	report, err := Restore(ctx, memoryStores(s), restored, RestoreOptions{Mode: ModeReplace})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if report.Parts.Deleted != 2 || report.Parts.Written != 1 || report.Revisions.Written != 3 {
+		t.Errorf("report %+v", report)
+	}
+
+	got, err := s.GetPart(ctx, part.ID)
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if got.Title != "Second" || got.Version != backedUp.Version || !got.UpdatedAt.Equal(backedUp.UpdatedAt) {
+		t.Errorf("restored part %q at version %d, updated %v; want %q at version %d, updated %v",
+			got.Title, got.Version, got.UpdatedAt, backedUp.Title, backedUp.Version, backedUp.UpdatedAt)
+	}
+	if _, err := s.GetPart(ctx, extra.ID); err == nil {
+		t.Error("part created after the backup survived a replace")
+	}
+	gotHistory, err := s.ListRevisions(ctx, storage.RevisionTypePart, part.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if len(gotHistory) != len(history) {
+		t.Fatalf("%d revisions after restore, want %d", len(gotHistory), len(history))
+	}
+	for i := range history {
+		if gotHistory[i].ID != history[i].ID || gotHistory[i].Part.Title != history[i].Part.Title {
+			t.Errorf("revision %d is %s %q, want %s %q", i, gotHistory[i].ID, gotHistory[i].Part.Title, history[i].ID, history[i].Part.Title)
+		}
+	}
+
+	// The restored part saves on top of its history as usual
+	got.Title = "Fourth"
+	if err := s.SavePart(ctx, got); err != nil {
+		t.Fatalf("SavePart after restore: %v", err)
+	}
+}
+
+func TestReplaceNeedsRevisions(t *testing.T) {
+	s := memory.NewStore()
+	_, err := Restore(context.Background(), memoryStores(s), &Snapshot{}, RestoreOptions{Mode: ModeReplace})
+	if err == nil {
+		t.Error("replace restored an archive without revisions")
+	}
+}
@@ -0,0 +1,247 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// BundleFormatVersion is the event bundle layout written by this version of the service
+const BundleFormatVersion = 1
+
+// ErrEventTypeMissing is returned by ImportEvent when the bundle's event type
+// doesn't exist in the target and the import wasn't told how to handle that
+var ErrEventTypeMissing = errors.New("event type does not exist")
+
+// EventBundle is a self-contained copy of one event that can be imported
+// into another environment
+type EventBundle struct {
+	FormatVersion int                          `json:"format_version"`
+	ExportedAt    time.Time                    `json:"exported_at"`
+	Event         *storage.Event               `json:"event"`
+	Parts         []*storage.LessonPart        `json:"parts"`                // All languages, trashed parts excluded
+	EventType     *storage.EventType           `json:"event_type,omitempty"` // nil if the event's type no longer exists
+	Templates     []storage.TemplateDefinition `json:"templates,omitempty"`  // Title templates used by the parts
+}
+
+// ExportEvent collects an event, its parts in all languages, its event type and
+// the title templates its parts use
+func ExportEvent(ctx context.Context, st Stores, eventID string) (*EventBundle, error) {
+	event, err := st.Events.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	parts, _, err := st.Parts.ListPartsFiltered(ctx, storage.PartQuery{EventID: eventID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+
+	bundle := &EventBundle{
+		FormatVersion: BundleFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Event:         event,
+		Parts:         parts,
+	}
+	eventType, err := st.EventTypes.GetEventTypeByName(ctx, event.Type)
+	switch {
+	case err == nil:
+		bundle.EventType = eventType
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, fmt.Errorf("failed to get event type: %w", err)
+	}
+	if config, err := st.Templates.GetConfig(ctx); err == nil {
+		bundle.Templates = referencedTemplates(config, parts)
+	}
+	return bundle, nil
+}
+
+// referencedTemplates returns the templates whose translation matches the title
+// of a part in the same language
+func referencedTemplates(config *storage.TemplateConfig, parts []*storage.LessonPart) []storage.TemplateDefinition {
+	var templates []storage.TemplateDefinition
+	for _, t := range config.Templates {
+		for _, p := range parts {
+			if p.Title != "" && t.Translations[p.Language] == p.Title {
+				templates = append(templates, t)
+				break
+			}
+		}
+	}
+	return templates
+}
+
+// ValidateBundle checks that a decoded bundle can be imported by this build
+func ValidateBundle(bundle *EventBundle) error {
+	if bundle.FormatVersion < 1 || bundle.FormatVersion > BundleFormatVersion {
+		return fmt.Errorf("unsupported bundle format version %d (this build reads up to %d)", bundle.FormatVersion, BundleFormatVersion)
+	}
+	if bundle.Event == nil {
+		return fmt.Errorf("bundle has no event")
+	}
+	return nil
+}
+
+// MissingEventType selects what ImportEvent does when the bundle's event type
+// doesn't exist in the target
+type MissingEventType string
+
+const (
+	// MissingEventTypeFail rejects the import with ErrEventTypeMissing
+	MissingEventTypeFail MissingEventType = "fail"
+	// MissingEventTypeCreate creates the event type from the bundle
+	MissingEventTypeCreate MissingEventType = "create"
+)
+
+// ParseMissingEventType validates a missing event type policy name
+func ParseMissingEventType(name string) (MissingEventType, error) {
+	switch MissingEventType(name) {
+	case "":
+		return MissingEventTypeFail, nil
+	case MissingEventTypeFail, MissingEventTypeCreate:
+		return MissingEventType(name), nil
+	}
+	return "", fmt.Errorf("unknown missing event type policy %q (use %q or %q)", name, MissingEventTypeFail, MissingEventTypeCreate)
+}
+
+// ImportOptions controls ImportEvent
+type ImportOptions struct {
+	Date             *time.Time       // Moves the event and its parts to this date
+	EventType        string           // Imports the event as this (existing) type instead of the bundle's
+	MissingEventType MissingEventType // What to do if the event type doesn't exist
+	UpdatedBy        string
+}
+
+// ImportResult describes an imported event
+type ImportResult struct {
+	Event            *storage.Event `json:"event"`
+	PartsCreated     int            `json:"parts_created"`
+	EventTypeCreated bool           `json:"event_type_created"`
+	TemplatesAdded   []string       `json:"templates_added,omitempty"`
+}
+
+// ImportEvent recreates a bundled event with fresh event, part and translation
+// group IDs. The event and its parts are written in one transaction; a created
+// event type and added templates are written before it.
+func ImportEvent(ctx context.Context, st Stores, bundle *EventBundle, opts ImportOptions) (*ImportResult, error) {
+	if err := ValidateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+
+	typeName := bundle.Event.Type
+	if opts.EventType != "" {
+		typeName = opts.EventType
+	}
+	if _, err := st.EventTypes.GetEventTypeByName(ctx, typeName); err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("failed to get event type: %w", err)
+		}
+		if opts.EventType != "" || opts.MissingEventType != MissingEventTypeCreate || bundle.EventType == nil || bundle.EventType.Name != typeName {
+			return nil, fmt.Errorf("%w: %s", ErrEventTypeMissing, typeName)
+		}
+		eventType := *bundle.EventType
+		eventType.ID = ""
+		eventType.Version = 0
+		if err := st.EventTypes.CreateEventType(ctx, &eventType); err != nil {
+			return nil, fmt.Errorf("failed to create event type %s: %w", eventType.Name, err)
+		}
+		result.EventTypeCreated = true
+	}
+
+	added, err := addMissingTemplates(ctx, st.Templates, bundle.Templates)
+	if err != nil {
+		return nil, err
+	}
+	result.TemplatesAdded = added
+
+	err = st.Transactor.RunInTransaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		result.PartsCreated = 0
+		newGroups := make(map[string]string)
+
+		event := *bundle.Event
+		event.ID = ""
+		event.Type = typeName
+		event.Version = 0
+		event.CreatedAt = time.Time{}
+		event.EmailSentAt = nil
+		event.DeletedAt = nil
+		event.DeletedBy = ""
+		event.UpdatedBy = opts.UpdatedBy
+		if opts.Date != nil {
+			event.Date = *opts.Date
+		}
+		if err := tx.Events.SaveEvent(ctx, &event); err != nil {
+			return fmt.Errorf("failed to create event: %w", err)
+		}
+		result.Event = &event
+
+		for _, original := range bundle.Parts {
+			key := original.TranslationGroupID
+			if key == "" {
+				key = fmt.Sprintf("order-%d", original.Order)
+			}
+			if newGroups[key] == "" {
+				newGroups[key] = storage.NewTranslationGroupID()
+			}
+
+			part := *original
+			part.ID = ""
+			part.EventID = event.ID
+			part.TranslationGroupID = newGroups[key]
+			part.Version = 0
+			part.CreatedAt = time.Time{}
+			part.DeletedAt = nil
+			part.DeletedBy = ""
+			part.UpdatedBy = opts.UpdatedBy
+			if opts.Date != nil {
+				part.Date = *opts.Date
+			}
+			if err := tx.Parts.SavePart(ctx, &part); err != nil {
+				return fmt.Errorf("failed to create %s part %d: %w", part.Language, part.Order, err)
+			}
+			result.PartsCreated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// addMissingTemplates appends the bundled templates whose IDs aren't in the
+// template config yet and returns their IDs. Nothing is added if no template
+// config is stored; the server seeds one from the templates file on start.
+func addMissingTemplates(ctx context.Context, store storage.TemplateStore, templates []storage.TemplateDefinition) ([]string, error) {
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	config, err := store.GetConfig(ctx)
+	if err != nil {
+		return nil, nil
+	}
+
+	existing := make(map[string]bool, len(config.Templates))
+	for _, t := range config.Templates {
+		existing[t.ID] = true
+	}
+	var added []string
+	for _, t := range templates {
+		if existing[t.ID] {
+			continue
+		}
+		config.Templates = append(config.Templates, t)
+		added = append(added, t.ID)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if err := store.SaveConfig(ctx, config); err != nil {
+		return nil, fmt.Errorf("failed to add templates: %w", err)
+	}
+	return added, nil
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config points at an S3-compatible object store, such as AWS S3 or a local MinIO
+type S3Config struct {
+	Endpoint  string // host[:port], e.g. "localhost:9000"
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+}
+
+// s3Prefix marks a location as an object in an S3 bucket: s3://bucket/key
+const s3Prefix = "s3://"
+
+// IsS3 reports whether a location refers to an S3 object
+func IsS3(location string) bool {
+	return strings.HasPrefix(location, s3Prefix)
+}
+
+// Save stores an archive at a local path or an s3://bucket/key location
+func Save(ctx context.Context, location string, data []byte, cfg S3Config) error {
+	if !IsS3(location) {
+		if dir := filepath.Dir(location); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+		}
+		if err := os.WriteFile(location, data, 0644); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+		return nil
+	}
+
+	client, bucket, key, err := openS3(location, cfg)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/gzip",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload backup to %s: %w", location, err)
+	}
+	return nil
+}
+
+// Load reads an archive from a local path or an s3://bucket/key location
+func Load(ctx context.Context, location string, cfg S3Config) ([]byte, error) {
+	if !IsS3(location) {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		return data, nil
+	}
+
+	client, bucket, key, err := openS3(location, cfg)
+	if err != nil {
+		return nil, err
+	}
+	object, err := client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup from %s: %w", location, err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download backup from %s: %w", location, err)
+	}
+	return data, nil
+}
+
+// openS3 connects to the configured endpoint and splits an s3:// location into bucket and key
+func openS3(location string, cfg S3Config) (*minio.Client, string, string, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(location, s3Prefix), "/")
+	if bucket == "" || key == "" {
+		return nil, "", "", fmt.Errorf("invalid S3 location %q, use s3://bucket/key", location)
+	}
+	if cfg.Endpoint == "" {
+		return nil, "", "", fmt.Errorf("backup.s3.endpoint is not configured")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to connect to %s: %w", cfg.Endpoint, err)
+	}
+	return client, bucket, key, nil
+}
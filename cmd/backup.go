@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/backup"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [destination]",
+	Short: "Back up events, parts, revisions, event types and templates",
+	Long: `Write all events, lesson parts (including trashed ones), their revisions, event types and the
+template configuration to a compressed archive with a manifest and checksums.
+
+The destination is a local path or s3://bucket/key for the S3-compatible store
+configured under [backup.s3]. It defaults to ./backups/study-materials-<time>.tar.gz.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: backupFn,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <source>",
+	Short: "Restore a backup archive",
+	Long: `Restore an archive written by the backup command from a local path or s3://bucket/key.
+
+Modes:
+  replace  delete all events, parts, revisions and event types, then write the backup (needs --yes)
+  merge    only write documents whose IDs aren't stored yet; keep the template config if there is one
+
+Use --dry-run to see what would change without writing anything.`,
+	Args: cobra.ExactArgs(1),
+	RunE: restoreFn,
+}
+
+var (
+	restoreMode    string
+	restoreDryRun  bool
+	restoreConfirm bool
+)
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreMode, "mode", string(backup.ModeMerge), "restore mode: replace or merge")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "report what would change without writing anything")
+	restoreCmd.Flags().BoolVar(&restoreConfirm, "yes", false, "confirm that replace may delete existing data")
+
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
+
+// backupTimeout bounds a single backup or restore command
+const backupTimeout = 30 * time.Minute
+
+// s3Config reads the backup.s3.* settings
+func s3Config() backup.S3Config {
+	return backup.S3Config{
+		Endpoint:  viper.GetString("backup.s3.endpoint"),
+		AccessKey: viper.GetString("backup.s3.access_key"),
+		SecretKey: viper.GetString("backup.s3.secret_key"),
+		Region:    viper.GetString("backup.s3.region"),
+		UseSSL:    viper.GetBool("backup.s3.use_ssl"),
+	}
+}
+
+func backupStores(st *stores) backup.Stores {
+	return backup.Stores{Parts: st.parts, Events: st.events, Revisions: st.revisions, EventTypes: st.eventTypes, Templates: st.templates, Transactor: st.transactor}
+}
+
+func backupFn(cmd *cobra.Command, args []string) error {
+	destination := fmt.Sprintf("./backups/study-materials-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	if len(args) > 0 {
+		destination = args[0]
+	}
+
+	st, err := openStores()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	snap, err := backup.Export(ctx, backupStores(st))
+	if err != nil {
+		return err
+	}
+
+	storageType := viper.GetString("storage.type")
+	if storageType == "" {
+		storageType = "mongodb"
+	}
+	var buf bytes.Buffer
+	manifest, err := backup.WriteArchive(&buf, snap, storageType)
+	if err != nil {
+		return err
+	}
+	if err := backup.Save(ctx, destination, buf.Bytes(), s3Config()); err != nil {
+		return err
+	}
+
+	for _, f := range manifest.Files {
+		fmt.Printf("%-18s %6d documents  sha256 %s\n", f.Name, f.Documents, f.SHA256)
+	}
+	fmt.Printf("Backup written to %s (%d bytes)\n", destination, buf.Len())
+	return nil
+}
+
+func restoreFn(cmd *cobra.Command, args []string) error {
+	mode, err := backup.ParseMode(restoreMode)
+	if err != nil {
+		return err
+	}
+	if mode == backup.ModeReplace && !restoreDryRun && !restoreConfirm {
+		return fmt.Errorf("replace deletes all existing events, parts and event types; pass --yes to confirm or --dry-run to preview")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	data, err := backup.Load(ctx, args[0], s3Config())
+	if err != nil {
+		return err
+	}
+	snap, manifest, err := backup.ReadArchive(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Archive from %s (%s storage), checksums verified\n", manifest.CreatedAt.Format(time.RFC3339), manifest.StorageType)
+
+	st, err := openStores()
+	if err != nil {
+		return err
+	}
+
+	report, err := backup.Restore(ctx, backupStores(st), snap, backup.RestoreOptions{Mode: mode, DryRun: restoreDryRun})
+	if err != nil {
+		return err
+	}
+
+	verb := "Restored"
+	if report.DryRun {
+		verb = "Would restore"
+	}
+	fmt.Printf("%s (%s):\n", verb, mode)
+	fmt.Printf("  event types  deleted %d, written %d, skipped %d\n", report.EventTypes.Deleted, report.EventTypes.Written, report.EventTypes.Skipped)
+	fmt.Printf("  events       deleted %d, written %d, skipped %d\n", report.Events.Deleted, report.Events.Written, report.Events.Skipped)
+	fmt.Printf("  parts        deleted %d, written %d, skipped %d\n", report.Parts.Deleted, report.Parts.Written, report.Parts.Skipped)
+	fmt.Printf("  revisions    written %d, skipped %d\n", report.Revisions.Written, report.Revisions.Skipped)
+	fmt.Printf("  templates    written %v\n", report.TemplateWritten)
+	return nil
+}
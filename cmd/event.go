@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/backup"
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/spf13/cobra"
+)
+
+var eventCmd = &cobra.Command{
+	Use:   "event",
+	Short: "Move single events between environments and maintain their parts",
+	Long:  "Export an event with all its parts, its event type and templates to a bundle, import bundles with fresh IDs, and repair the order of event parts",
+}
+
+var eventExportCmd = &cobra.Command{
+	Use:   "export <event-id> [destination]",
+	Short: "Export an event to a JSON bundle",
+	Long: `Write an event, its parts in all languages, its event type and the title templates
+its parts use to a JSON bundle.
+
+The destination is a local path or s3://bucket/key. It defaults to ./event-<event-id>.json.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: eventExportFn,
+}
+
+var eventImportCmd = &cobra.Command{
+	Use:   "import <source>",
+	Short: "Import an event bundle with fresh IDs",
+	Long: `Recreate an event bundle written by "event export" or GET /api/events/{id}/export
+from a local path or s3://bucket/key. The event, its parts and their translation
+groups get new IDs; templates the target doesn't have yet are added.
+
+If the bundle's event type doesn't exist, the import fails unless
+--missing-event-type=create or --event-type names an existing type.`,
+	Args: cobra.ExactArgs(1),
+	RunE: eventImportFn,
+}
+
+var eventRepairOrderCmd = &cobra.Command{
+	Use:   "repair-order [event-id...]",
+	Short: "Repair events whose languages have drifted into different part orders",
+	Long: `Find parts whose language versions have different orders and move every
+language to the order of the Hebrew version. Checks all events unless event IDs
+are given.`,
+	RunE: eventRepairOrderFn,
+}
+
+var (
+	importDate             string
+	importEventType        string
+	importMissingEventType string
+	repairOrderDryRun      bool
+)
+
+func init() {
+	eventImportCmd.Flags().StringVar(&importDate, "date", "", "move the event and its parts to this date (YYYY-MM-DD)")
+	eventImportCmd.Flags().StringVar(&importEventType, "event-type", "", "import as this existing event type instead of the bundle's")
+	eventImportCmd.Flags().StringVar(&importMissingEventType, "missing-event-type", string(backup.MissingEventTypeFail), "what to do if the event type doesn't exist: fail or create")
+	eventRepairOrderCmd.Flags().BoolVar(&repairOrderDryRun, "dry-run", false, "only list the drifted events")
+
+	eventCmd.AddCommand(eventExportCmd, eventImportCmd, eventRepairOrderCmd)
+	rootCmd.AddCommand(eventCmd)
+}
+
+// eventBundleTimeout bounds a single event command
+const eventBundleTimeout = 5 * time.Minute
+
+func eventExportFn(cmd *cobra.Command, args []string) error {
+	eventID := args[0]
+	destination := fmt.Sprintf("./event-%s.json", eventID)
+	if len(args) > 1 {
+		destination = args[1]
+	}
+
+	st, err := openStores()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventBundleTimeout)
+	defer cancel()
+
+	bundle, err := backup.ExportEvent(ctx, backupStores(st), eventID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := backup.Save(ctx, destination, data, s3Config()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported event %s (%s, %s) with %d parts and %d templates to %s\n",
+		eventID, bundle.Event.Type, bundle.Event.Date.Format("2006-01-02"), len(bundle.Parts), len(bundle.Templates), destination)
+	return nil
+}
+
+func eventImportFn(cmd *cobra.Command, args []string) error {
+	policy, err := backup.ParseMissingEventType(importMissingEventType)
+	if err != nil {
+		return err
+	}
+	opts := backup.ImportOptions{
+		EventType:        importEventType,
+		MissingEventType: policy,
+		UpdatedBy:        "cli",
+	}
+	if importDate != "" {
+		date, err := time.Parse("2006-01-02", importDate)
+		if err != nil {
+			return fmt.Errorf("invalid --date %q, use YYYY-MM-DD", importDate)
+		}
+		opts.Date = &date
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventBundleTimeout)
+	defer cancel()
+
+	data, err := backup.Load(ctx, args[0], s3Config())
+	if err != nil {
+		return err
+	}
+	var bundle backup.EventBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("failed to decode bundle: %w", err)
+	}
+
+	st, err := openStores()
+	if err != nil {
+		return err
+	}
+
+	result, err := backup.ImportEvent(ctx, backupStores(st), &bundle, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported event %s as %s (%s, %s) with %d parts\n",
+		bundle.Event.ID, result.Event.ID, result.Event.Type, result.Event.Date.Format("2006-01-02"), result.PartsCreated)
+	if result.EventTypeCreated {
+		fmt.Printf("Created event type %s\n", result.Event.Type)
+	}
+	for _, id := range result.TemplatesAdded {
+		fmt.Printf("Added template %s\n", id)
+	}
+	return nil
+}
+
+func eventRepairOrderFn(cmd *cobra.Command, args []string) error {
+	st, err := openStores()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventBundleTimeout)
+	defer cancel()
+
+	eventIDs := args
+	if len(eventIDs) == 0 {
+		events, err := st.events.ListEvents(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list events: %w", err)
+		}
+		for _, event := range events {
+			eventIDs = append(eventIDs, event.ID)
+		}
+	}
+
+	drifted, repaired := 0, 0
+	for _, eventID := range eventIDs {
+		parts, _, err := st.parts.ListPartsFiltered(ctx, storage.PartQuery{EventID: eventID})
+		if err != nil {
+			return fmt.Errorf("failed to list parts of event %s: %w", eventID, err)
+		}
+		var driftedGroups []*storage.PartGroup
+		for _, group := range storage.GroupEventParts(parts) {
+			if group.Drifted() {
+				driftedGroups = append(driftedGroups, group)
+			}
+		}
+		if len(driftedGroups) == 0 {
+			continue
+		}
+
+		drifted++
+		fmt.Printf("Event %s:\n", eventID)
+		for _, group := range driftedGroups {
+			fmt.Printf("  %q should be at order %d:", group.Reference().Title, group.Order)
+			for _, p := range group.Parts {
+				if p.Order != group.Order {
+					fmt.Printf(" %s=%d", p.Language, p.Order)
+				}
+			}
+			fmt.Println()
+		}
+		if repairOrderDryRun {
+			continue
+		}
+
+		var changed int
+		err = st.transactor.RunInTransaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+			var err error
+			_, changed, err = storage.RepairEventPartOrder(ctx, tx, eventID, "cli")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to repair event %s: %w", eventID, err)
+		}
+		repaired += changed
+		fmt.Printf("  Moved %d parts\n", changed)
+	}
+
+	if repairOrderDryRun {
+		fmt.Printf("%d of %d events have drifted\n", drifted, len(eventIDs))
+	} else {
+		fmt.Printf("Repaired %d events, %d parts moved\n", drifted, repaired)
+	}
+	return nil
+}
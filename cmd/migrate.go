@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage/migrations"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema migrations",
+	Long:  "Apply, revert and inspect the numbered schema migrations tracked in the schema_migrations collection",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	RunE:  migrateUpFn,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recently applied migrations",
+	RunE:  migrateDownFn,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which migrations have been applied",
+	RunE:  migrateStatusFn,
+}
+
+var (
+	migrateTarget int
+	migrateSteps  int
+)
+
+func init() {
+	migrateUpCmd.Flags().IntVar(&migrateTarget, "to", 0, "apply migrations up to this version (default: latest)")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "number of migrations to revert")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// migrationTimeout bounds a single migrate command. It must stay below the
+// migration lock's stale age, or a slow run could have its lock taken over.
+const migrationTimeout = 30 * time.Minute
+
+// openMigrator opens storage and returns a migrator for its MongoDB database
+func openMigrator() (*migrations.Migrator, error) {
+	st, err := openStores()
+	if err != nil {
+		return nil, err
+	}
+	if st.database == nil {
+		return nil, fmt.Errorf("migrations only apply to mongodb storage")
+	}
+	return migrations.NewMigrator(st.database), nil
+}
+
+func migrateUpFn(cmd *cobra.Command, args []string) error {
+	migrator, err := openMigrator()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	applied, err := migrator.Up(ctx, migrateTarget)
+	for _, m := range applied {
+		fmt.Printf("Applied %04d %s\n", m.Version, m.Description)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("No pending migrations")
+	}
+	return nil
+}
+
+func migrateDownFn(cmd *cobra.Command, args []string) error {
+	migrator, err := openMigrator()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	reverted, err := migrator.Down(ctx, migrateSteps)
+	for _, m := range reverted {
+		fmt.Printf("Reverted %04d %s\n", m.Version, m.Description)
+	}
+	if err != nil {
+		return err
+	}
+	if len(reverted) == 0 {
+		fmt.Println("No applied migrations to revert")
+	}
+	return nil
+}
+
+func migrateStatusFn(cmd *cobra.Command, args []string) error {
+	migrator, err := openMigrator()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = "applied " + st.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%04d  %-28s  %s\n", st.Version, applied, st.Description)
+	}
+	return nil
+}
+
+// autoMigrate applies pending migrations when the server starts (migrations.auto)
+func autoMigrate(st *stores) error {
+	if st.database == nil {
+		log.Println("Skipping auto-migrate: migrations only apply to mongodb storage")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	applied, err := migrations.NewMigrator(st.database).Up(ctx, 0)
+	for _, m := range applied {
+		log.Printf("Applied migration %04d: %s", m.Version, m.Description)
+	}
+	return err
+}
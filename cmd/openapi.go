@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Bnei-Baruch/study-material-service/api"
+	"github.com/spf13/cobra"
+)
+
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Print the OpenAPI document",
+	Long:  "Print the OpenAPI 3 document served at /api/openapi.json",
+	Args:  cobra.NoArgs,
+	RunE:  openapiFn,
+}
+
+var openapiCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the OpenAPI document describes every route",
+	Long:  "Fail if a registered route is missing from the OpenAPI document, or the document describes a route that isn't registered",
+	Args:  cobra.NoArgs,
+	RunE:  openapiCheckFn,
+}
+
+func init() {
+	openapiCmd.AddCommand(openapiCheckCmd)
+	rootCmd.AddCommand(openapiCmd)
+}
+
+func openapiFn(cmd *cobra.Command, args []string) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(api.OpenAPIDocument())
+}
+
+func openapiCheckFn(cmd *cobra.Command, args []string) error {
+	undescribed, unregistered, err := api.UndescribedRoutes()
+	if err != nil {
+		return err
+	}
+	for _, route := range undescribed {
+		fmt.Printf("not in the OpenAPI document: %s\n", route)
+	}
+	for _, route := range unregistered {
+		fmt.Printf("described but not registered: %s\n", route)
+	}
+	if len(undescribed) > 0 || len(unregistered) > 0 {
+		return fmt.Errorf("the OpenAPI document is out of date: add the routes to apiOperations in api/openapi_routes.go")
+	}
+	fmt.Println("The OpenAPI document describes every route")
+	return nil
+}
@@ -40,6 +40,20 @@ func initConfig() {
 	viper.BindEnv("app.max-lessons-per-language", "MAX_LESSONS_PER_LANGUAGE")
 	viper.BindEnv("templates.path", "TEMPLATES_PATH")
 	viper.BindEnv("api.secret_key", "API_SECRET_KEY")
+	viper.BindEnv("migrations.auto", "AUTO_MIGRATE")
+	viper.BindEnv("trash.retention_days", "TRASH_RETENTION_DAYS")
+	viper.BindEnv("server.timeouts.read", "TIMEOUT_READ")
+	viper.BindEnv("server.timeouts.write", "TIMEOUT_WRITE")
+	viper.BindEnv("server.timeouts.bulk", "TIMEOUT_BULK")
+	viper.BindEnv("ids.length", "ID_LENGTH")
+	viper.BindEnv("ids.part_prefix", "ID_PART_PREFIX")
+	viper.BindEnv("ids.event_prefix", "ID_EVENT_PREFIX")
+	viper.BindEnv("ids.event_type_prefix", "ID_EVENT_TYPE_PREFIX")
+	viper.BindEnv("backup.s3.endpoint", "BACKUP_S3_ENDPOINT")
+	viper.BindEnv("backup.s3.access_key", "BACKUP_S3_ACCESS_KEY")
+	viper.BindEnv("backup.s3.secret_key", "BACKUP_S3_SECRET_KEY")
+	viper.BindEnv("backup.s3.region", "BACKUP_S3_REGION")
+	viper.BindEnv("backup.s3.use_ssl", "BACKUP_S3_USE_SSL")
 
 	// Read config file (if exists)
 	if err := viper.ReadInConfig(); err != nil {
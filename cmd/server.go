@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -23,42 +24,25 @@ func init() {
 }
 
 func serverFn(cmd *cobra.Command, args []string) {
-	// Initialize MongoDB storage
-	mongoURI := viper.GetString("mongodb.uri")
-	if mongoURI == "" {
-		mongoURI = "mongodb://localhost:27017"
-	}
-	mongoDatabase := viper.GetString("mongodb.database")
-	if mongoDatabase == "" {
-		mongoDatabase = "study_materials_db"
-	}
-
-	mongoPartStore, err := storage.NewMongoDBStore(mongoURI, mongoDatabase)
-	if err != nil {
-		log.Fatalf("Failed to initialize MongoDB storage: %v", err)
-	}
-	partStore := mongoPartStore
-
-	// Create event store using the same MongoDB database
-	mongoEventStore, err := storage.NewMongoDBEventStore(mongoPartStore.GetDatabase())
+	// Initialize storage backend (mongodb, json or memory)
+	st, err := openStores()
 	if err != nil {
-		log.Fatalf("Failed to initialize MongoDB event store: %v", err)
+		log.Fatalf("Failed to initialize storage: %v", err)
 	}
-	eventStore := mongoEventStore
+	ctx := context.Background()
 
-	// Create event type store using the same MongoDB database
-	mongoEventTypeStore, err := storage.NewMongoDBEventTypeStore(mongoPartStore.GetDatabase())
-	if err != nil {
-		log.Fatalf("Failed to initialize MongoDB event type store: %v", err)
+	// Apply pending schema migrations if enabled
+	if viper.GetBool("migrations.auto") {
+		if err := autoMigrate(st); err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
 	}
 
 	// Seed default event types on first run
-	if err := storage.SeedDefaultEventTypes(mongoEventTypeStore); err != nil {
+	if err := storage.SeedDefaultEventTypes(ctx, st.eventTypes); err != nil {
 		log.Fatalf("Failed to seed default event types: %v", err)
 	}
 
-	log.Printf("Initialized MongoDB storage: %s/%s", mongoURI, mongoDatabase)
-
 	// Initialize kabbalahmedia client
 	kabbalahmediaURL := viper.GetString("kabbalahmedia.sqdata_url")
 	if kabbalahmediaURL == "" {
@@ -82,23 +66,17 @@ func serverFn(cmd *cobra.Command, args []string) {
 	}
 	log.Printf("Loaded %d templates in %d languages from JSON", len(jsonTemplateConfig.Templates), len(jsonTemplateConfig.Languages))
 
-	// Initialize template store
-	mongoTemplateStore, err := storage.NewMongoDBTemplateStore(mongoEventStore.GetDatabase())
-	if err != nil {
-		log.Fatalf("Failed to initialize MongoDB template store: %v", err)
-	}
-
-	// Initialize MongoDB with JSON data on first run (if not already initialized)
-	if err := mongoTemplateStore.InitializeFromJSON(jsonTemplateConfig); err != nil {
-		log.Fatalf("Failed to initialize templates in MongoDB: %v", err)
+	// Initialize the template store with JSON data on first run (if not already initialized)
+	if err := st.templates.InitializeFromJSON(ctx, jsonTemplateConfig); err != nil {
+		log.Fatalf("Failed to initialize templates in storage: %v", err)
 	}
 
-	// Load templates from MongoDB
-	templateConfig, err := mongoTemplateStore.GetConfig()
+	// Load templates from storage
+	templateConfig, err := st.templates.GetConfig(ctx)
 	if err != nil {
-		log.Fatalf("Failed to load templates from MongoDB: %v", err)
+		log.Fatalf("Failed to load templates from storage: %v", err)
 	}
-	log.Printf("Loaded %d templates from MongoDB", len(templateConfig.Templates))
+	log.Printf("Loaded %d templates from storage", len(templateConfig.Templates))
 
 	// Load API secret key
 	apiSecretKey := viper.GetString("api.secret_key")
@@ -108,7 +86,42 @@ func serverFn(cmd *cobra.Command, args []string) {
 		log.Println("API secret key protection enabled")
 	}
 
+	// Purge trashed events and parts once they are past the retention period
+	retentionDays := viper.GetInt("trash.retention_days")
+	if retentionDays <= 0 {
+		retentionDays = 30
+	}
+	trashRetention := time.Duration(retentionDays) * 24 * time.Hour
+	startTrashPurger(st.parts, st.events, trashRetention)
+	log.Printf("Trashed items are purged after %d days", retentionDays)
+
+	// Restrict moving and copying parts to compatible event types, if configured
+	var eventTypeGroups [][]string
+	if err := viper.UnmarshalKey("parts.compatible_event_types", &eventTypeGroups); err != nil {
+		log.Fatalf("Invalid parts.compatible_event_types: %v", err)
+	}
+
+	// Cache-Control for GET responses, by route
+	cacheControl := api.CacheControl{
+		Default: viper.GetString("cache.default"),
+		Routes:  viper.GetStringMapString("cache.routes"),
+	}
+
+	// Date the deprecated v1 reads will be removed, announced in the Sunset header
+	var v1Sunset time.Time
+	if s := viper.GetString("api.v1_sunset"); s != "" {
+		if v1Sunset, err = time.Parse("2006-01-02", s); err != nil {
+			log.Fatalf("Invalid api.v1_sunset %q, use YYYY-MM-DD: %v", s, err)
+		}
+	}
+
+	// Bounds on the cost of a GraphQL query
+	graphQLLimits := api.GraphQLLimits{
+		MaxDepth:      viper.GetInt("graphql.max_depth"),
+		MaxComplexity: viper.GetInt("graphql.max_complexity"),
+	}
+
 	// Start API server with dependencies
-	app := api.NewApp(partStore, eventStore, mongoEventTypeStore, mongoTemplateStore, kabbalahmediaClient, templateConfig, apiSecretKey)
+	app := api.NewApp(st.parts, st.events, st.revisions, st.eventTypes, st.templates, st.transactor, kabbalahmediaClient, templateConfig, apiSecretKey, trashRetention, eventTypeGroups, cacheControl, v1Sunset, graphQLLimits)
 	app.Init()
 }
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/Bnei-Baruch/study-material-service/storage/jsonfile"
+	"github.com/Bnei-Baruch/study-material-service/storage/memory"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// stores bundles the storage backends the API depends on
+type stores struct {
+	parts      storage.PartStore
+	events     storage.EventStore
+	revisions  storage.RevisionStore
+	eventTypes storage.EventTypeStore
+	templates  storage.TemplateStore
+	transactor storage.Transactor
+	database   *mongo.Database // Set only for mongodb storage
+}
+
+// openStores initializes the storage backend selected by storage.type (mongodb, json or memory)
+func openStores() (*stores, error) {
+	if err := configureIDs(); err != nil {
+		return nil, err
+	}
+	configureTimeouts()
+
+	storageType := viper.GetString("storage.type")
+	switch storageType {
+	case "", "mongodb":
+		return openMongoStores()
+	case "json":
+		dataDir := viper.GetString("storage.data_dir")
+		if dataDir == "" {
+			dataDir = "./data"
+		}
+		store, err := jsonfile.Open(dataDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize JSON storage: %w", err)
+		}
+		log.Printf("Initialized JSON file storage: %s", dataDir)
+		return &stores{parts: store, events: store, revisions: store, eventTypes: store, templates: store, transactor: store}, nil
+	case "memory":
+		store := memory.NewStore()
+		log.Println("Initialized in-memory storage (data is lost on restart)")
+		return &stores{parts: store, events: store, revisions: store, eventTypes: store, templates: store, transactor: store}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", storageType)
+	}
+}
+
+// openMongoStores connects to MongoDB and creates all collection stores
+func openMongoStores() (*stores, error) {
+	mongoURI := viper.GetString("mongodb.uri")
+	if mongoURI == "" {
+		mongoURI = "mongodb://localhost:27017"
+	}
+	mongoDatabase := viper.GetString("mongodb.database")
+	if mongoDatabase == "" {
+		mongoDatabase = "study_materials_db"
+	}
+
+	mongoPartStore, err := storage.NewMongoDBStore(mongoURI, mongoDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MongoDB storage: %w", err)
+	}
+
+	// Create event store using the same MongoDB database
+	mongoEventStore, err := storage.NewMongoDBEventStore(mongoPartStore.GetDatabase())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MongoDB event store: %w", err)
+	}
+
+	// Create revision store using the same MongoDB database
+	mongoRevisionStore, err := storage.NewMongoDBRevisionStore(mongoPartStore.GetDatabase())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MongoDB revision store: %w", err)
+	}
+
+	// Create event type store using the same MongoDB database
+	mongoEventTypeStore, err := storage.NewMongoDBEventTypeStore(mongoPartStore.GetDatabase())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MongoDB event type store: %w", err)
+	}
+
+	// Initialize template store
+	mongoTemplateStore, err := storage.NewMongoDBTemplateStore(mongoPartStore.GetDatabase())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MongoDB template store: %w", err)
+	}
+
+	// Cascading writes across parts and events run in transactions
+	mongoTransactor, err := storage.NewMongoDBTransactor(mongoPartStore, mongoEventStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MongoDB transactions: %w", err)
+	}
+
+	log.Printf("Initialized MongoDB storage: %s/%s", mongoURI, mongoDatabase)
+
+	return &stores{
+		parts:      mongoPartStore,
+		events:     mongoEventStore,
+		revisions:  mongoRevisionStore,
+		eventTypes: mongoEventTypeStore,
+		templates:  mongoTemplateStore,
+		transactor: mongoTransactor,
+		database:   mongoPartStore.GetDatabase(),
+	}, nil
+}
+
+// configureIDs applies the ids.* settings to generated document IDs
+func configureIDs() error {
+	err := storage.ConfigureIDs(viper.GetInt("ids.length"), map[string]string{
+		storage.IDCollectionParts:      viper.GetString("ids.part_prefix"),
+		storage.IDCollectionEvents:     viper.GetString("ids.event_prefix"),
+		storage.IDCollectionEventTypes: viper.GetString("ids.event_type_prefix"),
+	})
+	if err != nil {
+		return fmt.Errorf("invalid ids configuration: %w", err)
+	}
+	return nil
+}
+
+// configureTimeouts applies the server.timeouts.* settings to storage operations
+func configureTimeouts() {
+	storage.ConfigureTimeouts(storage.Timeouts{
+		Read:  viper.GetDuration("server.timeouts.read"),
+		Write: viper.GetDuration("server.timeouts.write"),
+		Bulk:  viper.GetDuration("server.timeouts.bulk"),
+	})
+	t := storage.CurrentTimeouts()
+	log.Printf("Storage timeouts: read %v, write %v, bulk %v", t.Read, t.Write, t.Bulk)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// trashPurgeInterval is how often trashed documents past their retention are purged
+const trashPurgeInterval = time.Hour
+
+// startTrashPurger permanently deletes events and parts that have been in the
+// trash for longer than retention, once on start and then every trashPurgeInterval
+func startTrashPurger(parts storage.PartStore, events storage.EventStore, retention time.Duration) {
+	purge := func() {
+		ctx := context.Background()
+		before := time.Now().Add(-retention)
+
+		purgedEvents, err := events.PurgeTrashedEvents(ctx, before)
+		if err != nil {
+			log.Printf("Failed to purge trashed events: %v", err)
+		}
+		purgedParts, err := parts.PurgeTrashedParts(ctx, before)
+		if err != nil {
+			log.Printf("Failed to purge trashed parts: %v", err)
+		}
+		if purgedEvents > 0 || purgedParts > 0 {
+			log.Printf("Purged %d events and %d parts from the trash", purgedEvents, purgedParts)
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
@@ -2,7 +2,7 @@
 
 import { useState, useEffect } from 'react'
 import { useParams } from 'next/navigation'
-import { getApiUrl } from '@/lib/api'
+import { getApiUrl, getErrorMessage } from '@/lib/api'
 import { formatEventDate, formatDateTimeInIsraelTimezone, formatDateForInput } from '@/lib/dateUtils'
 import Link from 'next/link'
 import EventTypeBadge from '@/components/EventTypeBadge'
@@ -402,7 +402,7 @@ const fetchEventAndParts = async () => {
       })
 
       if (!response.ok) {
-        const errorText = await response.text()
+        const errorText = await getErrorMessage(response)
         throw new Error(`Failed to update part: ${response.status} ${errorText}`)
       }
 
@@ -444,7 +444,7 @@ const fetchEventAndParts = async () => {
         })
 
         if (!response.ok) {
-          const errorText = await response.text()
+          const errorText = await getErrorMessage(response)
           throw new Error(`Failed to update ${part.language} version: ${response.status} ${errorText}`)
         }
       }
@@ -2,7 +2,7 @@
 
 import { useState } from 'react'
 import { useRouter } from 'next/navigation'
-import { getApiUrl } from '@/lib/api'
+import { getApiUrl, getErrorMessage } from '@/lib/api'
 import Link from 'next/link'
 import ProtectedRoute from '@/components/ProtectedRoute'
 import { useAuth } from '@/contexts/AuthContext'
@@ -162,7 +162,7 @@ function CreateEventPageContent() {
       })
 
       if (!response.ok) {
-        const text = await response.text()
+        const text = await getErrorMessage(response)
         throw new Error(text || 'Failed to create event')
       }
 
@@ -1,7 +1,7 @@
 'use client'
 
 import { useState, useEffect } from 'react'
-import { getApiUrl } from '@/lib/api'
+import { getApiUrl, getErrorMessage } from '@/lib/api'
 import { SourceSearch } from './SourceSearch'
 
 interface Source {
@@ -236,7 +236,7 @@ export default function PartForm({ eventId, eventDate, existingParts, onPartCrea
       })
 
       if (!response.ok) {
-        const text = await response.text()
+        const text = await getErrorMessage(response)
         throw new Error(text || 'Failed to create part')
       }
 
@@ -2,7 +2,7 @@
 
 import { useState, useEffect } from 'react'
 import { Trash2, Plus, Check, X } from 'lucide-react'
-import { getApiUrl } from '@/lib/api'
+import { getApiUrl, getErrorMessage } from '@/lib/api'
 
 interface TemplateDefinition {
   id: string
@@ -84,7 +84,7 @@ export default function TemplateManager() {
       })
 
       if (!res.ok) {
-        const error = await res.text()
+        const error = await getErrorMessage(res)
         throw new Error(error || 'Failed to save template')
       }
 
@@ -133,7 +133,7 @@ export default function TemplateManager() {
       })
 
       if (!res.ok) {
-        const error = await res.text()
+        const error = await getErrorMessage(res)
         throw new Error(error || 'Failed to create template')
       }
 
@@ -30,3 +30,19 @@ export const getApiUrl = (endpoint: string): string => {
 
 
 
+/**
+ * Reads the message of an API error response.
+ * Errors are JSON: { "error": { "code", "message", "details", "request_id" } }
+ */
+export const getErrorMessage = async (response: Response): Promise<string> => {
+  const text = await response.text();
+  try {
+    const body = JSON.parse(text);
+    if (body?.error?.message) {
+      return body.error.message;
+    }
+  } catch {
+    // Not JSON, e.g. from a proxy in front of the API
+  }
+  return text;
+};
@@ -1,6 +1,7 @@
 package kabbalahmedia
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -43,13 +44,14 @@ func init() {
 }
 
 // SearchSources searches for sources in kabbalahmedia sqdata across multiple languages
-func (c *Client) SearchSources(query string) ([]SourceResult, error) {
+// Sources that aren't cached yet are fetched with ctx, so cancelling it stops the fetch.
+func (c *Client) SearchSources(ctx context.Context, query string) ([]SourceResult, error) {
 	// Support languages: Hebrew, Russian, English, Spanish
 	languages := []string{"he", "ru", "en", "es"}
 
 	// Fetch and cache sources for all languages if not already cached
 	for _, lang := range languages {
-		if err := c.ensureSourcesCacheForLanguage(lang); err != nil {
+		if err := c.ensureSourcesCacheForLanguage(ctx, lang); err != nil {
 			return nil, fmt.Errorf("failed to fetch sources for %s: %w", lang, err)
 		}
 	}
@@ -89,7 +91,7 @@ func (c *Client) SearchSources(query string) ([]SourceResult, error) {
 }
 
 // ensureSourcesCacheForLanguage fetches and caches sources for a specific language
-func (c *Client) ensureSourcesCacheForLanguage(lang string) error {
+func (c *Client) ensureSourcesCacheForLanguage(ctx context.Context, lang string) error {
 	cacheMutex.RLock()
 	if cachedSources[lang] != nil {
 		cacheMutex.RUnlock()
@@ -109,7 +111,11 @@ func (c *Client) ensureSourcesCacheForLanguage(lang string) error {
 	url := fmt.Sprintf("%s?language=%s", c.baseURL, lang)
 
 	// Fetch from API
-	resp, err := c.httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -164,9 +170,9 @@ func (c *Client) searchNodeMultiLang(node SourceNode, path string, query string,
 }
 
 // GetSourceTitle retrieves the title of a source in a specific language
-func (c *Client) GetSourceTitle(sourceID string, language string) (string, error) {
+func (c *Client) GetSourceTitle(ctx context.Context, sourceID string, language string) (string, error) {
 	// Ensure cache is loaded for the requested language
-	if err := c.ensureSourcesCacheForLanguage(language); err != nil {
+	if err := c.ensureSourcesCacheForLanguage(ctx, language); err != nil {
 		return "", fmt.Errorf("failed to load sources for language %s: %w", language, err)
 	}
 
@@ -0,0 +1,60 @@
+package storage
+
+import "context"
+
+// Attempt is one run of a unit of work of RunInTransaction. Stores don't change
+// the caller's documents until the transaction commits, so a unit of work that
+// is aborted, or run again after a transient error, sees them as they were.
+type Attempt struct {
+	commit []func()
+	abort  []func()
+}
+
+type attemptKey struct{}
+
+// NewAttempt returns a context for one run of a unit of work
+func NewAttempt(ctx context.Context) (context.Context, *Attempt) {
+	a := &Attempt{}
+	return context.WithValue(ctx, attemptKey{}, a), a
+}
+
+// AfterCommit calls fn once the writes made with ctx are committed: right away
+// outside a unit of work
+func AfterCommit(ctx context.Context, fn func()) {
+	if a, ok := ctx.Value(attemptKey{}).(*Attempt); ok {
+		a.commit = append(a.commit, fn)
+		return
+	}
+	fn()
+}
+
+// AfterAbort calls fn if the unit of work the writes made with ctx belong to is
+// aborted or run again. Outside a unit of work fn is never called.
+func AfterAbort(ctx context.Context, fn func()) {
+	if a, ok := ctx.Value(attemptKey{}).(*Attempt); ok {
+		a.abort = append(a.abort, fn)
+	}
+}
+
+// Commit applies the changes to the caller's documents once the transaction
+// has committed
+func (a *Attempt) Commit() {
+	if a == nil {
+		return
+	}
+	for _, fn := range a.commit {
+		fn()
+	}
+	a.commit, a.abort = nil, nil
+}
+
+// Abort undoes what the attempt already changed on the caller's documents
+func (a *Attempt) Abort() {
+	if a == nil {
+		return
+	}
+	for i := len(a.abort) - 1; i >= 0; i-- {
+		a.abort[i]()
+	}
+	a.commit, a.abort = nil, nil
+}
@@ -0,0 +1,11 @@
+package storage
+
+import "errors"
+
+// ErrNotFound is returned when the requested document doesn't exist, or is
+// trashed and the lookup doesn't include trashed documents
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is returned when a document would duplicate a unique field of
+// another, e.g. the name of an event type
+var ErrAlreadyExists = errors.New("already exists")
@@ -1,43 +1,93 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"math/rand"
 	"sync"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
-	// IDLength is the length of generated short IDs (6 characters)
-	IDLength = 6
+	// DefaultIDLength is the length of the random part of generated IDs
+	DefaultIDLength = 6
+	// MaxIDAttempts is how many fresh IDs are tried before creation gives up
+	MaxIDAttempts = 10
 	// Charset for generating IDs - alphanumeric characters
 	charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 )
 
+// Collections that IDs are generated for
+const (
+	IDCollectionParts      = "parts"
+	IDCollectionEvents     = "events"
+	IDCollectionEventTypes = "event_types"
+)
+
+// IDFormat controls how IDs for a collection look
+type IDFormat struct {
+	Prefix string // Optional prefix, e.g. "ev_"
+	Length int    // Length of the random part
+}
+
 var (
 	seededRand = rand.New(rand.NewSource(time.Now().UnixNano()))
-	idMutex    sync.RWMutex
-	usedIDs    = make(map[string]bool)
+	idMutex    sync.Mutex
+	idLength   = DefaultIDLength
+	idPrefixes = map[string]string{}
 )
 
-// generateShortID generates a random 6-character alphanumeric ID
-// It ensures uniqueness by checking against previously generated IDs
-func generateShortID() string {
+// ConfigureIDs sets the length of generated IDs and the prefix used for each collection.
+// A length of 0 keeps DefaultIDLength; collections without a prefix get none.
+func ConfigureIDs(length int, prefixes map[string]string) error {
+	if length < 0 {
+		return fmt.Errorf("invalid id length: %d", length)
+	}
+	for collection, prefix := range prefixes {
+		for _, c := range prefix {
+			if !isIDChar(c) {
+				return fmt.Errorf("invalid id prefix %q for %s: only letters, digits, '_' and '-' are allowed", prefix, collection)
+			}
+		}
+	}
+
 	idMutex.Lock()
 	defer idMutex.Unlock()
-
-	for {
-		id := randomString(IDLength)
-		if !usedIDs[id] {
-			usedIDs[id] = true
-			return id
-		}
+	idLength = DefaultIDLength
+	if length > 0 {
+		idLength = length
+	}
+	idPrefixes = make(map[string]string, len(prefixes))
+	for collection, prefix := range prefixes {
+		idPrefixes[collection] = prefix
 	}
+	return nil
+}
+
+// IDFormatFor returns the configured ID format of a collection
+func IDFormatFor(collection string) IDFormat {
+	idMutex.Lock()
+	defer idMutex.Unlock()
+	return IDFormat{Prefix: idPrefixes[collection], Length: idLength}
+}
+
+// NewID generates a random ID for a collection.
+// IDs are not guaranteed to be unique: stores check that an ID is free before
+// inserting it, and call NewID again when it is taken.
+func NewID(collection string) string {
+	format := IDFormatFor(collection)
+	return format.Prefix + randomString(format.Length)
 }
 
 // randomString generates a random string of specified length
 func randomString(length int) string {
+	idMutex.Lock()
+	defer idMutex.Unlock()
 	b := make([]byte, length)
 	for i := range b {
 		b[i] = charset[seededRand.Intn(len(charset))]
@@ -45,29 +95,24 @@ func randomString(length int) string {
 	return string(b)
 }
 
-// RegisterID registers an existing ID to prevent collision
-// This should be called when loading IDs from storage
-func RegisterID(id string) {
-	idMutex.Lock()
-	defer idMutex.Unlock()
-	usedIDs[id] = true
+func isIDChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
 }
 
-// ClearRegisteredIDs clears all registered IDs
-// Use this with caution - typically only for testing
-func ClearRegisteredIDs() {
-	idMutex.Lock()
-	defer idMutex.Unlock()
-	usedIDs = make(map[string]bool)
+// isDuplicateIDError reports whether a MongoDB write failed because the _id is taken,
+// as opposed to another unique index
+func isDuplicateIDError(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCodeWithMessage(11000, "index: _id_")
 }
 
-// GetIDInfo returns information about generated IDs for debugging
-func GetIDInfo() map[string]interface{} {
-	idMutex.RLock()
-	defer idMutex.RUnlock()
-	return map[string]interface{}{
-		"total_generated": len(usedIDs),
-		"charset_length": len(charset),
-		"possible_combinations": fmt.Sprintf("%.0f", math.Pow(float64(len(charset)), IDLength)),
+// idTaken reports whether a document, trashed or not, already has the ID.
+// Stores check before inserting because inside a transaction a failed insert
+// aborts the whole transaction and can't simply be retried.
+func idTaken(ctx context.Context, collection *mongo.Collection, id string) (bool, error) {
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check id %s: %w", id, err)
 	}
+	return count > 0, nil
 }
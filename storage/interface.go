@@ -1,39 +1,90 @@
 package storage
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"context"
+	"time"
 
-// PartStore defines the interface for lesson part storage
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PartStore defines the interface for lesson part storage.
+// SavePart appends a revision of the saved part to its history.
+// GetPart and ListPartsFiltered hide trashed parts unless the query asks for them;
+// ListParts returns every stored part, trashed or not.
+// SearchParts ranks untrashed parts by how well they match a full-text search.
+// ImportParts writes parts and their revisions exactly as given, e.g. from a backup:
+// versions and timestamps are kept and no revision is appended.
 type PartStore interface {
-	SavePart(part *LessonPart) error
-	GetPart(id string) (*LessonPart, error)
-	ListParts() ([]*LessonPart, error)
-	DeletePart(id string) error
+	SavePart(ctx context.Context, part *LessonPart) error
+	GetPart(ctx context.Context, id string) (*LessonPart, error)
+	ListParts(ctx context.Context) ([]*LessonPart, error)
+	ListPartsFiltered(ctx context.Context, query PartQuery) ([]*LessonPart, int, error)
+	DeletePart(ctx context.Context, id string) error
+	TrashPart(ctx context.Context, id string, deletion Deletion) error
+	RestorePart(ctx context.Context, id string) error
+	PurgeTrashedParts(ctx context.Context, before time.Time) (int, error)
+	SearchParts(ctx context.Context, query SearchQuery) ([]PartHit, error)
+	ImportParts(ctx context.Context, parts []*LessonPart, revisions []*Revision) error
 }
 
-// EventStore defines the interface for event storage
+// EventStore defines the interface for event storage.
+// SaveEvent appends a revision of the saved event to its history.
+// GetEvent and ListEventsFiltered hide trashed events unless the filter constrains
+// deleted_at; ListEvents returns every stored event, trashed or not.
+// SearchEvents ranks untrashed events by how well their titles match a full-text search.
+// ImportEvents writes events and their revisions exactly as given, like ImportParts.
 type EventStore interface {
-	SaveEvent(event *Event) error
-	GetEvent(id string) (*Event, error)
-	ListEvents() ([]*Event, error)
-	ListEventsFiltered(filter bson.M, limit, offset int) ([]Event, int, error)
-	DeleteEvent(id string) error
+	SaveEvent(ctx context.Context, event *Event) error
+	GetEvent(ctx context.Context, id string) (*Event, error)
+	ListEvents(ctx context.Context) ([]*Event, error)
+	ListEventsFiltered(ctx context.Context, filter bson.M, limit, offset int) ([]Event, int, error)
+	DeleteEvent(ctx context.Context, id string) error
+	TrashEvent(ctx context.Context, id string, deletion Deletion) error
+	RestoreEvent(ctx context.Context, id string) error
+	PurgeTrashedEvents(ctx context.Context, before time.Time) (int, error)
+	SearchEvents(ctx context.Context, query SearchQuery) ([]EventHit, error)
+	ListPublicEvents(ctx context.Context, query PublicEventsQuery) ([]*EventWithParts, error)
+	ImportEvents(ctx context.Context, events []*Event, revisions []*Revision) error
+}
+
+// RevisionStore reads the revision history that SavePart and SaveEvent append to.
+// Revisions are listed oldest first; ListAllRevisions returns every stored one.
+type RevisionStore interface {
+	ListRevisions(ctx context.Context, documentType, documentID string) ([]*Revision, error)
+	GetRevision(ctx context.Context, documentType, documentID string, rev int) (*Revision, error)
+	ListAllRevisions(ctx context.Context) ([]*Revision, error)
+}
+
+// Tx holds the stores bound to a running transaction
+type Tx struct {
+	Parts  PartStore
+	Events EventStore
+}
+
+// Transactor runs units of work that span several part and event writes
+type Transactor interface {
+	// RunInTransaction calls fn with stores bound to a transaction. fn must pass the
+	// context it is given to every call it makes through tx. If fn returns an error,
+	// none of the writes made through tx are kept. The whole unit of work is bounded
+	// by the bulk timeout.
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
 }
 
 // EventTypeStore defines the interface for event type storage
 type EventTypeStore interface {
-	CreateEventType(eventType *EventType) error
-	GetEventType(id string) (*EventType, error)
-	GetEventTypeByName(name string) (*EventType, error)
-	ListEventTypes() ([]*EventType, error)
-	UpdateEventType(eventType *EventType) error
-	DeleteEventType(id string) error
-	CountEventTypes() (int64, error)
+	CreateEventType(ctx context.Context, eventType *EventType) error
+	GetEventType(ctx context.Context, id string) (*EventType, error)
+	GetEventTypeByName(ctx context.Context, name string) (*EventType, error)
+	ListEventTypes(ctx context.Context) ([]*EventType, error)
+	UpdateEventType(ctx context.Context, eventType *EventType) error
+	DeleteEventType(ctx context.Context, id string) error
+	CountEventTypes(ctx context.Context) (int64, error)
 }
 
 // TemplateStore defines the interface for template storage
 type TemplateStore interface {
-	SaveConfig(config *TemplateConfig) error
-	GetConfig() (*TemplateConfig, error)
-	InitializeFromJSON(config *TemplateConfig) error
+	SaveConfig(ctx context.Context, config *TemplateConfig) error
+	GetConfig(ctx context.Context) (*TemplateConfig, error)
+	InitializeFromJSON(ctx context.Context, config *TemplateConfig) error
 }
 
@@ -0,0 +1,32 @@
+//go:build !unix
+
+package jsonfile
+
+import (
+	"fmt"
+	"os"
+)
+
+// fileLock is a lock file created exclusively. Unlike flock it is not released
+// if the process crashes; delete the .lock file by hand in that case.
+type fileLock struct {
+	path string
+}
+
+func acquireLock(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("already in use by another process (remove %s if it is stale)", path)
+		}
+		return nil, err
+	}
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+	f.Close()
+
+	return &fileLock{path: path}, nil
+}
+
+func (l *fileLock) release() error {
+	return os.Remove(l.path)
+}
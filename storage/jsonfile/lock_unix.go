@@ -0,0 +1,42 @@
+//go:build unix
+
+package jsonfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileLock is an exclusive advisory lock held for the lifetime of the store.
+// The kernel drops it automatically if the process dies.
+type fileLock struct {
+	file *os.File
+}
+
+func acquireLock(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("already in use by another process")
+		}
+		return nil, err
+	}
+
+	// Record the owner for debugging; the lock itself is the flock
+	f.Truncate(0)
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+
+	return &fileLock{file: f}, nil
+}
+
+func (l *fileLock) release() error {
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	return l.file.Close()
+}
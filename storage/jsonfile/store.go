@@ -0,0 +1,213 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"github.com/Bnei-Baruch/study-material-service/storage/memory"
+)
+
+// Store keeps every event, part, revision and event type as its own JSON document under a
+// data directory. Documents are indexed in memory on startup, so reads never touch
+// the disk; writes go to disk first and only then update the index.
+//
+// Layout of the data directory:
+//
+//	events/<id>.json
+//	parts/<id>.json
+//	event_types/<id>.json
+//	revisions/<type>-<id>-<rev>.json
+//	templates.json
+//	.lock
+type Store struct {
+	*memory.Store
+	dir  string
+	lock *fileLock
+}
+
+// collectionDirs maps storage collections to their directory under the data dir
+var collectionDirs = map[string]string{
+	memory.CollectionParts:      "parts",
+	memory.CollectionEvents:     "events",
+	memory.CollectionEventTypes: "event_types",
+	memory.CollectionRevisions:  "revisions",
+}
+
+const templatesFile = "templates.json"
+
+// validDocID guards against IDs that would escape the collection directory
+var validDocID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Open locks the data directory and rebuilds the in-memory index from its documents.
+// Only one process may have a data directory open at a time.
+func Open(dir string) (*Store, error) {
+	for _, sub := range collectionDirs {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+
+	lock, err := acquireLock(filepath.Join(dir, ".lock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock data directory %s: %w", dir, err)
+	}
+
+	s := &Store{dir: dir, lock: lock}
+	s.Store = memory.NewPersistentStore(s)
+
+	if err := s.rebuildIndex(); err != nil {
+		lock.release()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close releases the data directory lock
+func (s *Store) Close() error {
+	return s.lock.release()
+}
+
+// rebuildIndex loads all documents from disk into the in-memory index
+func (s *Store) rebuildIndex() error {
+	var parts []*storage.LessonPart
+	if err := loadCollection(s.collectionDir(memory.CollectionParts), func() interface{} {
+		part := &storage.LessonPart{}
+		parts = append(parts, part)
+		return part
+	}); err != nil {
+		return fmt.Errorf("failed to load parts: %w", err)
+	}
+
+	var events []*storage.Event
+	if err := loadCollection(s.collectionDir(memory.CollectionEvents), func() interface{} {
+		event := &storage.Event{}
+		events = append(events, event)
+		return event
+	}); err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+
+	var eventTypes []*storage.EventType
+	if err := loadCollection(s.collectionDir(memory.CollectionEventTypes), func() interface{} {
+		et := &storage.EventType{}
+		eventTypes = append(eventTypes, et)
+		return et
+	}); err != nil {
+		return fmt.Errorf("failed to load event types: %w", err)
+	}
+
+	var revisions []*storage.Revision
+	if err := loadCollection(s.collectionDir(memory.CollectionRevisions), func() interface{} {
+		rev := &storage.Revision{}
+		revisions = append(revisions, rev)
+		return rev
+	}); err != nil {
+		return fmt.Errorf("failed to load revisions: %w", err)
+	}
+
+	if matches, err := filepath.Glob(filepath.Join(s.dir, templatesFile+".*.tmp")); err == nil {
+		for _, stale := range matches {
+			os.Remove(stale)
+		}
+	}
+
+	var config *storage.TemplateConfig
+	data, err := os.ReadFile(filepath.Join(s.dir, templatesFile))
+	switch {
+	case err == nil:
+		config = &storage.TemplateConfig{}
+		if err := json.Unmarshal(data, config); err != nil {
+			return fmt.Errorf("failed to load template config: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to load template config: %w", err)
+	}
+
+	s.Seed(parts, events, eventTypes, revisions, config)
+	log.Printf("Loaded %d events, %d parts and %d event types from %s", len(events), len(parts), len(eventTypes), s.dir)
+	return nil
+}
+
+// loadCollection decodes every *.json file in dir into a value returned by newDoc.
+// Leftover temp files from interrupted writes are removed.
+func loadCollection(dir string, newDoc func() interface{}) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, newDoc()); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Put writes a document to disk (implements memory.Persister)
+func (s *Store) Put(collection, id string, doc interface{}) error {
+	path, err := s.docPath(collection, id)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Remove deletes a document from disk (implements memory.Persister)
+func (s *Store) Remove(collection, id string) error {
+	path, err := s.docPath(collection, id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// docPath returns the file holding a document
+func (s *Store) docPath(collection, id string) (string, error) {
+	if collection == memory.CollectionTemplates {
+		return filepath.Join(s.dir, templatesFile), nil
+	}
+	if _, ok := collectionDirs[collection]; !ok {
+		return "", fmt.Errorf("unknown collection: %s", collection)
+	}
+	if !validDocID.MatchString(id) {
+		return "", fmt.Errorf("invalid document id: %q", id)
+	}
+	return filepath.Join(s.collectionDir(collection), id+".json"), nil
+}
+
+func (s *Store) collectionDir(collection string) string {
+	return filepath.Join(s.dir, collectionDirs[collection])
+}
@@ -0,0 +1,113 @@
+package jsonfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// TestReopen writes documents, closes the store and opens the directory again:
+// the rebuilt index must hold the same documents
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	date := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.CreateEventType(ctx, &storage.EventType{Name: "morning_lesson"}); err != nil {
+		t.Fatalf("CreateEventType: %v", err)
+	}
+	event := &storage.Event{Date: date, Type: "morning_lesson", Number: 1}
+	if err := s.SaveEvent(ctx, event); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+	part := &storage.LessonPart{EventID: event.ID, Language: "he", Order: 1, Date: date, Title: "First", PartType: "live_lesson"}
+	if err := s.SavePart(ctx, part); err != nil {
+		t.Fatalf("SavePart: %v", err)
+	}
+	part.Title = "Second"
+	if err := s.SavePart(ctx, part); err != nil {
+		t.Fatalf("SavePart: %v", err)
+	}
+	if err := s.SaveConfig(ctx, &storage.TemplateConfig{}); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	// A temp file left by an interrupted write is cleaned up on open
+	stale := filepath.Join(dir, "parts", part.ID+".json.1.tmp")
+	if err := os.WriteFile(stale, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+
+	gotEvent, err := s.GetEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if !gotEvent.Date.Equal(date) || gotEvent.Version != event.Version {
+		t.Errorf("event on %v at version %d, want %v at version %d", gotEvent.Date, gotEvent.Version, date, event.Version)
+	}
+	gotPart, err := s.GetPart(ctx, part.ID)
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if gotPart.Title != "Second" || gotPart.Version != 2 || gotPart.EventID != event.ID {
+		t.Errorf("part %q at version %d of event %s, want \"Second\" at version 2 of %s", gotPart.Title, gotPart.Version, gotPart.EventID, event.ID)
+	}
+	revisions, err := s.ListRevisions(ctx, storage.RevisionTypePart, part.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Errorf("%d revisions of the part, want 2", len(revisions))
+	}
+	if _, err := s.GetEventTypeByName(ctx, "morning_lesson"); err != nil {
+		t.Errorf("GetEventTypeByName: %v", err)
+	}
+	if config, err := s.GetConfig(ctx); err != nil || config == nil {
+		t.Errorf("GetConfig: %v, %v", config, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file kept: %v", err)
+	}
+
+	// The store keeps writing where it left off
+	gotPart.Title = "Third"
+	if err := s.SavePart(ctx, gotPart); err != nil {
+		t.Fatalf("SavePart after reopen: %v", err)
+	}
+}
+
+func TestOpenLocked(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if second, err := Open(dir); err == nil {
+		second.Close()
+		t.Fatal("opened a data directory that is already open")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open after Close: %v", err)
+	}
+	s.Close()
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// The store hands out copies so that callers mutating a returned document
+// don't change stored state until they save it, just like with MongoDB.
+
+func clonePart(part *storage.LessonPart) *storage.LessonPart {
+	c := *part
+	if part.Sources != nil {
+		c.Sources = append([]storage.Source(nil), part.Sources...)
+	}
+	if part.CustomLinks != nil {
+		c.CustomLinks = append([]storage.CustomLink(nil), part.CustomLinks...)
+	}
+	c.DeletedAt = cloneTime(part.DeletedAt)
+	return &c
+}
+
+func cloneEvent(event *storage.Event) *storage.Event {
+	c := *event
+	c.Titles = cloneStringMap(event.Titles)
+	c.EmailSentAt = cloneTime(event.EmailSentAt)
+	c.DeletedAt = cloneTime(event.DeletedAt)
+	return &c
+}
+
+func cloneRevision(rev *storage.Revision) *storage.Revision {
+	c := *rev
+	if rev.Part != nil {
+		c.Part = clonePart(rev.Part)
+	}
+	if rev.Event != nil {
+		c.Event = cloneEvent(rev.Event)
+	}
+	return &c
+}
+
+func cloneEventType(et *storage.EventType) *storage.EventType {
+	c := *et
+	c.Titles = cloneStringMap(et.Titles)
+	return &c
+}
+
+func cloneTemplateConfig(config *storage.TemplateConfig) *storage.TemplateConfig {
+	c := &storage.TemplateConfig{
+		Preparation: cloneStringMap(config.Preparation),
+		Version:     config.Version,
+	}
+	if config.Languages != nil {
+		c.Languages = append([]string(nil), config.Languages...)
+	}
+	if config.Templates != nil {
+		c.Templates = make([]storage.TemplateDefinition, len(config.Templates))
+		for i, tmpl := range config.Templates {
+			c.Templates[i] = storage.TemplateDefinition{
+				ID:           tmpl.ID,
+				Translations: cloneStringMap(tmpl.Translations),
+				Visible:      tmpl.Visible,
+			}
+		}
+	}
+	return c
+}
+
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
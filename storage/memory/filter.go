@@ -0,0 +1,300 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// matchEvent reports whether an event matches a MongoDB-style filter.
+// It supports equality, $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin and $exists
+// on top-level fields, plus $and / $or.
+func matchEvent(event *storage.Event, filter bson.M) (bool, error) {
+	return matchDocument(func(key string) (interface{}, bool, error) {
+		return eventField(event, key)
+	}, filter)
+}
+
+// fieldLookup returns a document field's value and whether it is present
+type fieldLookup func(key string) (interface{}, bool, error)
+
+func matchDocument(lookup fieldLookup, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		switch key {
+		case "$and", "$or":
+			clauses, err := toFilterList(cond)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", key, err)
+			}
+			matchedAny := false
+			for _, clause := range clauses {
+				ok, err := matchDocument(lookup, clause)
+				if err != nil {
+					return false, err
+				}
+				if key == "$and" && !ok {
+					return false, nil
+				}
+				if ok {
+					matchedAny = true
+				}
+			}
+			if key == "$or" && !matchedAny {
+				return false, nil
+			}
+			continue
+		}
+
+		value, present, err := lookup(key)
+		if err != nil {
+			return false, err
+		}
+		ok, err := matchCondition(value, present, cond)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", key, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchCondition(value interface{}, present bool, cond interface{}) (bool, error) {
+	ops, isOps := toOperatorMap(cond)
+	if !isOps {
+		return present && equal(value, cond), nil
+	}
+
+	for op, operand := range ops {
+		var ok bool
+		switch op {
+		case "$eq":
+			ok = present && equal(value, operand)
+		case "$ne":
+			ok = !present || !equal(value, operand)
+		case "$gt", "$gte", "$lt", "$lte":
+			if !present {
+				return false, nil
+			}
+			c, comparable := compare(value, operand)
+			if !comparable {
+				return false, nil
+			}
+			switch op {
+			case "$gt":
+				ok = c > 0
+			case "$gte":
+				ok = c >= 0
+			case "$lt":
+				ok = c < 0
+			case "$lte":
+				ok = c <= 0
+			}
+		case "$in", "$nin":
+			list := reflect.ValueOf(operand)
+			if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+				return false, fmt.Errorf("%s needs an array", op)
+			}
+			found := false
+			for i := 0; i < list.Len(); i++ {
+				candidate := list.Index(i).Interface()
+				if (candidate == nil && !present) || (present && equal(value, candidate)) {
+					found = true
+					break
+				}
+			}
+			ok = found == (op == "$in")
+		case "$exists":
+			want, isBool := operand.(bool)
+			if !isBool {
+				return false, fmt.Errorf("$exists needs a boolean")
+			}
+			ok = present == want
+		default:
+			return false, fmt.Errorf("unsupported operator %s", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// toOperatorMap returns cond as an operator document if all its keys start with "$"
+func toOperatorMap(cond interface{}) (map[string]interface{}, bool) {
+	var m map[string]interface{}
+	switch c := cond.(type) {
+	case bson.M:
+		m = c
+	case map[string]interface{}:
+		m = c
+	case bson.D:
+		m = c.Map()
+	default:
+		return nil, false
+	}
+	if len(m) == 0 {
+		return nil, false
+	}
+	for k := range m {
+		if len(k) == 0 || k[0] != '$' {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+func toFilterList(cond interface{}) ([]bson.M, error) {
+	switch c := cond.(type) {
+	case []bson.M:
+		return c, nil
+	case bson.A:
+		return filtersFromSlice(c)
+	case []interface{}:
+		return filtersFromSlice(c)
+	}
+	return nil, fmt.Errorf("expected an array of filters")
+}
+
+func filtersFromSlice(items []interface{}) ([]bson.M, error) {
+	filters := make([]bson.M, 0, len(items))
+	for _, item := range items {
+		switch f := item.(type) {
+		case bson.M:
+			filters = append(filters, f)
+		case map[string]interface{}:
+			filters = append(filters, bson.M(f))
+		case bson.D:
+			filters = append(filters, f.Map())
+		default:
+			return nil, fmt.Errorf("expected a filter document, got %T", item)
+		}
+	}
+	return filters, nil
+}
+
+func equal(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two scalar values of compatible kinds
+func compare(a, b interface{}) (int, bool) {
+	if at, ok := toTime(a); ok {
+		bt, ok := toTime(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case at.Before(bt):
+			return -1, true
+		case at.After(bt):
+			return 1, true
+		}
+		return 0, true
+	}
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	if as, ok := a.(string); ok {
+		bs, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case as < bs:
+			return -1, true
+		case as > bs:
+			return 1, true
+		}
+		return 0, true
+	}
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		if !ok || ab != bb {
+			return 0, false
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	}
+	return time.Time{}, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// eventField maps a bson field name of Event to its value
+func eventField(e *storage.Event, key string) (interface{}, bool, error) {
+	switch key {
+	case "_id":
+		return e.ID, true, nil
+	case "date":
+		return e.Date, true, nil
+	case "start_time":
+		return e.StartTime, e.StartTime != "", nil
+	case "end_time":
+		return e.EndTime, e.EndTime != "", nil
+	case "type":
+		return e.Type, true, nil
+	case "number":
+		return e.Number, true, nil
+	case "order":
+		return e.Order, true, nil
+	case "public":
+		return e.Public, true, nil
+	case "email_sent_at":
+		if e.EmailSentAt == nil {
+			return nil, false, nil
+		}
+		return *e.EmailSentAt, true, nil
+	case "created_at":
+		return e.CreatedAt, true, nil
+	case "deleted_at":
+		if e.DeletedAt == nil {
+			return nil, false, nil
+		}
+		return *e.DeletedAt, true, nil
+	case "deleted_by":
+		return e.DeletedBy, e.DeletedBy != "", nil
+	}
+	return nil, false, fmt.Errorf("unsupported event field %q", key)
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// ImportParts writes parts and their revisions as they are, replacing stored parts
+// with the same IDs
+func (s *Store) ImportParts(ctx context.Context, parts []*storage.LessonPart, revisions []*storage.Revision) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.importParts(parts, revisions)
+}
+
+func (s *Store) importParts(parts []*storage.LessonPart, revisions []*storage.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, part := range parts {
+		stored := clonePart(part)
+		if err := s.put(CollectionParts, stored.ID, stored); err != nil {
+			return fmt.Errorf("failed to import part %s: %w", stored.ID, err)
+		}
+		s.parts[stored.ID] = stored
+	}
+	return s.importRevisions(revisions)
+}
+
+// ImportEvents writes events and their revisions as they are, replacing stored
+// events with the same IDs
+func (s *Store) ImportEvents(ctx context.Context, events []*storage.Event, revisions []*storage.Revision) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.importEvents(events, revisions)
+}
+
+func (s *Store) importEvents(events []*storage.Event, revisions []*storage.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		stored := cloneEvent(event)
+		if err := s.put(CollectionEvents, stored.ID, stored); err != nil {
+			return fmt.Errorf("failed to import event %s: %w", stored.ID, err)
+		}
+		s.events[stored.ID] = stored
+	}
+	return s.importRevisions(revisions)
+}
+
+// importRevisions adds revisions to their documents' histories, replacing stored
+// ones with the same ID. Histories are rebuilt rather than changed in place, so a
+// transaction can still roll them back. The caller must hold s.mu for writing.
+func (s *Store) importRevisions(revisions []*storage.Revision) error {
+	changed := make(map[string][]*storage.Revision)
+	for _, rev := range revisions {
+		stored := cloneRevision(rev)
+		if err := s.put(CollectionRevisions, stored.ID, stored); err != nil {
+			return fmt.Errorf("failed to import revision %s: %w", stored.ID, err)
+		}
+		key := revisionKey(stored.DocumentType, stored.DocumentID)
+		history, ok := changed[key]
+		if !ok {
+			history = append([]*storage.Revision(nil), s.revisions[key]...)
+		}
+		kept := history[:0:0]
+		for _, r := range history {
+			if r.ID != stored.ID {
+				kept = append(kept, r)
+			}
+		}
+		changed[key] = append(kept, stored)
+	}
+	for key, history := range changed {
+		sort.Slice(history, func(i, j int) bool { return history[i].Rev < history[j].Rev })
+		s.revisions[key] = history
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// ListPublicEvents returns public events with their parts and event types,
+// newest first
+func (s *Store) ListPublicEvents(ctx context.Context, query storage.PublicEventsQuery) ([]*storage.EventWithParts, error) {
+	filter := query.Filter()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*storage.Event
+	for _, event := range s.events {
+		ok, err := matchEvent(event, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find events: %w", err)
+		}
+		if ok {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.After(events[j].Date)
+		}
+		if events[i].Order != events[j].Order {
+			return events[i].Order < events[j].Order
+		}
+		return events[i].ID < events[j].ID
+	})
+	events = paginate(events, query.Limit, 0)
+
+	eventTypes := make(map[string]*storage.EventType)
+	for _, et := range s.eventTypes {
+		eventTypes[et.Name] = et
+	}
+
+	result := make([]*storage.EventWithParts, len(events))
+	for i, event := range events {
+		partQuery := query.PartFilter(event.ID)
+		var parts []*storage.LessonPart
+		for _, part := range s.parts {
+			if partQuery.Matches(part) {
+				parts = append(parts, clonePart(part))
+			}
+		}
+		sort.Slice(parts, func(i, j int) bool {
+			return storage.ComparePartsBy(parts[i], parts[j], storage.DefaultPartSort) < 0
+		})
+
+		result[i] = &storage.EventWithParts{Event: cloneEvent(event), Parts: parts}
+		if et, ok := eventTypes[event.Type]; ok {
+			result[i].EventType = cloneEventType(et)
+		}
+	}
+	return result, nil
+}
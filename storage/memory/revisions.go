@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+var _ storage.RevisionStore = (*Store)(nil)
+
+// revisionKey identifies a document's revision history
+func revisionKey(documentType, documentID string) string {
+	return documentType + "/" + documentID
+}
+
+// ListRevisions returns a document's revisions, oldest first
+func (s *Store) ListRevisions(ctx context.Context, documentType, documentID string) ([]*storage.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := s.revisions[revisionKey(documentType, documentID)]
+	revisions := make([]*storage.Revision, len(history))
+	for i, rev := range history {
+		revisions[i] = cloneRevision(rev)
+	}
+	return revisions, nil
+}
+
+// GetRevision retrieves one revision of a document
+func (s *Store) GetRevision(ctx context.Context, documentType, documentID string, rev int) (*storage.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.revisions[revisionKey(documentType, documentID)] {
+		if r.Rev == rev {
+			return cloneRevision(r), nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d of %s %s %w", rev, documentType, documentID, storage.ErrNotFound)
+}
+
+// ListAllRevisions returns every stored revision, grouped by document and oldest first
+func (s *Store) ListAllRevisions(ctx context.Context) ([]*storage.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.revisions))
+	for key := range s.revisions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	revisions := []*storage.Revision{}
+	for _, key := range keys {
+		for _, rev := range s.revisions[key] {
+			revisions = append(revisions, cloneRevision(rev))
+		}
+	}
+	return revisions, nil
+}
+
+// appendRevision numbers a revision after the document's latest one and persists it.
+// The caller must hold s.mu for writing and persist the document itself afterwards,
+// removing the revision again if that fails.
+func (s *Store) appendRevision(revision *storage.Revision) (*storage.Revision, error) {
+	latest := 0
+	if history := s.revisions[revisionKey(revision.DocumentType, revision.DocumentID)]; len(history) > 0 {
+		latest = history[len(history)-1].Rev
+	}
+	stored := cloneRevision(revision)
+	stored.Number(latest + 1)
+
+	if err := s.put(CollectionRevisions, stored.ID, stored); err != nil {
+		return nil, fmt.Errorf("failed to save revision: %w", err)
+	}
+	return stored, nil
+}
+
+// commitRevision adds a persisted revision to the document's history
+func (s *Store) commitRevision(revision *storage.Revision) {
+	key := revisionKey(revision.DocumentType, revision.DocumentID)
+	s.revisions[key] = append(s.revisions[key], revision)
+}
+
+// deleteRevisions removes a document's revision history. The caller must hold s.mu for writing.
+func (s *Store) deleteRevisions(documentType, documentID string) error {
+	key := revisionKey(documentType, documentID)
+	for _, rev := range s.revisions[key] {
+		if err := s.remove(CollectionRevisions, rev.ID); err != nil {
+			return fmt.Errorf("failed to delete revisions: %w", err)
+		}
+	}
+	delete(s.revisions, key)
+	return nil
+}
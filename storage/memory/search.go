@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// SearchParts ranks untrashed parts by their matches in title, description and source titles
+func (s *Store) SearchParts(ctx context.Context, query storage.SearchQuery) ([]storage.PartHit, error) {
+	terms := storage.SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	filter := query.PartFilter()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hits []storage.PartHit
+	for _, part := range s.parts {
+		if !filter.Matches(part) {
+			continue
+		}
+		if score := storage.ScorePart(part, terms); score > 0 {
+			hits = append(hits, storage.PartHit{Part: clonePart(part), Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if !hits[i].Part.Date.Equal(hits[j].Part.Date) {
+			return hits[i].Part.Date.After(hits[j].Part.Date)
+		}
+		return hits[i].Part.ID < hits[j].Part.ID
+	})
+	return paginate(hits, query.Limit, 0), nil
+}
+
+// SearchEvents ranks untrashed events by their matches in their titles
+func (s *Store) SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.EventHit, error) {
+	terms := storage.SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	filter := query.EventFilter()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hits []storage.EventHit
+	for _, event := range s.events {
+		ok, err := matchEvent(event, filter)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if score := storage.ScoreEvent(event, terms); score > 0 {
+			hits = append(hits, storage.EventHit{Event: cloneEvent(event), Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if !hits[i].Event.Date.Equal(hits[j].Event.Date) {
+			return hits[i].Event.Date.After(hits[j].Event.Date)
+		}
+		return hits[i].Event.ID < hits[j].Event.ID
+	})
+	return paginate(hits, query.Limit, 0), nil
+}
@@ -0,0 +1,620 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Store keeps lesson parts, events, their revisions, event types and the template configuration
+// in memory. It implements PartStore, EventStore, RevisionStore, EventTypeStore and TemplateStore with the same
+// semantics as the MongoDB stores, so it can be used for local development and tests.
+type Store struct {
+	writeMu    sync.Mutex   // Serializes writers and transactions
+	mu         sync.RWMutex // Guards the maps below
+	parts      map[string]*storage.LessonPart
+	events     map[string]*storage.Event
+	eventTypes map[string]*storage.EventType
+	revisions  map[string][]*storage.Revision // Keyed by revisionKey, oldest first
+	templates  *storage.TemplateConfig
+	persister  Persister
+}
+
+// Collection names passed to a Persister, matching the MongoDB collections
+const (
+	CollectionParts      = "lesson_parts"
+	CollectionEvents     = "events"
+	CollectionEventTypes = "event_types"
+	CollectionRevisions  = "revisions"
+	CollectionTemplates  = "templates"
+
+	// templateConfigID is the document ID of the single template configuration
+	templateConfigID = "config"
+)
+
+// Persister receives every write before it is applied to the in-memory maps,
+// so a durable backend can use the Store as its index. A failed write leaves
+// the Store unchanged.
+type Persister interface {
+	Put(collection, id string, doc interface{}) error
+	Remove(collection, id string) error
+}
+
+var (
+	_ storage.PartStore      = (*Store)(nil)
+	_ storage.EventStore     = (*Store)(nil)
+	_ storage.EventTypeStore = (*Store)(nil)
+	_ storage.TemplateStore  = (*Store)(nil)
+)
+
+// NewStore creates an empty in-memory store
+func NewStore() *Store {
+	return &Store{
+		parts:      make(map[string]*storage.LessonPart),
+		events:     make(map[string]*storage.Event),
+		eventTypes: make(map[string]*storage.EventType),
+		revisions:  make(map[string][]*storage.Revision),
+	}
+}
+
+// NewPersistentStore creates an empty store that forwards every write to p
+func NewPersistentStore(p Persister) *Store {
+	s := NewStore()
+	s.persister = p
+	return s
+}
+
+// Seed loads existing documents into the store without persisting them.
+// It is used to rebuild the index when a durable backend starts up.
+func (s *Store) Seed(parts []*storage.LessonPart, events []*storage.Event, eventTypes []*storage.EventType, revisions []*storage.Revision, config *storage.TemplateConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, part := range parts {
+		s.parts[part.ID] = clonePart(part)
+	}
+	for _, event := range events {
+		s.events[event.ID] = cloneEvent(event)
+	}
+	for _, et := range eventTypes {
+		s.eventTypes[et.ID] = cloneEventType(et)
+	}
+
+	sorted := append([]*storage.Revision(nil), revisions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Rev < sorted[j].Rev })
+	for _, rev := range sorted {
+		key := revisionKey(rev.DocumentType, rev.DocumentID)
+		s.revisions[key] = append(s.revisions[key], cloneRevision(rev))
+	}
+	if config != nil {
+		s.templates = cloneTemplateConfig(config)
+	}
+}
+
+// put forwards a write to the persister, if any
+func (s *Store) put(collection, id string, doc interface{}) error {
+	if s.persister == nil {
+		return nil
+	}
+	return s.persister.Put(collection, id, doc)
+}
+
+// allocateID generates an ID for collection that taken reports as unused
+func allocateID(collection string, taken func(id string) bool) (string, error) {
+	for attempt := 0; attempt < storage.MaxIDAttempts; attempt++ {
+		if id := storage.NewID(collection); !taken(id) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no free id after %d attempts", storage.MaxIDAttempts)
+}
+
+// remove forwards a delete to the persister, if any
+func (s *Store) remove(collection, id string) error {
+	if s.persister == nil {
+		return nil
+	}
+	return s.persister.Remove(collection, id)
+}
+
+// SavePart inserts or replaces a lesson part
+// The stored part must still be at part.Version, otherwise ErrVersionConflict is returned.
+// Within a unit of work of RunInTransaction part is only updated once it commits.
+func (s *Store) SavePart(ctx context.Context, part *storage.LessonPart) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.savePart(ctx, part)
+}
+
+func (s *Store) savePart(ctx context.Context, part *storage.LessonPart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := clonePart(part)
+	inserted := stored.ID == ""
+	if inserted {
+		id, err := allocateID(storage.IDCollectionParts, func(id string) bool { return s.parts[id] != nil })
+		if err != nil {
+			return fmt.Errorf("failed to save part: %w", err)
+		}
+		stored.ID = id
+		stored.Version = 0
+	}
+
+	// Set created time if not set
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+
+	if existing, ok := s.parts[stored.ID]; ok && existing.Version != stored.Version {
+		return fmt.Errorf("failed to save part %s: %w", stored.ID, storage.ErrVersionConflict)
+	}
+
+	stored.Version++
+	stored.UpdatedAt = time.Now()
+	revision, err := s.appendRevision(storage.NewPartRevision(stored))
+	if err != nil {
+		return err
+	}
+	if err := s.put(CollectionParts, stored.ID, stored); err != nil {
+		s.remove(CollectionRevisions, revision.ID)
+		return fmt.Errorf("failed to save part: %w", err)
+	}
+	s.parts[stored.ID] = stored
+	s.commitRevision(revision)
+	if inserted {
+		part.ID = stored.ID
+		storage.AfterAbort(ctx, func() { part.ID = "" })
+	}
+	storage.AfterCommit(ctx, func() {
+		part.Version = stored.Version
+		part.CreatedAt = stored.CreatedAt
+		part.UpdatedAt = stored.UpdatedAt
+	})
+	return nil
+}
+
+// GetPart retrieves a lesson part by ID
+func (s *Store) GetPart(ctx context.Context, id string) (*storage.LessonPart, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	part, ok := s.parts[id]
+	if !ok || part.DeletedAt != nil {
+		return nil, fmt.Errorf("part %s %w", id, storage.ErrNotFound)
+	}
+	return clonePart(part), nil
+}
+
+// ListParts returns all lesson parts in creation order, including trashed ones
+func (s *Store) ListParts(ctx context.Context) ([]*storage.LessonPart, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	parts := make([]*storage.LessonPart, 0, len(s.parts))
+	for _, part := range s.parts {
+		parts = append(parts, clonePart(part))
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		if !parts[i].CreatedAt.Equal(parts[j].CreatedAt) {
+			return parts[i].CreatedAt.Before(parts[j].CreatedAt)
+		}
+		return parts[i].ID < parts[j].ID
+	})
+	return parts, nil
+}
+
+// ListPartsFiltered returns the parts matching a query along with the total number of matches
+func (s *Store) ListPartsFiltered(ctx context.Context, query storage.PartQuery) ([]*storage.LessonPart, int, error) {
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*storage.LessonPart
+	for _, part := range s.parts {
+		if !query.Matches(part) {
+			continue
+		}
+		if query.PublicEventsOnly {
+			event, ok := s.events[part.EventID]
+			if !ok || !event.Public || event.DeletedAt != nil {
+				continue
+			}
+		}
+		matched = append(matched, clonePart(part))
+	}
+
+	order := query.SortOrDefault()
+	sort.Slice(matched, func(i, j int) bool {
+		return storage.ComparePartsBy(matched[i], matched[j], order) < 0
+	})
+
+	return paginate(matched, query.Limit, query.Offset), len(matched), nil
+}
+
+// DeletePart deletes a lesson part by ID
+func (s *Store) DeletePart(ctx context.Context, id string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.deletePart(id)
+}
+
+func (s *Store) deletePart(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.parts[id]; !ok {
+		return fmt.Errorf("part %s %w", id, storage.ErrNotFound)
+	}
+	if err := s.remove(CollectionParts, id); err != nil {
+		return fmt.Errorf("failed to delete part: %w", err)
+	}
+	delete(s.parts, id)
+	return s.deleteRevisions(storage.RevisionTypePart, id)
+}
+
+// SaveEvent inserts or replaces an event
+// The stored event must still be at event.Version, otherwise ErrVersionConflict is returned.
+func (s *Store) SaveEvent(ctx context.Context, event *storage.Event) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.saveEvent(ctx, event)
+}
+
+func (s *Store) saveEvent(ctx context.Context, event *storage.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := cloneEvent(event)
+	inserted := stored.ID == ""
+	if inserted {
+		id, err := allocateID(storage.IDCollectionEvents, func(id string) bool { return s.events[id] != nil })
+		if err != nil {
+			return fmt.Errorf("failed to save event: %w", err)
+		}
+		stored.ID = id
+		stored.Version = 0
+	}
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+
+	if existing, ok := s.events[stored.ID]; ok && existing.Version != stored.Version {
+		return fmt.Errorf("failed to save event %s: %w", stored.ID, storage.ErrVersionConflict)
+	}
+
+	stored.Version++
+	stored.UpdatedAt = time.Now()
+	revision, err := s.appendRevision(storage.NewEventRevision(stored))
+	if err != nil {
+		return err
+	}
+	if err := s.put(CollectionEvents, stored.ID, stored); err != nil {
+		s.remove(CollectionRevisions, revision.ID)
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+	s.events[stored.ID] = stored
+	s.commitRevision(revision)
+	if inserted {
+		event.ID = stored.ID
+		storage.AfterAbort(ctx, func() { event.ID = "" })
+	}
+	storage.AfterCommit(ctx, func() {
+		event.Version = stored.Version
+		event.CreatedAt = stored.CreatedAt
+		event.UpdatedAt = stored.UpdatedAt
+	})
+	return nil
+}
+
+// GetEvent retrieves an event by ID
+func (s *Store) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok || event.DeletedAt != nil {
+		return nil, fmt.Errorf("event %s %w", id, storage.ErrNotFound)
+	}
+	return cloneEvent(event), nil
+}
+
+// ListEvents lists all events in creation order, including trashed ones
+func (s *Store) ListEvents(ctx context.Context) ([]*storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]*storage.Event, 0, len(s.events))
+	for _, event := range s.events {
+		events = append(events, cloneEvent(event))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.Before(events[j].CreatedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// ListEventsFiltered lists events matching a MongoDB-style filter, sorted by order
+// ascending and date descending. Only the operators used by the API are supported.
+// Trashed events are excluded unless the filter constrains deleted_at.
+func (s *Store) ListEventsFiltered(ctx context.Context, filter bson.M, limit, offset int) ([]storage.Event, int, error) {
+	filter = storage.WithoutTrashed(filter)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []storage.Event
+	for _, event := range s.events {
+		ok, err := matchEvent(event, filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to find events: %w", err)
+		}
+		if ok {
+			matched = append(matched, *cloneEvent(event))
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Order != matched[j].Order {
+			return matched[i].Order < matched[j].Order
+		}
+		if !matched[i].Date.Equal(matched[j].Date) {
+			return matched[i].Date.After(matched[j].Date)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	return paginate(matched, limit, offset), len(matched), nil
+}
+
+// DeleteEvent deletes an event by ID
+func (s *Store) DeleteEvent(ctx context.Context, id string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.deleteEvent(id)
+}
+
+func (s *Store) deleteEvent(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[id]; !ok {
+		return fmt.Errorf("event %s %w", id, storage.ErrNotFound)
+	}
+	if err := s.remove(CollectionEvents, id); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	delete(s.events, id)
+	return s.deleteRevisions(storage.RevisionTypeEvent, id)
+}
+
+// CreateEventType inserts a new event type
+func (s *Store) CreateEventType(ctx context.Context, et *storage.EventType) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.eventTypes {
+		if existing.Name == et.Name {
+			return fmt.Errorf("event type with name %q %w", et.Name, storage.ErrAlreadyExists)
+		}
+	}
+
+	if et.ID == "" {
+		id, err := allocateID(storage.IDCollectionEventTypes, func(id string) bool { return s.eventTypes[id] != nil })
+		if err != nil {
+			return fmt.Errorf("failed to create event type: %w", err)
+		}
+		et.ID = id
+	}
+	if _, ok := s.eventTypes[et.ID]; ok {
+		return fmt.Errorf("failed to create event type: duplicate id %s", et.ID)
+	}
+	now := time.Now()
+	et.CreatedAt = now
+	et.UpdatedAt = now
+	et.Version = 1
+
+	stored := cloneEventType(et)
+	if err := s.put(CollectionEventTypes, stored.ID, stored); err != nil {
+		return fmt.Errorf("failed to create event type: %w", err)
+	}
+	s.eventTypes[et.ID] = stored
+	return nil
+}
+
+// GetEventType retrieves an event type by ID
+func (s *Store) GetEventType(ctx context.Context, id string) (*storage.EventType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	et, ok := s.eventTypes[id]
+	if !ok {
+		return nil, fmt.Errorf("event type %s %w", id, storage.ErrNotFound)
+	}
+	return cloneEventType(et), nil
+}
+
+// GetEventTypeByName retrieves an event type by name slug
+func (s *Store) GetEventTypeByName(ctx context.Context, name string) (*storage.EventType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, et := range s.eventTypes {
+		if et.Name == name {
+			return cloneEventType(et), nil
+		}
+	}
+	return nil, fmt.Errorf("event type %s %w", name, storage.ErrNotFound)
+}
+
+// ListEventTypes returns all event types sorted by order
+func (s *Store) ListEventTypes(ctx context.Context) ([]*storage.EventType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	types := make([]*storage.EventType, 0, len(s.eventTypes))
+	for _, et := range s.eventTypes {
+		types = append(types, cloneEventType(et))
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].Order != types[j].Order {
+			return types[i].Order < types[j].Order
+		}
+		return types[i].Name < types[j].Name
+	})
+	return types, nil
+}
+
+// UpdateEventType saves changes to an existing event type
+// The stored event type must still be at et.Version, otherwise ErrVersionConflict is returned.
+func (s *Store) UpdateEventType(ctx context.Context, et *storage.EventType) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.eventTypes[et.ID]
+	if !ok {
+		return fmt.Errorf("event type %s %w", et.ID, storage.ErrNotFound)
+	}
+	if current.Version != et.Version {
+		return fmt.Errorf("failed to update event type %s: %w", et.ID, storage.ErrVersionConflict)
+	}
+	for _, existing := range s.eventTypes {
+		if existing.ID != et.ID && existing.Name == et.Name {
+			return fmt.Errorf("failed to update event type: name %q %w", et.Name, storage.ErrAlreadyExists)
+		}
+	}
+
+	stored := cloneEventType(et)
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	if err := s.put(CollectionEventTypes, stored.ID, stored); err != nil {
+		return fmt.Errorf("failed to update event type: %w", err)
+	}
+	s.eventTypes[et.ID] = stored
+	et.UpdatedAt = stored.UpdatedAt
+	et.Version = stored.Version
+	return nil
+}
+
+// DeleteEventType deletes an event type by ID
+func (s *Store) DeleteEventType(ctx context.Context, id string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.eventTypes[id]; !ok {
+		return fmt.Errorf("event type %s %w", id, storage.ErrNotFound)
+	}
+	if err := s.remove(CollectionEventTypes, id); err != nil {
+		return fmt.Errorf("failed to delete event type: %w", err)
+	}
+	delete(s.eventTypes, id)
+	return nil
+}
+
+// CountEventTypes returns the number of stored event types
+func (s *Store) CountEventTypes(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.eventTypes)), nil
+}
+
+// SaveConfig saves the entire template configuration
+// The stored configuration must still be at config.Version, otherwise ErrVersionConflict is returned.
+func (s *Store) SaveConfig(ctx context.Context, config *storage.TemplateConfig) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.templates != nil && s.templates.Version != config.Version {
+		return fmt.Errorf("failed to save template config: %w", storage.ErrVersionConflict)
+	}
+
+	stored := cloneTemplateConfig(config)
+	stored.Version++
+	if err := s.put(CollectionTemplates, templateConfigID, stored); err != nil {
+		return fmt.Errorf("failed to save template config: %w", err)
+	}
+	s.templates = stored
+	config.Version = stored.Version
+	return nil
+}
+
+// GetConfig retrieves the entire template configuration
+func (s *Store) GetConfig(ctx context.Context) (*storage.TemplateConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.templates == nil {
+		return nil, fmt.Errorf("template config %w", storage.ErrNotFound)
+	}
+	return cloneTemplateConfig(s.templates), nil
+}
+
+// InitializeFromJSON stores the given configuration unless one already exists
+func (s *Store) InitializeFromJSON(ctx context.Context, config *storage.TemplateConfig) error {
+	s.mu.RLock()
+	initialized := s.templates != nil
+	s.mu.RUnlock()
+
+	// If config already exists, don't overwrite it
+	if initialized {
+		return nil
+	}
+	return s.SaveConfig(ctx, config)
+}
+
+// paginate returns the page of items selected by limit and offset
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(items) {
+			return nil
+		}
+		items = items[offset:]
+	}
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+// seededStore returns a store with events and parts covering the fields the
+// API filters and sorts on
+func seededStore() *Store {
+	sent := day(2)
+	trashed := day(5)
+	events := []*storage.Event{
+		{ID: "e1", Date: day(1), Type: "morning_lesson", Number: 1, Order: 0, Public: true},
+		{ID: "e2", Date: day(2), Type: "morning_lesson", Number: 2, Order: 1, Public: false, EmailSentAt: &sent},
+		{ID: "e3", Date: day(3), Type: "congress", Number: 1, Order: 0, Public: true},
+		{ID: "e4", Date: day(4), Type: "congress", Number: 1, Order: 0, Public: true, DeletedAt: &trashed},
+	}
+	parts := []*storage.LessonPart{
+		{ID: "p1", EventID: "e1", Language: "he", Order: 1, Date: day(1), Title: "Gamma"},
+		{ID: "p2", EventID: "e1", Language: "en", Order: 1, Date: day(1), Title: "Alpha"},
+		{ID: "p3", EventID: "e1", Language: "he", Order: 0, Date: day(1), Title: "Beta"},
+		{ID: "p4", EventID: "e2", Language: "he", Order: 0, Date: day(2), Title: "Delta"},
+		{ID: "p5", EventID: "e3", Language: "ru", Order: 0, Date: day(3), Title: "Epsilon", TranslationStub: true},
+		{ID: "p6", EventID: "e4", Language: "he", Order: 0, Date: day(4), Title: "Zeta"},
+	}
+	s := NewStore()
+	s.Seed(parts, events, nil, nil, nil)
+	return s
+}
+
+func eventIDs(events []storage.Event) []string {
+	ids := []string{}
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func partIDs(parts []*storage.LessonPart) []string {
+	ids := []string{}
+	for _, p := range parts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// TestListEventsFiltered checks the subset of MongoDB filters the API uses,
+// with results in MongoDB's order: order ascending, then date descending
+func TestListEventsFiltered(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.M
+		want   []string
+	}{
+		{"no filter hides trashed", bson.M{}, []string{"e3", "e1", "e2"}},
+		{"equality", bson.M{"type": "congress"}, []string{"e3"}},
+		{"$eq", bson.M{"public": bson.M{"$eq": false}}, []string{"e2"}},
+		{"$ne", bson.M{"type": bson.M{"$ne": "congress"}}, []string{"e1", "e2"}},
+		{"$gte and $lt", bson.M{"date": bson.M{"$gte": day(2), "$lt": day(4)}}, []string{"e3", "e2"}},
+		{"$gt and $lte", bson.M{"date": bson.M{"$gt": day(1), "$lte": day(2)}}, []string{"e2"}},
+		{"$in", bson.M{"_id": bson.M{"$in": []string{"e1", "e4"}}}, []string{"e1"}},
+		{"$nin", bson.M{"_id": bson.M{"$nin": bson.A{"e1"}}}, []string{"e3", "e2"}},
+		{"$exists", bson.M{"email_sent_at": bson.M{"$exists": true}}, []string{"e2"}},
+		{"deleted_at lists trashed", bson.M{"deleted_at": bson.M{"$exists": true}}, []string{"e4"}},
+		{"$or", bson.M{"$or": bson.A{bson.M{"number": 2}, bson.M{"type": "congress"}}}, []string{"e3", "e2"}},
+		{"$and", bson.M{"$and": []bson.M{{"public": true}, {"type": "morning_lesson"}}}, []string{"e1"}},
+	}
+	s := seededStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, total, err := s.ListEventsFiltered(context.Background(), tt.filter, 0, 0)
+			if err != nil {
+				t.Fatalf("ListEventsFiltered: %v", err)
+			}
+			if got := eventIDs(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if total != len(tt.want) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestListEventsFilteredUnsupported(t *testing.T) {
+	s := seededStore()
+	if _, _, err := s.ListEventsFiltered(context.Background(), bson.M{"titles.en": "x"}, 0, 0); err == nil {
+		t.Error("expected an error for an unsupported field")
+	}
+	if _, _, err := s.ListEventsFiltered(context.Background(), bson.M{"date": bson.M{"$regex": "x"}}, 0, 0); err == nil {
+		t.Error("expected an error for an unsupported operator")
+	}
+}
+
+func TestListPartsFiltered(t *testing.T) {
+	stub := true
+	tests := []struct {
+		name  string
+		query storage.PartQuery
+		want  []string
+		total int
+	}{
+		{"default sort by order then language", storage.PartQuery{EventID: "e1"}, []string{"p3", "p2", "p1"}, 3},
+		{"descending sort", storage.PartQuery{Sort: []storage.SortField{{Field: "date", Desc: true}, {Field: "title"}}}, []string{"p6", "p5", "p4", "p2", "p3", "p1"}, 6},
+		{"page", storage.PartQuery{Sort: []storage.SortField{{Field: "title"}}, Limit: 2, Offset: 1}, []string{"p3", "p4"}, 6},
+		{"languages", storage.PartQuery{Languages: []string{"en", "ru"}}, []string{"p5", "p2"}, 2},
+		{"date range", storage.PartQuery{FromDate: ptr(day(2)), ToDate: ptr(day(3))}, []string{"p4", "p5"}, 2},
+		{"translation stubs", storage.PartQuery{TranslationStub: &stub}, []string{"p5"}, 1},
+		{"public events only", storage.PartQuery{PublicEventsOnly: true, Sort: []storage.SortField{{Field: "title"}}}, []string{"p2", "p3", "p5", "p1"}, 4},
+		{"only trashed", storage.PartQuery{Trashed: storage.OnlyTrashed}, []string{}, 0},
+	}
+	s := seededStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, total, err := s.ListPartsFiltered(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("ListPartsFiltered: %v", err)
+			}
+			if got := partIDs(parts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if total != tt.total {
+				t.Errorf("total = %d, want %d", total, tt.total)
+			}
+		})
+	}
+}
+
+func TestListPartsFilteredInvalidSort(t *testing.T) {
+	s := seededStore()
+	query := storage.PartQuery{Sort: []storage.SortField{{Field: "sources"}}}
+	if _, _, err := s.ListPartsFiltered(context.Background(), query); err == nil {
+		t.Error("expected an error for an unsupported sort field")
+	}
+}
+
+// TestNotFound checks that lookups and writes of missing documents fail with
+// storage.ErrNotFound, like the MongoDB stores
+func TestNotFound(t *testing.T) {
+	ctx := context.Background()
+	s := seededStore()
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetPart", func() error { _, err := s.GetPart(ctx, "missing"); return err }},
+		{"GetEvent", func() error { _, err := s.GetEvent(ctx, "missing"); return err }},
+		{"GetEvent trashed", func() error { _, err := s.GetEvent(ctx, "e4"); return err }},
+		{"GetEventType", func() error { _, err := s.GetEventType(ctx, "missing"); return err }},
+		{"GetEventTypeByName", func() error { _, err := s.GetEventTypeByName(ctx, "missing"); return err }},
+		{"GetRevision", func() error { _, err := s.GetRevision(ctx, storage.RevisionTypePart, "p1", 1); return err }},
+		{"GetConfig", func() error { _, err := s.GetConfig(ctx); return err }},
+		{"DeletePart", func() error { return s.DeletePart(ctx, "missing") }},
+		{"DeleteEvent", func() error { return s.DeleteEvent(ctx, "missing") }},
+		{"DeleteEventType", func() error { return s.DeleteEventType(ctx, "missing") }},
+		{"RestorePart", func() error { return s.RestorePart(ctx, "p1") }},
+		{"RestoreEvent", func() error { return s.RestoreEvent(ctx, "e1") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("got %v, want storage.ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestCreateEventTypeDuplicateName(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	if err := s.CreateEventType(ctx, &storage.EventType{Name: "congress"}); err != nil {
+		t.Fatalf("CreateEventType: %v", err)
+	}
+	err := s.CreateEventType(ctx, &storage.EventType{Name: "congress"})
+	if !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Errorf("got %v, want storage.ErrAlreadyExists", err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// TestRetriedTransaction runs a unit of work again after its first attempt was
+// aborted, as the MongoDB driver does after a transient error. The second
+// attempt must see the documents as they were before the first.
+func TestRetriedTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := seededStore()
+	part, err := s.GetPart(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	version := part.Version
+	created := &storage.LessonPart{EventID: "e1", Language: "en", Order: 2, Date: day(1), Title: "New"}
+	work := func(ctx context.Context, tx storage.Tx) error {
+		part.Title = "Changed"
+		if err := tx.Parts.SavePart(ctx, part); err != nil {
+			return err
+		}
+		return tx.Parts.SavePart(ctx, created)
+	}
+
+	errTransient := errors.New("transient error")
+	err = s.RunInTransaction(ctx, func(ctx context.Context, tx storage.Tx) error {
+		if err := work(ctx, tx); err != nil {
+			return err
+		}
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("first attempt: got %v, want %v", err, errTransient)
+	}
+	if part.Version != version || created.ID != "" || created.Version != 0 {
+		t.Fatalf("aborted attempt changed the documents: part at version %d, created %q at version %d", part.Version, created.ID, created.Version)
+	}
+
+	if err := s.RunInTransaction(ctx, work); err != nil {
+		t.Fatalf("second attempt: %v", err)
+	}
+	if part.Version != version+1 {
+		t.Errorf("part at version %d, want %d", part.Version, version+1)
+	}
+	if created.ID == "" || created.Version != 1 {
+		t.Errorf("created part %q at version %d, want an ID at version 1", created.ID, created.Version)
+	}
+	stored, err := s.GetPart(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetPart: %v", err)
+	}
+	if stored.Version != version+1 || stored.Title != "Changed" {
+		t.Errorf("stored part %q at version %d", stored.Title, stored.Version)
+	}
+}
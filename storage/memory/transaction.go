@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var _ storage.Transactor = (*Store)(nil)
+
+// RunInTransaction runs fn while holding the store's writer lock, so no other write
+// interleaves with it, and rolls back every part and event change if fn fails.
+// Readers are not blocked and may observe changes before the transaction ends.
+// Writes made through tx fail once ctx is done, which aborts the transaction.
+// Like with MongoDB, the documents fn saved are only updated once fn succeeds.
+func (s *Store) RunInTransaction(ctx context.Context, fn func(ctx context.Context, tx storage.Tx) error) error {
+	ctx, cancel := storage.BulkContext(ctx)
+	defer cancel()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	snap := s.snapshot()
+	view := &txStore{s: s}
+	ctx, attempt := storage.NewAttempt(ctx)
+	if err := fn(ctx, storage.Tx{Parts: view, Events: view}); err != nil {
+		attempt.Abort()
+		if rbErr := s.rollback(snap); rbErr != nil {
+			return fmt.Errorf("transaction aborted: %v (rollback failed: %v)", err, rbErr)
+		}
+		return fmt.Errorf("transaction aborted: %w", err)
+	}
+	attempt.Commit()
+	return nil
+}
+
+// snapshot holds the part, event and revision maps as they were when a transaction began.
+// Stored documents are never mutated in place and revision histories are only ever
+// appended to, so copying the maps is enough.
+type snapshot struct {
+	parts     map[string]*storage.LessonPart
+	events    map[string]*storage.Event
+	revisions map[string][]*storage.Revision
+}
+
+func (s *Store) snapshot() snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := snapshot{
+		parts:     make(map[string]*storage.LessonPart, len(s.parts)),
+		events:    make(map[string]*storage.Event, len(s.events)),
+		revisions: make(map[string][]*storage.Revision, len(s.revisions)),
+	}
+	for id, part := range s.parts {
+		snap.parts[id] = part
+	}
+	for id, event := range s.events {
+		snap.events[id] = event
+	}
+	for key, history := range s.revisions {
+		snap.revisions[key] = history
+	}
+	return snap
+}
+
+// rollback restores the maps from snap and reverts the persisted documents that changed
+func (s *Store) rollback(snap snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	revert := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for id, part := range s.parts {
+		if old, ok := snap.parts[id]; !ok {
+			revert(s.remove(CollectionParts, id))
+		} else if old != part {
+			revert(s.put(CollectionParts, id, old))
+		}
+	}
+	for id, old := range snap.parts {
+		if _, ok := s.parts[id]; !ok {
+			revert(s.put(CollectionParts, id, old))
+		}
+	}
+	for id, event := range s.events {
+		if old, ok := snap.events[id]; !ok {
+			revert(s.remove(CollectionEvents, id))
+		} else if old != event {
+			revert(s.put(CollectionEvents, id, old))
+		}
+	}
+	for id, old := range snap.events {
+		if _, ok := s.events[id]; !ok {
+			revert(s.put(CollectionEvents, id, old))
+		}
+	}
+
+	for key, history := range s.revisions {
+		old := snap.revisions[key]
+		// Usually the transaction only appended to the history; otherwise it was
+		// deleted and recreated, and every revision is reverted
+		kept := 0
+		if len(history) >= len(old) && (len(old) == 0 || history[len(old)-1] == old[len(old)-1]) {
+			kept = len(old)
+		}
+		for _, rev := range history[kept:] {
+			revert(s.remove(CollectionRevisions, rev.ID))
+		}
+		for _, rev := range old[kept:] {
+			revert(s.put(CollectionRevisions, rev.ID, rev))
+		}
+	}
+	for key, old := range snap.revisions {
+		if _, ok := s.revisions[key]; !ok {
+			for _, rev := range old {
+				revert(s.put(CollectionRevisions, rev.ID, rev))
+			}
+		}
+	}
+
+	s.parts = snap.parts
+	s.events = snap.events
+	s.revisions = snap.revisions
+	return firstErr
+}
+
+// txStore is the view of a Store handed to a transaction. Its writes skip the
+// writer lock, which the transaction already holds.
+type txStore struct {
+	s *Store
+}
+
+func (t *txStore) SavePart(ctx context.Context, part *storage.LessonPart) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.savePart(ctx, part)
+}
+func (t *txStore) DeletePart(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.deletePart(id)
+}
+func (t *txStore) SaveEvent(ctx context.Context, event *storage.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.saveEvent(ctx, event)
+}
+func (t *txStore) DeleteEvent(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.deleteEvent(id)
+}
+
+func (t *txStore) TrashPart(ctx context.Context, id string, deletion storage.Deletion) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.trashPart(id, deletion)
+}
+func (t *txStore) RestorePart(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.restorePart(id)
+}
+func (t *txStore) TrashEvent(ctx context.Context, id string, deletion storage.Deletion) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.trashEvent(id, deletion)
+}
+func (t *txStore) RestoreEvent(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.restoreEvent(id)
+}
+func (t *txStore) PurgeTrashedParts(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return t.s.purgeTrashedParts(before)
+}
+func (t *txStore) PurgeTrashedEvents(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return t.s.purgeTrashedEvents(before)
+}
+
+func (t *txStore) ImportParts(ctx context.Context, parts []*storage.LessonPart, revisions []*storage.Revision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.importParts(parts, revisions)
+}
+func (t *txStore) ImportEvents(ctx context.Context, events []*storage.Event, revisions []*storage.Revision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.s.importEvents(events, revisions)
+}
+
+func (t *txStore) GetPart(ctx context.Context, id string) (*storage.LessonPart, error) {
+	return t.s.GetPart(ctx, id)
+}
+func (t *txStore) ListParts(ctx context.Context) ([]*storage.LessonPart, error) {
+	return t.s.ListParts(ctx)
+}
+func (t *txStore) ListPartsFiltered(ctx context.Context, query storage.PartQuery) ([]*storage.LessonPart, int, error) {
+	return t.s.ListPartsFiltered(ctx, query)
+}
+func (t *txStore) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	return t.s.GetEvent(ctx, id)
+}
+func (t *txStore) ListEvents(ctx context.Context) ([]*storage.Event, error) {
+	return t.s.ListEvents(ctx)
+}
+func (t *txStore) ListEventsFiltered(ctx context.Context, filter bson.M, limit, offset int) ([]storage.Event, int, error) {
+	return t.s.ListEventsFiltered(ctx, filter, limit, offset)
+}
+func (t *txStore) SearchParts(ctx context.Context, query storage.SearchQuery) ([]storage.PartHit, error) {
+	return t.s.SearchParts(ctx, query)
+}
+func (t *txStore) SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.EventHit, error) {
+	return t.s.SearchEvents(ctx, query)
+}
+func (t *txStore) ListPublicEvents(ctx context.Context, query storage.PublicEventsQuery) ([]*storage.EventWithParts, error) {
+	return t.s.ListPublicEvents(ctx, query)
+}
@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bnei-Baruch/study-material-service/storage"
+)
+
+// TrashPart moves a lesson part to the trash
+func (s *Store) TrashPart(ctx context.Context, id string, deletion storage.Deletion) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.trashPart(id, deletion)
+}
+
+func (s *Store) trashPart(id string, deletion storage.Deletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	part, ok := s.parts[id]
+	if !ok || part.DeletedAt != nil {
+		return fmt.Errorf("part %s %w", id, storage.ErrNotFound)
+	}
+
+	stored := clonePart(part)
+	at := deletion.At
+	stored.DeletedAt = &at
+	stored.DeletedBy = deletion.By
+	stored.UpdatedAt = deletion.At
+	stored.Version++
+	if err := s.put(CollectionParts, id, stored); err != nil {
+		return fmt.Errorf("failed to trash part: %w", err)
+	}
+	s.parts[id] = stored
+	return nil
+}
+
+// RestorePart takes a lesson part out of the trash
+func (s *Store) RestorePart(ctx context.Context, id string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.restorePart(id)
+}
+
+func (s *Store) restorePart(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	part, ok := s.parts[id]
+	if !ok || part.DeletedAt == nil {
+		return fmt.Errorf("trashed part %s %w", id, storage.ErrNotFound)
+	}
+
+	stored := clonePart(part)
+	stored.DeletedAt = nil
+	stored.DeletedBy = ""
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	if err := s.put(CollectionParts, id, stored); err != nil {
+		return fmt.Errorf("failed to restore part: %w", err)
+	}
+	s.parts[id] = stored
+	return nil
+}
+
+// PurgeTrashedParts permanently deletes parts trashed before the given time
+func (s *Store) PurgeTrashedParts(ctx context.Context, before time.Time) (int, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.purgeTrashedParts(before)
+}
+
+func (s *Store) purgeTrashedParts(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, part := range s.parts {
+		if part.DeletedAt == nil || !part.DeletedAt.Before(before) {
+			continue
+		}
+		if err := s.remove(CollectionParts, id); err != nil {
+			return purged, fmt.Errorf("failed to purge trashed parts: %w", err)
+		}
+		delete(s.parts, id)
+		purged++
+		if err := s.deleteRevisions(storage.RevisionTypePart, id); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// TrashEvent moves an event to the trash
+func (s *Store) TrashEvent(ctx context.Context, id string, deletion storage.Deletion) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.trashEvent(id, deletion)
+}
+
+func (s *Store) trashEvent(id string, deletion storage.Deletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok || event.DeletedAt != nil {
+		return fmt.Errorf("event %s %w", id, storage.ErrNotFound)
+	}
+
+	stored := cloneEvent(event)
+	at := deletion.At
+	stored.DeletedAt = &at
+	stored.DeletedBy = deletion.By
+	stored.UpdatedAt = deletion.At
+	stored.Version++
+	if err := s.put(CollectionEvents, id, stored); err != nil {
+		return fmt.Errorf("failed to trash event: %w", err)
+	}
+	s.events[id] = stored
+	return nil
+}
+
+// RestoreEvent takes an event out of the trash
+func (s *Store) RestoreEvent(ctx context.Context, id string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.restoreEvent(id)
+}
+
+func (s *Store) restoreEvent(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok || event.DeletedAt == nil {
+		return fmt.Errorf("trashed event %s %w", id, storage.ErrNotFound)
+	}
+
+	stored := cloneEvent(event)
+	stored.DeletedAt = nil
+	stored.DeletedBy = ""
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	if err := s.put(CollectionEvents, id, stored); err != nil {
+		return fmt.Errorf("failed to restore event: %w", err)
+	}
+	s.events[id] = stored
+	return nil
+}
+
+// PurgeTrashedEvents permanently deletes events trashed before the given time
+func (s *Store) PurgeTrashedEvents(ctx context.Context, before time.Time) (int, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.purgeTrashedEvents(before)
+}
+
+func (s *Store) purgeTrashedEvents(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, event := range s.events {
+		if event.DeletedAt == nil || !event.DeletedAt.Before(before) {
+			continue
+		}
+		if err := s.remove(CollectionEvents, id); err != nil {
+			return purged, fmt.Errorf("failed to purge trashed events: %w", err)
+		}
+		delete(s.events, id)
+		purged++
+		if err := s.deleteRevisions(storage.RevisionTypeEvent, id); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
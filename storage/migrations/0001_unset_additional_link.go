@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// additional_link was added to lesson parts in v3.2 and later dropped from the model.
+// Remove the leftover field so documents match LessonPart.
+func init() {
+	register(Migration{
+		Version:     1,
+		Description: "remove dropped additional_link field from lesson parts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("lesson_parts").UpdateMany(ctx,
+				bson.M{"additional_link": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"additional_link": ""}},
+			)
+			return err
+		},
+		// Nothing reads additional_link any more, so there is nothing to restore
+		Down: func(ctx context.Context, db *mongo.Database) error { return nil },
+	})
+}
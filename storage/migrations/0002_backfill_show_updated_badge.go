@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// show_updated_badge was added after parts already existed. Store the default
+// explicitly so queries on the field see every part.
+func init() {
+	register(Migration{
+		Version:     2,
+		Description: "backfill show_updated_badge on lesson parts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("lesson_parts").UpdateMany(ctx,
+				bson.M{"show_updated_badge": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"show_updated_badge": false}},
+			)
+			return err
+		},
+		// false is the zero value, so leaving the backfilled field in place is harmless
+		Down: func(ctx context.Context, db *mongo.Database) error { return nil },
+	})
+}
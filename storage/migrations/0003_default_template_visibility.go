@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Templates created before the visible flag existed should stay visible.
+// LoadTemplates does this for templates.json; this does it for the stored config.
+func init() {
+	register(Migration{
+		Version:     3,
+		Description: "default template visibility to true in stored template config",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			opts := options.Update().SetArrayFilters(options.ArrayFilters{
+				Filters: []interface{}{bson.M{"t.visible": bson.M{"$exists": false}}},
+			})
+			_, err := db.Collection("templates").UpdateOne(ctx,
+				bson.M{"_id": "config"},
+				bson.M{"$set": bson.M{"templates.$[t].visible": true}},
+				opts,
+			)
+			return err
+		},
+		// Hidden and visible templates can't be told apart afterwards
+		Down: nil,
+	})
+}
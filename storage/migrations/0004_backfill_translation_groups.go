@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Translations used to be linked only by sharing event_id and order. Give every
+// such set a translation group, named after its Hebrew part when there is one.
+// Parts without an event form a group of their own.
+func init() {
+	register(Migration{
+		Version:     4,
+		Description: "backfill translation groups on lesson parts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			parts := db.Collection("lesson_parts")
+			missing := bson.M{"translation_group_id": bson.M{"$exists": false}}
+
+			pipeline := mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"translation_group_id": bson.M{"$exists": false},
+					"event_id":             bson.M{"$exists": true, "$ne": ""},
+				}}},
+				{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+				{{Key: "$group", Value: bson.M{
+					"_id": bson.M{"event_id": "$event_id", "order": "$order"},
+					"ids": bson.M{"$push": "$_id"},
+					"hebrew": bson.M{"$push": bson.M{
+						"$cond": bson.A{bson.M{"$eq": bson.A{"$language", "he"}}, "$_id", "$$REMOVE"},
+					}},
+				}}},
+			}
+			cursor, err := parts.Aggregate(ctx, pipeline)
+			if err != nil {
+				return fmt.Errorf("failed to group parts by event and order: %w", err)
+			}
+			defer cursor.Close(ctx)
+
+			for cursor.Next(ctx) {
+				var set struct {
+					IDs    []string `bson:"ids"`
+					Hebrew []string `bson:"hebrew"`
+				}
+				if err := cursor.Decode(&set); err != nil {
+					return err
+				}
+				group := set.IDs[0]
+				if len(set.Hebrew) > 0 {
+					group = set.Hebrew[0]
+				}
+				filter := bson.M{"_id": bson.M{"$in": set.IDs}, "translation_group_id": bson.M{"$exists": false}}
+				if _, err := parts.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"translation_group_id": group}}); err != nil {
+					return err
+				}
+			}
+			if err := cursor.Err(); err != nil {
+				return err
+			}
+
+			// Whatever is left has no event and is its own group
+			_, err = parts.UpdateMany(ctx, missing, mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{"translation_group_id": "$_id"}}},
+			})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("lesson_parts").UpdateMany(ctx,
+				bson.M{"translation_group_id": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"translation_group_id": ""}},
+			)
+			return err
+		},
+	})
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The lesson_parts text index stems each part by its search_language, which
+// SavePart derives from the part's language. Set it on parts saved before the
+// field existed; parts in languages without a MongoDB stemmer keep the "none" default.
+func init() {
+	searchLanguages := map[string]string{
+		"en":    "english",
+		"ru":    "russian",
+		"es":    "spanish",
+		"de":    "german",
+		"it":    "italian",
+		"fr":    "french",
+		"pt-BR": "portuguese",
+		"tr":    "turkish",
+	}
+
+	register(Migration{
+		Version:     5,
+		Description: "backfill search_language on lesson parts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			parts := db.Collection("lesson_parts")
+			for language, searchLanguage := range searchLanguages {
+				_, err := parts.UpdateMany(ctx,
+					bson.M{"language": language, "search_language": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"search_language": searchLanguage}},
+				)
+				if err != nil {
+					return fmt.Errorf("failed to set search language for %s parts: %w", language, err)
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("lesson_parts").UpdateMany(ctx,
+				bson.M{"search_language": bson.M{"$exists": true}},
+				bson.M{"$unset": bson.M{"search_language": ""}},
+			)
+			return err
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Translation stubs weren't marked before translation_stub existed. Mark the
+// ones still carrying the placeholder title; stubs titled from a template can't
+// be told apart from translated parts and stay unmarked.
+func init() {
+	register(Migration{
+		Version:     6,
+		Description: "mark untranslated stubs on lesson parts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("lesson_parts").UpdateMany(ctx,
+				bson.M{"title": "[Translation needed]", "translation_stub": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"translation_stub": true}},
+			)
+			return err
+		},
+		// The app marks new stubs too, and those can't be told apart from the
+		// backfilled ones
+		Down: nil,
+	})
+}
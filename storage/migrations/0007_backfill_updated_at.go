@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The stores set updated_at on every save, trash and restore, and conditional
+// GETs send it as Last-Modified. Start documents saved before the field existed
+// at their creation time.
+func init() {
+	collections := []string{"lesson_parts", "events"}
+
+	register(Migration{
+		Version:     7,
+		Description: "backfill updated_at on lesson parts and events",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range collections {
+				_, err := db.Collection(name).UpdateMany(ctx,
+					bson.M{"updated_at": bson.M{"$exists": false}},
+					mongo.Pipeline{{{Key: "$set", Value: bson.M{"updated_at": "$created_at"}}}},
+				)
+				if err != nil {
+					return fmt.Errorf("failed to backfill updated_at on %s: %w", name, err)
+				}
+			}
+			return nil
+		},
+		// Every save sets updated_at since, so unsetting it would drop real
+		// modification times along with the backfilled ones
+		Down: nil,
+	})
+}
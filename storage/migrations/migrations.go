@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is a numbered change to the MongoDB data or schema.
+// Migrations are applied in version order and recorded in schema_migrations.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error // nil if the migration can't be reverted
+}
+
+// Status describes whether a registered migration has been applied
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// appliedMigration is the schema_migrations document for an applied migration
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+const (
+	collectionName = "schema_migrations"
+	lockID         = "lock"
+	// staleLockAge is how long a lock is honoured before another process may take it over.
+	// It must outlast the migrate command's timeout (30 minutes), so a lock is only taken
+	// over once the process holding it has given up.
+	staleLockAge = time.Hour
+)
+
+var registry = map[int]Migration{}
+
+// register adds a migration to the registry. Each migration file calls it from init().
+func register(m Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic(fmt.Sprintf("duplicate migration version %d", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All returns the registered migrations in version order
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Migrator applies and reverts migrations against a MongoDB database
+type Migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewMigrator creates a migrator for the given database
+func NewMigrator(db *mongo.Database) *Migrator {
+	return &Migrator{
+		db:         db,
+		collection: db.Collection(collectionName),
+	}
+}
+
+// Status lists every registered migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, mig := range All() {
+		st := Status{Version: mig.Version, Description: mig.Description}
+		if a, ok := applied[mig.Version]; ok {
+			appliedAt := a.AppliedAt
+			st.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Up applies pending migrations up to and including target (0 means all of them)
+// and returns the migrations it applied
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range All() {
+		if target > 0 && mig.Version > target {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		if err := mig.Up(ctx, m.db); err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", mig.Version, mig.Description, err)
+		}
+
+		record := appliedMigration{Version: mig.Version, Description: mig.Description, AppliedAt: time.Now()}
+		if _, err := m.collection.InsertOne(ctx, record); err != nil {
+			return done, fmt.Errorf("failed to record migration %d: %w", mig.Version, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the given number of most recently applied migrations
+// and returns the migrations it reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all := All()
+	var done []Migration
+	for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := all[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("migration %d (%s) cannot be reverted", mig.Version, mig.Description)
+		}
+
+		if err := mig.Down(ctx, m.db); err != nil {
+			return done, fmt.Errorf("reverting migration %d (%s) failed: %w", mig.Version, mig.Description, err)
+		}
+		if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": mig.Version}); err != nil {
+			return done, fmt.Errorf("failed to unrecord migration %d: %w", mig.Version, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// applied returns the applied migrations keyed by version
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []appliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]appliedMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// lock takes the migration lock so that two processes (e.g. two replicas
+// auto-migrating on start) never run migrations at the same time.
+// A lock older than staleLockAge is assumed abandoned and taken over.
+func (m *Migrator) lock(ctx context.Context) error {
+	now := time.Now()
+	host, _ := os.Hostname()
+
+	filter := bson.M{"_id": lockID, "locked_at": bson.M{"$lt": now.Add(-staleLockAge)}}
+	update := bson.M{"$set": bson.M{"locked_at": now, "host": host, "pid": os.Getpid()}}
+	_, err := m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("migrations are already running in another process")
+		}
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	return nil
+}
+
+// unlock releases the migration lock
+func (m *Migrator) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m.collection.DeleteOne(ctx, bson.M{"_id": lockID})
+}
@@ -12,6 +12,8 @@ type LessonPart struct {
 	Language               string       `json:"language" bson:"language"`                                                       // ISO 639-1 code (e.g., "he", "en", "ru")
 	EventID                string       `json:"event_id,omitempty" bson:"event_id,omitempty"`                                   // Optional: links part to an event
 	Order                  int          `json:"order" bson:"order"`                                                             // Position within event (0=preparation, 1, 2, 3...)
+	TranslationGroupID     string       `json:"translation_group_id,omitempty" bson:"translation_group_id,omitempty"`           // Shared by a part and all its translations
+	TranslationStub        bool         `json:"translation_stub,omitempty" bson:"translation_stub,omitempty"`                   // Auto-created for another language and not translated yet
 	ExcerptsLink           string       `json:"excerpts_link,omitempty" bson:"excerpts_link,omitempty"`                         // Optional: link to selected excerpts
 	TranscriptLink         string       `json:"transcript_link,omitempty" bson:"transcript_link,omitempty"`                     // Optional: link to transcript
 	LessonLink             string       `json:"lesson_link,omitempty" bson:"lesson_link,omitempty"`                             // Optional: kabbalahmedia lesson URL
@@ -23,7 +25,22 @@ type LessonPart struct {
 	Sources                []Source     `json:"sources" bson:"sources"`
 	CustomLinks            []CustomLink `json:"custom_links,omitempty" bson:"custom_links,omitempty"` // Optional: custom links with titles (language-specific)
 	ShowUpdatedBadge       bool         `json:"show_updated_badge" bson:"show_updated_badge"`
+	Version                int          `json:"version" bson:"version"`                                                         // Incremented on every save, used for optimistic concurrency
 	CreatedAt              time.Time    `json:"created_at" bson:"created_at"`
+	UpdatedAt              time.Time    `json:"updated_at" bson:"updated_at"`                     // Set by the store on every save, trash and restore
+	UpdatedBy              string       `json:"updated_by,omitempty" bson:"updated_by,omitempty"` // Who saved this version of the part
+	DeletedAt              *time.Time   `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set while the part is in the trash
+	DeletedBy              string       `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"` // Who moved the part to the trash
+	SearchLanguage         string       `json:"-" bson:"search_language,omitempty"`               // MongoDB text search language, set on save from Language
+}
+
+// LastModified returns when the part was last changed. Parts saved before
+// updated_at existed report their creation time.
+func (p *LessonPart) LastModified() time.Time {
+	if p.UpdatedAt.IsZero() {
+		return p.CreatedAt
+	}
+	return p.UpdatedAt
 }
 
 // Source represents a study source from kabbalahmedia
@@ -77,7 +94,21 @@ type Event struct {
 	Titles      map[string]string `json:"titles,omitempty" bson:"titles,omitempty"`         // Multi-language titles (he, en, ru, es, de, it, fr, uk)
 	Public      bool              `json:"public" bson:"public"`                             // Whether event is public
 	EmailSentAt *time.Time        `json:"email_sent_at,omitempty" bson:"email_sent_at,omitempty"` // Track when email was sent to Google Group
+	Version     int               `json:"version" bson:"version"`                           // Incremented on every save, used for optimistic concurrency
 	CreatedAt   time.Time         `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" bson:"updated_at"`                     // Set by the store on every save, trash and restore
+	UpdatedBy   string            `json:"updated_by,omitempty" bson:"updated_by,omitempty"` // Who saved this version of the event
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set while the event is in the trash
+	DeletedBy   string            `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"` // Who moved the event to the trash
+}
+
+// LastModified returns when the event was last changed. Events saved before
+// updated_at existed report their creation time.
+func (e *Event) LastModified() time.Time {
+	if e.UpdatedAt.IsZero() {
+		return e.CreatedAt
+	}
+	return e.UpdatedAt
 }
 
 // EventType represents a configurable event type stored in MongoDB
@@ -87,6 +118,7 @@ type EventType struct {
 	Titles    map[string]string `json:"titles" bson:"titles"`       // Multi-language titles
 	Color     string            `json:"color" bson:"color"`         // Tailwind color name, e.g. "blue", "amber"
 	Order     int               `json:"order" bson:"order"`         // Display order
+	Version   int               `json:"version" bson:"version"`     // Incremented on every update, used for optimistic concurrency
 	CreatedAt time.Time         `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at" bson:"updated_at"`
 }
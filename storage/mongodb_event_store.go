@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,8 +13,10 @@ import (
 
 // MongoDBEventStore manages MongoDB-based storage for events
 type MongoDBEventStore struct {
-	collection *mongo.Collection
-	database   *mongo.Database
+	collection   *mongo.Collection
+	database     *mongo.Database
+	revisions    *mongo.Collection
+	transactions bool // Saves write the event and its revision in one transaction
 }
 
 // NewMongoDBEventStore creates a new MongoDB event store
@@ -28,9 +31,16 @@ func NewMongoDBEventStore(database *mongo.Database) (*MongoDBEventStore, error)
 		return nil, fmt.Errorf("failed to create event indexes: %w", err)
 	}
 
+	transactions, err := transactionsSupported(ctx, database.Client())
+	if err != nil {
+		return nil, err
+	}
+
 	return &MongoDBEventStore{
-		collection: collection,
-		database:   database,
+		collection:   collection,
+		database:     database,
+		revisions:    database.Collection(revisionsCollection),
+		transactions: transactions,
 	}, nil
 }
 
@@ -53,49 +63,125 @@ func createEventIndexes(ctx context.Context, collection *mongo.Collection) error
 				{Key: "date", Value: -1},
 			},
 		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+		},
+		{
+			Keys:    titlesTextIndexKeys(),
+			Options: options.Index().SetName("text_search").SetDefaultLanguage("none"),
+		},
 	}
 
 	_, err := collection.Indexes().CreateMany(ctx, indexes)
 	return err
 }
 
+// titlesTextIndexKeys indexes the event title in every language for text search
+func titlesTextIndexKeys() bson.D {
+	keys := bson.D{}
+	for _, lang := range TitleLanguages {
+		keys = append(keys, bson.E{Key: "titles." + lang, Value: "text"})
+	}
+	return keys
+}
+
 // SaveEvent saves an event to MongoDB
-func (s *MongoDBEventStore) SaveEvent(event *Event) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// An event without an ID is inserted under a newly allocated one. Otherwise the stored
+// event must still be at event.Version, or ErrVersionConflict is returned.
+// On success event.Version is incremented. The event and its revision are written
+// in one transaction wherever the server supports transactions. Within a unit of
+// work of RunInTransaction event is only updated once the transaction commits.
+func (s *MongoDBEventStore) SaveEvent(ctx context.Context, event *Event) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
-	if event.ID == "" {
-		event.ID = generateShortID()
+	// Write a copy, so a failed or retried attempt leaves event as it was
+	stored := *event
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
 	}
-	if event.CreatedAt.IsZero() {
-		event.CreatedAt = time.Now()
+	stored.UpdatedAt = time.Now()
+	if stored.ID == "" {
+		return s.insertEvent(ctx, event, &stored)
 	}
 
-	// Use upsert to insert or update
-	filter := bson.M{"_id": event.ID}
-	update := bson.M{"$set": event}
-	opts := options.Update().SetUpsert(true)
-
-	_, err := s.collection.UpdateOne(ctx, filter, update, opts)
+	// Replace the whole document so the stored event always matches its latest revision.
+	// If the stored event has moved past the expected version the upsert fails on _id.
+	stored.Version = event.Version + 1
+	filter := bson.M{"_id": event.ID, "version": versionFilter(event.Version)}
+	opts := options.Replace().SetUpsert(true)
+
+	err := withTransaction(ctx, s.database.Client(), s.transactions, func(ctx context.Context) error {
+		if _, err := s.collection.ReplaceOne(ctx, filter, &stored, opts); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("failed to save event %s: %w", event.ID, ErrVersionConflict)
+			}
+			return fmt.Errorf("failed to save event: %w", err)
+		}
+		return appendRevision(ctx, s.revisions, NewEventRevision(&stored))
+	})
 	if err != nil {
-		return fmt.Errorf("failed to save event: %w", err)
+		return err
 	}
-
+	AfterCommit(ctx, func() { savedEvent(event, &stored) })
 	return nil
 }
 
+// savedEvent copies the fields a save sets from the stored copy to the caller's event
+func savedEvent(event, stored *Event) {
+	event.Version = stored.Version
+	event.CreatedAt = stored.CreatedAt
+	event.UpdatedAt = stored.UpdatedAt
+}
+
+// insertEvent inserts a new event under a fresh ID, drawing another ID whenever
+// the generated one is already taken, so an existing event is never overwritten.
+// An ID taken between the check and the insert is only retried outside a
+// transaction; inside one the failed insert has aborted the transaction.
+// event gets its ID right away, so later writes of a unit of work can refer to
+// it, and loses it again if the unit of work is aborted.
+func (s *MongoDBEventStore) insertEvent(ctx context.Context, event, stored *Event) error {
+	stored.Version = 1
+	for attempt := 0; attempt < MaxIDAttempts; attempt++ {
+		stored.ID = NewID(IDCollectionEvents)
+		taken, err := idTaken(ctx, s.collection, stored.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save event: %w", err)
+		}
+		if taken {
+			continue
+		}
+		err = withTransaction(ctx, s.database.Client(), s.transactions, func(ctx context.Context) error {
+			if _, err := s.collection.InsertOne(ctx, stored); err != nil {
+				return err
+			}
+			return appendRevision(ctx, s.revisions, NewEventRevision(stored))
+		})
+		if err == nil {
+			event.ID = stored.ID
+			AfterAbort(ctx, func() { event.ID = "" })
+			AfterCommit(ctx, func() { savedEvent(event, stored) })
+			return nil
+		}
+		if !isDuplicateIDError(err) || inTransaction(ctx) {
+			return fmt.Errorf("failed to save event: %w", err)
+		}
+	}
+	return fmt.Errorf("failed to save event: no free id after %d attempts", MaxIDAttempts)
+}
+
 // GetEvent retrieves an event by ID
-func (s *MongoDBEventStore) GetEvent(id string) (*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventStore) GetEvent(ctx context.Context, id string) (*Event, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	var event Event
-	filter := bson.M{"_id": id}
+	filter := bson.M{"_id": id, "deleted_at": notTrashed}
 
 	err := s.collection.FindOne(ctx, filter).Decode(&event)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("event not found: %s", id)
+			return nil, fmt.Errorf("event %s %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get event: %w", err)
 	}
@@ -103,9 +189,9 @@ func (s *MongoDBEventStore) GetEvent(id string) (*Event, error) {
 	return &event, nil
 }
 
-// ListEvents lists all events
-func (s *MongoDBEventStore) ListEvents() ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ListEvents lists all events, including trashed ones
+func (s *MongoDBEventStore) ListEvents(ctx context.Context) ([]*Event, error) {
+	ctx, cancel := BulkContext(ctx)
 	defer cancel()
 
 	cursor, err := s.collection.Find(ctx, bson.M{})
@@ -123,10 +209,13 @@ func (s *MongoDBEventStore) ListEvents() ([]*Event, error) {
 }
 
 // ListEventsFiltered lists events with filters
-func (s *MongoDBEventStore) ListEventsFiltered(filter bson.M, limit, offset int) ([]Event, int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Trashed events are excluded unless the filter constrains deleted_at.
+func (s *MongoDBEventStore) ListEventsFiltered(ctx context.Context, filter bson.M, limit, offset int) ([]Event, int, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
+	filter = WithoutTrashed(filter)
+
 	// Count total matching documents
 	total, err := s.collection.CountDocuments(ctx, filter)
 	if err != nil {
@@ -159,9 +248,30 @@ func (s *MongoDBEventStore) ListEventsFiltered(filter bson.M, limit, offset int)
 	return events, int(total), nil
 }
 
+// ImportEvents writes events and their revisions as they are, replacing stored
+// events with the same IDs. The events and revisions are written in one
+// transaction wherever the server supports transactions.
+func (s *MongoDBEventStore) ImportEvents(ctx context.Context, events []*Event, revisions []*Revision) error {
+	ctx, cancel := BulkContext(ctx)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, len(events))
+	for i, event := range events {
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": event.ID}).SetReplacement(event).SetUpsert(true)
+	}
+	return withTransaction(ctx, s.database.Client(), s.transactions, func(ctx context.Context) error {
+		if len(models) > 0 {
+			if _, err := s.collection.BulkWrite(ctx, models); err != nil {
+				return fmt.Errorf("failed to import events: %w", err)
+			}
+		}
+		return importRevisions(ctx, s.revisions, revisions)
+	})
+}
+
 // DeleteEvent deletes an event by ID
-func (s *MongoDBEventStore) DeleteEvent(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventStore) DeleteEvent(ctx context.Context, id string) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
 	filter := bson.M{"_id": id}
@@ -171,14 +281,189 @@ func (s *MongoDBEventStore) DeleteEvent(id string) error {
 	}
 
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("event not found: %s", id)
+		return fmt.Errorf("event %s %w", id, ErrNotFound)
+	}
+
+	return deleteRevisions(ctx, s.revisions, RevisionTypeEvent, []string{id})
+}
+
+// TrashEvent moves an event to the trash
+func (s *MongoDBEventStore) TrashEvent(ctx context.Context, id string, deletion Deletion) error {
+	ctx, cancel := WriteContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "deleted_at": notTrashed}
+	update := bson.M{
+		"$set": bson.M{"deleted_at": deletion.At, "deleted_by": deletion.By, "updated_at": deletion.At},
+		"$inc": bson.M{"version": 1},
+	}
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to trash event: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("event %s %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// RestoreEvent takes an event out of the trash
+func (s *MongoDBEventStore) RestoreEvent(ctx context.Context, id string) error {
+	ctx, cancel := WriteContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to restore event: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("trashed event %s %w", id, ErrNotFound)
 	}
 
 	return nil
 }
 
+// PurgeTrashedEvents permanently deletes events trashed before the given time
+func (s *MongoDBEventStore) PurgeTrashedEvents(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := BulkContext(ctx)
+	defer cancel()
+
+	ids, err := trashedIDs(ctx, s.collection, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find trashed events: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trashed events: %w", err)
+	}
+
+	if err := deleteRevisions(ctx, s.revisions, RevisionTypeEvent, ids); err != nil {
+		return int(result.DeletedCount), err
+	}
+	return int(result.DeletedCount), nil
+}
+
 // GetDatabase returns the MongoDB database instance
 func (s *MongoDBEventStore) GetDatabase() *mongo.Database {
 	return s.database
 }
 
+
+// SearchEvents ranks untrashed events against a $text search on their titles
+func (s *MongoDBEventStore) SearchEvents(ctx context.Context, query SearchQuery) ([]EventHit, error) {
+	terms := SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := ReadContext(ctx)
+	defer cancel()
+
+	filter := query.EventFilter()
+	filter["$text"] = bson.M{"$search": strings.Join(terms, " ")}
+
+	score := bson.M{"$meta": "textScore"}
+	findOpts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "date", Value: -1}})
+	if query.Limit > 0 {
+		findOpts.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		Event `bson:",inline"`
+		Score float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+
+	hits := make([]EventHit, len(docs))
+	for i := range docs {
+		hits[i] = EventHit{Event: &docs[i].Event, Score: docs[i].Score}
+	}
+	return hits, nil
+}
+
+// ListPublicEvents loads public events with their parts and event types in a
+// single aggregation
+func (s *MongoDBEventStore) ListPublicEvents(ctx context.Context, query PublicEventsQuery) ([]*EventWithParts, error) {
+	ctx, cancel := ReadContext(ctx)
+	defer cancel()
+
+	partMatch := bson.M{
+		"$expr":      bson.M{"$eq": bson.A{"$event_id", "$$event_id"}},
+		"deleted_at": notTrashed,
+	}
+	if len(query.Languages) > 0 {
+		partMatch["language"] = bson.M{"$in": query.Languages}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query.Filter()}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "order", Value: 1}}}},
+	}
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: query.Limit}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from": "lesson_parts",
+			"let":  bson.M{"event_id": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": partMatch},
+				bson.M{"$sort": bson.D{{Key: "order", Value: 1}, {Key: "language", Value: 1}}},
+			},
+			"as": "parts",
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         "event_types",
+			"localField":   "type",
+			"foreignField": "name",
+			"as":           "event_types",
+		}}},
+	)
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate public events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		Event      `bson:",inline"`
+		Parts      []*LessonPart `bson:"parts"`
+		EventTypes []*EventType  `bson:"event_types"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode public events: %w", err)
+	}
+
+	events := make([]*EventWithParts, len(docs))
+	for i := range docs {
+		events[i] = &EventWithParts{Event: &docs[i].Event, Parts: docs[i].Parts}
+		if len(docs[i].EventTypes) > 0 {
+			events[i].EventType = docs[i].EventTypes[0]
+		}
+	}
+	return events, nil
+}
@@ -39,35 +39,47 @@ func NewMongoDBEventTypeStore(database *mongo.Database) (*MongoDBEventTypeStore,
 }
 
 // CreateEventType inserts a new event type
-func (s *MongoDBEventTypeStore) CreateEventType(et *EventType) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventTypeStore) CreateEventType(ctx context.Context, et *EventType) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
-	if et.ID == "" {
-		et.ID = generateShortID()
-	}
+	generateID := et.ID == ""
 	now := time.Now()
 	et.CreatedAt = now
 	et.UpdatedAt = now
+	et.Version = 1
 
-	if _, err := s.collection.InsertOne(ctx, et); err != nil {
+	for attempt := 0; attempt < MaxIDAttempts; attempt++ {
+		if generateID {
+			et.ID = NewID(IDCollectionEventTypes)
+		}
+		_, err := s.collection.InsertOne(ctx, et)
+		if err == nil {
+			return nil
+		}
+		if isDuplicateIDError(err) {
+			if generateID {
+				continue
+			}
+			return fmt.Errorf("failed to create event type: duplicate id %s", et.ID)
+		}
 		if mongo.IsDuplicateKeyError(err) {
-			return fmt.Errorf("event type with name %q already exists", et.Name)
+			return fmt.Errorf("event type with name %q %w", et.Name, ErrAlreadyExists)
 		}
 		return fmt.Errorf("failed to create event type: %w", err)
 	}
-	return nil
+	return fmt.Errorf("failed to create event type: no free id after %d attempts", MaxIDAttempts)
 }
 
 // GetEventType retrieves an event type by ID
-func (s *MongoDBEventTypeStore) GetEventType(id string) (*EventType, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventTypeStore) GetEventType(ctx context.Context, id string) (*EventType, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	var et EventType
 	if err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&et); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("event type not found: %s", id)
+			return nil, fmt.Errorf("event type %s %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get event type: %w", err)
 	}
@@ -75,14 +87,14 @@ func (s *MongoDBEventTypeStore) GetEventType(id string) (*EventType, error) {
 }
 
 // GetEventTypeByName retrieves an event type by name slug
-func (s *MongoDBEventTypeStore) GetEventTypeByName(name string) (*EventType, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventTypeStore) GetEventTypeByName(ctx context.Context, name string) (*EventType, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	var et EventType
 	if err := s.collection.FindOne(ctx, bson.M{"name": name}).Decode(&et); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("event type not found: %s", name)
+			return nil, fmt.Errorf("event type %s %w", name, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get event type by name: %w", err)
 	}
@@ -90,8 +102,8 @@ func (s *MongoDBEventTypeStore) GetEventTypeByName(name string) (*EventType, err
 }
 
 // ListEventTypes returns all event types sorted by order
-func (s *MongoDBEventTypeStore) ListEventTypes() ([]*EventType, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (s *MongoDBEventTypeStore) ListEventTypes(ctx context.Context) ([]*EventType, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	opts := options.Find().SetSort(bson.D{{Key: "order", Value: 1}})
@@ -109,28 +121,43 @@ func (s *MongoDBEventTypeStore) ListEventTypes() ([]*EventType, error) {
 }
 
 // UpdateEventType saves changes to an existing event type
-func (s *MongoDBEventTypeStore) UpdateEventType(et *EventType) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// The stored event type must still be at et.Version, otherwise ErrVersionConflict is returned.
+func (s *MongoDBEventTypeStore) UpdateEventType(ctx context.Context, et *EventType) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
+	expected := et.Version
 	et.UpdatedAt = time.Now()
-	filter := bson.M{"_id": et.ID}
+	et.Version = expected + 1
+	filter := bson.M{"_id": et.ID, "version": versionFilter(expected)}
 	update := bson.M{"$set": et}
 	opts := options.Update().SetUpsert(false)
 
 	result, err := s.collection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
+		et.Version = expected
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to update event type: name %q %w", et.Name, ErrAlreadyExists)
+		}
 		return fmt.Errorf("failed to update event type: %w", err)
 	}
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("event type not found: %s", et.ID)
+		et.Version = expected
+		count, err := s.collection.CountDocuments(ctx, bson.M{"_id": et.ID})
+		if err != nil {
+			return fmt.Errorf("failed to update event type: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("failed to update event type %s: %w", et.ID, ErrVersionConflict)
+		}
+		return fmt.Errorf("event type %s %w", et.ID, ErrNotFound)
 	}
 	return nil
 }
 
 // DeleteEventType deletes an event type by ID
-func (s *MongoDBEventTypeStore) DeleteEventType(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventTypeStore) DeleteEventType(ctx context.Context, id string) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
 	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -138,14 +165,14 @@ func (s *MongoDBEventTypeStore) DeleteEventType(id string) error {
 		return fmt.Errorf("failed to delete event type: %w", err)
 	}
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("event type not found: %s", id)
+		return fmt.Errorf("event type %s %w", id, ErrNotFound)
 	}
 	return nil
 }
 
 // CountEventTypes returns the number of event types in the collection
-func (s *MongoDBEventTypeStore) CountEventTypes() (int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBEventTypeStore) CountEventTypes(ctx context.Context) (int64, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	count, err := s.collection.CountDocuments(ctx, bson.M{})
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revisionsCollection holds the revision history of parts and events
+const revisionsCollection = "revisions"
+
+// maxRevisionAttempts bounds how often appendRevision retries when a concurrent
+// save took the same revision number
+const maxRevisionAttempts = 5
+
+// MongoDBRevisionStore reads part and event revisions from MongoDB
+type MongoDBRevisionStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDBRevisionStore creates a new MongoDB revision store
+func NewMongoDBRevisionStore(database *mongo.Database) (*MongoDBRevisionStore, error) {
+	collection := database.Collection(revisionsCollection)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "document_type", Value: 1},
+			{Key: "document_id", Value: 1},
+			{Key: "rev", Value: -1},
+		},
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, fmt.Errorf("failed to create revision indexes: %w", err)
+	}
+
+	return &MongoDBRevisionStore{collection: collection}, nil
+}
+
+// ListRevisions returns a document's revisions, oldest first
+func (s *MongoDBRevisionStore) ListRevisions(ctx context.Context, documentType, documentID string) ([]*Revision, error) {
+	ctx, cancel := ReadContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"document_type": documentType, "document_id": documentID}
+	opts := options.Find().SetSort(bson.D{{Key: "rev", Value: 1}})
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []*Revision{}
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetRevision retrieves one revision of a document
+func (s *MongoDBRevisionStore) GetRevision(ctx context.Context, documentType, documentID string, rev int) (*Revision, error) {
+	ctx, cancel := ReadContext(ctx)
+	defer cancel()
+
+	var revision Revision
+	err := s.collection.FindOne(ctx, bson.M{"_id": RevisionID(documentType, documentID, rev)}).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("revision %d of %s %s %w", rev, documentType, documentID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// ListAllRevisions returns every stored revision, grouped by document and oldest first
+func (s *MongoDBRevisionStore) ListAllRevisions(ctx context.Context) ([]*Revision, error) {
+	ctx, cancel := BulkContext(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "document_type", Value: 1}, {Key: "document_id", Value: 1}, {Key: "rev", Value: 1}})
+	cursor, err := s.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []*Revision{}
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// appendRevision numbers a revision after the document's latest one and inserts it.
+// Revision IDs include the number, so two concurrent saves can't both take it;
+// the loser retries with the next number.
+func appendRevision(ctx context.Context, collection *mongo.Collection, revision *Revision) error {
+	for attempt := 0; attempt < maxRevisionAttempts; attempt++ {
+		var latest Revision
+		filter := bson.M{"document_type": revision.DocumentType, "document_id": revision.DocumentID}
+		opts := options.FindOne().SetSort(bson.D{{Key: "rev", Value: -1}}).SetProjection(bson.M{"rev": 1})
+
+		err := collection.FindOne(ctx, filter, opts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to find latest revision: %w", err)
+		}
+
+		revision.Number(latest.Rev + 1)
+		_, err = collection.InsertOne(ctx, revision)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to save revision: %w", err)
+		}
+	}
+	return fmt.Errorf("failed to save revision: too many concurrent saves of %s %s", revision.DocumentType, revision.DocumentID)
+}
+
+// deleteRevisions removes the revision history of the given documents
+func deleteRevisions(ctx context.Context, collection *mongo.Collection, documentType string, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	filter := bson.M{"document_type": documentType, "document_id": bson.M{"$in": documentIDs}}
+	if _, err := collection.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete revisions: %w", err)
+	}
+	return nil
+}
+
+// importRevisions writes revisions as they are, replacing stored ones with the same ID
+func importRevisions(ctx context.Context, collection *mongo.Collection, revisions []*Revision) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, len(revisions))
+	for i, rev := range revisions {
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": rev.ID}).SetReplacement(rev).SetUpsert(true)
+	}
+	if _, err := collection.BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("failed to import revisions: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,9 +13,11 @@ import (
 
 // MongoDBStore manages MongoDB-based storage for lesson parts
 type MongoDBStore struct {
-	client     *mongo.Client
-	database   *mongo.Database
-	collection *mongo.Collection
+	client       *mongo.Client
+	database     *mongo.Database
+	collection   *mongo.Collection
+	revisions    *mongo.Collection
+	transactions bool // Saves write the part and its revision in one transaction
 }
 
 // NewMongoDBStore creates a new MongoDB store instance
@@ -41,10 +44,17 @@ func NewMongoDBStore(uri, dbName string) (*MongoDBStore, error) {
 		return nil, fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	transactions, err := transactionsSupported(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MongoDBStore{
-		client:     client,
-		database:   database,
-		collection: collection,
+		client:       client,
+		database:     database,
+		collection:   collection,
+		revisions:    database.Collection(revisionsCollection),
+		transactions: transactions,
 	}, nil
 }
 
@@ -69,6 +79,35 @@ func createIndexes(ctx context.Context, collection *mongo.Collection) error {
 				{Key: "language", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{{Key: "date", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "sources.source_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "translation_group_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+		},
+		{
+			// Hebrew and other languages without a stemmer are indexed as "none"
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "sources.source_title", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("text_search").
+				SetWeights(bson.D{
+					{Key: "title", Value: searchWeightTitle},
+					{Key: "description", Value: searchWeightDescription},
+					{Key: "sources.source_title", Value: searchWeightSourceTitle},
+				}).
+				SetDefaultLanguage("none").
+				SetLanguageOverride("search_language"),
+		},
 	}
 
 	_, err := collection.Indexes().CreateMany(ctx, indexes)
@@ -76,44 +115,105 @@ func createIndexes(ctx context.Context, collection *mongo.Collection) error {
 }
 
 // SavePart saves a lesson part to MongoDB
-func (s *MongoDBStore) SavePart(part *LessonPart) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// A part without an ID is inserted under a newly allocated one. Otherwise the stored
+// part must still be at part.Version, or ErrVersionConflict is returned.
+// On success part.Version is incremented. The part and its revision are written
+// in one transaction wherever the server supports transactions. Within a unit of
+// work of RunInTransaction part is only updated once the transaction commits.
+func (s *MongoDBStore) SavePart(ctx context.Context, part *LessonPart) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
-	// Generate ID if not set
-	if part.ID == "" {
-		part.ID = generateShortID()
+	// Write a copy, so a failed or retried attempt leaves part as it was
+	stored := *part
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
 	}
+	stored.SearchLanguage = TextSearchLanguage(stored.Language)
+	stored.UpdatedAt = time.Now()
 
-	// Set created time if not set
-	if part.CreatedAt.IsZero() {
-		part.CreatedAt = time.Now()
+	if stored.ID == "" {
+		return s.insertPart(ctx, part, &stored)
 	}
 
-	// Use ReplaceOne for atomic document replacement
-	filter := bson.M{"_id": part.ID}
+	// Use ReplaceOne for atomic document replacement. If the stored part has moved
+	// past the expected version the filter doesn't match and the upsert fails on _id.
+	stored.Version = part.Version + 1
+	filter := bson.M{"_id": part.ID, "version": versionFilter(part.Version)}
 	opts := options.Replace().SetUpsert(true)
 
-	_, err := s.collection.ReplaceOne(ctx, filter, part, opts)
+	err := withTransaction(ctx, s.client, s.transactions, func(ctx context.Context) error {
+		if _, err := s.collection.ReplaceOne(ctx, filter, &stored, opts); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("failed to save part %s: %w", part.ID, ErrVersionConflict)
+			}
+			return fmt.Errorf("failed to save part: %w", err)
+		}
+		return appendRevision(ctx, s.revisions, NewPartRevision(&stored))
+	})
 	if err != nil {
-		return fmt.Errorf("failed to save part: %w", err)
+		return err
 	}
-
+	AfterCommit(ctx, func() { savedPart(part, &stored) })
 	return nil
 }
 
+// savedPart copies the fields a save sets from the stored copy to the caller's part
+func savedPart(part, stored *LessonPart) {
+	part.Version = stored.Version
+	part.CreatedAt = stored.CreatedAt
+	part.UpdatedAt = stored.UpdatedAt
+	part.SearchLanguage = stored.SearchLanguage
+}
+
+// insertPart inserts a new part under a fresh ID, drawing another ID whenever
+// the generated one is already taken, so an existing part is never overwritten.
+// An ID taken between the check and the insert is only retried outside a
+// transaction; inside one the failed insert has aborted the transaction.
+// part gets its ID right away, so later writes of a unit of work can refer to
+// it, and loses it again if the unit of work is aborted.
+func (s *MongoDBStore) insertPart(ctx context.Context, part, stored *LessonPart) error {
+	stored.Version = 1
+	for attempt := 0; attempt < MaxIDAttempts; attempt++ {
+		stored.ID = NewID(IDCollectionParts)
+		taken, err := idTaken(ctx, s.collection, stored.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save part: %w", err)
+		}
+		if taken {
+			continue
+		}
+		err = withTransaction(ctx, s.client, s.transactions, func(ctx context.Context) error {
+			if _, err := s.collection.InsertOne(ctx, stored); err != nil {
+				return err
+			}
+			return appendRevision(ctx, s.revisions, NewPartRevision(stored))
+		})
+		if err == nil {
+			part.ID = stored.ID
+			AfterAbort(ctx, func() { part.ID = "" })
+			AfterCommit(ctx, func() { savedPart(part, stored) })
+			return nil
+		}
+		if !isDuplicateIDError(err) || inTransaction(ctx) {
+			return fmt.Errorf("failed to save part: %w", err)
+		}
+	}
+	return fmt.Errorf("failed to save part: no free id after %d attempts", MaxIDAttempts)
+}
+
 // GetPart retrieves a lesson part by ID
-func (s *MongoDBStore) GetPart(id string) (*LessonPart, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBStore) GetPart(ctx context.Context, id string) (*LessonPart, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	var part LessonPart
-	filter := bson.M{"_id": id}
+	filter := bson.M{"_id": id, "deleted_at": notTrashed}
 
 	err := s.collection.FindOne(ctx, filter).Decode(&part)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("part not found: %s", id)
+			return nil, fmt.Errorf("part %s %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get part: %w", err)
 	}
@@ -121,9 +221,9 @@ func (s *MongoDBStore) GetPart(id string) (*LessonPart, error) {
 	return &part, nil
 }
 
-// ListParts returns all lesson parts
-func (s *MongoDBStore) ListParts() ([]*LessonPart, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ListParts returns all lesson parts, including trashed ones
+func (s *MongoDBStore) ListParts(ctx context.Context) ([]*LessonPart, error) {
+	ctx, cancel := BulkContext(ctx)
 	defer cancel()
 
 	cursor, err := s.collection.Find(ctx, bson.M{})
@@ -140,9 +240,134 @@ func (s *MongoDBStore) ListParts() ([]*LessonPart, error) {
 	return parts, nil
 }
 
+// ListPartsFiltered returns the parts matching a query along with the total number
+// of matches. Filters on event_id, language and order are served by the indexes
+// created in createIndexes. The total is only counted separately when paginating.
+func (s *MongoDBStore) ListPartsFiltered(ctx context.Context, query PartQuery) ([]*LessonPart, int, error) {
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := ReadContext(ctx)
+	defer cancel()
+
+	filter := query.Filter()
+	if query.PublicEventsOnly {
+		return s.listPublicEventParts(ctx, query, filter)
+	}
+
+	findOpts := options.Find().SetSort(partSort(query))
+	if query.Limit > 0 {
+		findOpts.SetLimit(int64(query.Limit))
+	}
+	if query.Offset > 0 {
+		findOpts.SetSkip(int64(query.Offset))
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find parts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var parts []*LessonPart
+	if err = cursor.All(ctx, &parts); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode parts: %w", err)
+	}
+
+	total := len(parts)
+	if query.Limit > 0 || query.Offset > 0 {
+		count, err := s.collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count parts: %w", err)
+		}
+		total = int(count)
+	}
+
+	return parts, total, nil
+}
+
+// partSort returns the MongoDB sort of a query, ending with _id so pages are stable
+func partSort(query PartQuery) bson.D {
+	sort := bson.D{}
+	for _, f := range query.SortOrDefault() {
+		direction := 1
+		if f.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: f.Field, Value: direction})
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1})
+}
+
+// listPublicEventParts runs a part query that only matches parts of public events,
+// joining each part's event in the aggregation rather than listing the events first
+func (s *MongoDBStore) listPublicEventParts(ctx context.Context, query PartQuery, filter bson.M) ([]*LessonPart, int, error) {
+	match := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "events",
+			"let":  bson.M{"event_id": "$event_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"$expr":      bson.M{"$eq": bson.A{"$_id", "$$event_id"}},
+					"public":     true,
+					"deleted_at": notTrashed,
+				}},
+				bson.M{"$project": bson.M{"_id": 1}},
+			},
+			"as": "public_event",
+		}}},
+		{{Key: "$match", Value: bson.M{"public_event": bson.M{"$ne": bson.A{}}}}},
+	}
+
+	pipeline := append(mongo.Pipeline{}, match...)
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: partSort(query)}})
+	if query.Offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: query.Offset}})
+	}
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: query.Limit}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{"public_event": 0}}})
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find parts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var parts []*LessonPart
+	if err = cursor.All(ctx, &parts); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode parts: %w", err)
+	}
+
+	total := len(parts)
+	if query.Limit > 0 || query.Offset > 0 {
+		countPipeline := append(match, bson.D{{Key: "$count", Value: "total"}})
+		cursor, err := s.collection.Aggregate(ctx, countPipeline)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count parts: %w", err)
+		}
+		defer cursor.Close(ctx)
+		var counts []struct {
+			Total int `bson:"total"`
+		}
+		if err := cursor.All(ctx, &counts); err != nil {
+			return nil, 0, fmt.Errorf("failed to count parts: %w", err)
+		}
+		total = 0
+		if len(counts) > 0 {
+			total = counts[0].Total
+		}
+	}
+
+	return parts, total, nil
+}
+
 // DeletePart deletes a lesson part by ID
-func (s *MongoDBStore) DeletePart(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBStore) DeletePart(ctx context.Context, id string) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
 	filter := bson.M{"_id": id}
@@ -152,12 +377,127 @@ func (s *MongoDBStore) DeletePart(id string) error {
 	}
 
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("part not found: %s", id)
+		return fmt.Errorf("part %s %w", id, ErrNotFound)
+	}
+
+	return deleteRevisions(ctx, s.revisions, RevisionTypePart, []string{id})
+}
+
+// ImportParts writes parts and their revisions as they are, replacing stored parts
+// with the same IDs. The parts and revisions are written in one transaction
+// wherever the server supports transactions.
+func (s *MongoDBStore) ImportParts(ctx context.Context, parts []*LessonPart, revisions []*Revision) error {
+	ctx, cancel := BulkContext(ctx)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, len(parts))
+	for i, part := range parts {
+		stored := *part
+		stored.SearchLanguage = TextSearchLanguage(stored.Language)
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": part.ID}).SetReplacement(&stored).SetUpsert(true)
+	}
+	return withTransaction(ctx, s.client, s.transactions, func(ctx context.Context) error {
+		if len(models) > 0 {
+			if _, err := s.collection.BulkWrite(ctx, models); err != nil {
+				return fmt.Errorf("failed to import parts: %w", err)
+			}
+		}
+		return importRevisions(ctx, s.revisions, revisions)
+	})
+}
+
+// TrashPart moves a lesson part to the trash
+func (s *MongoDBStore) TrashPart(ctx context.Context, id string, deletion Deletion) error {
+	ctx, cancel := WriteContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "deleted_at": notTrashed}
+	update := bson.M{
+		"$set": bson.M{"deleted_at": deletion.At, "deleted_by": deletion.By, "updated_at": deletion.At},
+		"$inc": bson.M{"version": 1},
+	}
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to trash part: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("part %s %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// RestorePart takes a lesson part out of the trash
+func (s *MongoDBStore) RestorePart(ctx context.Context, id string) error {
+	ctx, cancel := WriteContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to restore part: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("trashed part %s %w", id, ErrNotFound)
 	}
 
 	return nil
 }
 
+// PurgeTrashedParts permanently deletes parts trashed before the given time
+func (s *MongoDBStore) PurgeTrashedParts(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := BulkContext(ctx)
+	defer cancel()
+
+	ids, err := trashedIDs(ctx, s.collection, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find trashed parts: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trashed parts: %w", err)
+	}
+
+	if err := deleteRevisions(ctx, s.revisions, RevisionTypePart, ids); err != nil {
+		return int(result.DeletedCount), err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// trashedIDs returns the IDs of documents trashed before the given time
+func trashedIDs(ctx context.Context, collection *mongo.Collection, before time.Time) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids, nil
+}
+
 // GetDatabase returns the MongoDB database instance
 func (s *MongoDBStore) GetDatabase() *mongo.Database {
 	return s.database
@@ -169,3 +509,54 @@ func (s *MongoDBStore) Close() error {
 	defer cancel()
 	return s.client.Disconnect(ctx)
 }
+
+// SearchParts ranks untrashed parts against $text searches on the text_search index.
+// Hits of searches stemmed by different languages are merged by score.
+func (s *MongoDBStore) SearchParts(ctx context.Context, query SearchQuery) ([]PartHit, error) {
+	terms := SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := ReadContext(ctx)
+	defer cancel()
+
+	score := bson.M{"$meta": "textScore"}
+	findOpts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "date", Value: -1}})
+	if query.Limit > 0 {
+		findOpts.SetLimit(int64(query.Limit))
+	}
+
+	var hits []PartHit
+	for _, filter := range query.partTextFilters(terms) {
+		cursor, err := s.collection.Find(ctx, filter, findOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search parts: %w", err)
+		}
+		var docs []struct {
+			LessonPart `bson:",inline"`
+			Score      float64 `bson:"score"`
+		}
+		err = cursor.All(ctx, &docs)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode parts: %w", err)
+		}
+		for i := range docs {
+			hits = append(hits, PartHit{Part: &docs[i].LessonPart, Score: docs[i].Score})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Part.Date.After(hits[j].Part.Date)
+	})
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
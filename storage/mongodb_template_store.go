@@ -45,17 +45,25 @@ func createTemplateIndexes(ctx context.Context, collection *mongo.Collection) er
 }
 
 // SaveConfig saves the entire template configuration to MongoDB
-func (s *MongoDBTemplateStore) SaveConfig(config *TemplateConfig) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// The stored configuration must still be at config.Version, otherwise ErrVersionConflict is returned.
+func (s *MongoDBTemplateStore) SaveConfig(ctx context.Context, config *TemplateConfig) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
-	// Use fixed ID "config" to store the entire configuration as a single document
-	filter := bson.M{"_id": "config"}
+	// Use fixed ID "config" to store the entire configuration as a single document.
+	// If the stored config has moved past the expected version the upsert fails on _id.
+	expected := config.Version
+	config.Version = expected + 1
+	filter := bson.M{"_id": "config", "version": versionFilter(expected)}
 	update := bson.M{"$set": config}
 	opts := options.Update().SetUpsert(true)
 
 	_, err := s.collection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
+		config.Version = expected
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to save template config: %w", ErrVersionConflict)
+		}
 		return fmt.Errorf("failed to save template config: %w", err)
 	}
 
@@ -63,8 +71,8 @@ func (s *MongoDBTemplateStore) SaveConfig(config *TemplateConfig) error {
 }
 
 // GetConfig retrieves the entire template configuration from MongoDB
-func (s *MongoDBTemplateStore) GetConfig() (*TemplateConfig, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBTemplateStore) GetConfig(ctx context.Context) (*TemplateConfig, error) {
+	ctx, cancel := ReadContext(ctx)
 	defer cancel()
 
 	var config TemplateConfig
@@ -73,7 +81,7 @@ func (s *MongoDBTemplateStore) GetConfig() (*TemplateConfig, error) {
 	err := s.collection.FindOne(ctx, filter).Decode(&config)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("template config not found")
+			return nil, fmt.Errorf("template config %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get template config: %w", err)
 	}
@@ -83,8 +91,8 @@ func (s *MongoDBTemplateStore) GetConfig() (*TemplateConfig, error) {
 
 // InitializeFromJSON initializes MongoDB with data from TemplateConfig (for first run)
 // This is used to seed MongoDB from the templates.json file on first startup
-func (s *MongoDBTemplateStore) InitializeFromJSON(config *TemplateConfig) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *MongoDBTemplateStore) InitializeFromJSON(ctx context.Context, config *TemplateConfig) error {
+	ctx, cancel := WriteContext(ctx)
 	defer cancel()
 
 	// Check if config already exists
@@ -99,5 +107,5 @@ func (s *MongoDBTemplateStore) InitializeFromJSON(config *TemplateConfig) error
 	}
 
 	// Save the new config
-	return s.SaveConfig(config)
+	return s.SaveConfig(ctx, config)
 }
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoDBTransactor runs units of work in MongoDB multi-document transactions
+type MongoDBTransactor struct {
+	client    *mongo.Client
+	parts     *MongoDBStore
+	events    *MongoDBEventStore
+	supported bool
+}
+
+// NewMongoDBTransactor creates a transactor for the given part and event stores.
+// Transactions need a replica set or sharded cluster; on a standalone server units
+// of work run without a transaction and a warning is logged once at startup.
+func NewMongoDBTransactor(parts *MongoDBStore, events *MongoDBEventStore) (*MongoDBTransactor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	supported, err := transactionsSupported(ctx, parts.client)
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		log.Println("WARNING: MongoDB is a standalone server — cascading deletes and duplicates run without transactions")
+	}
+
+	return &MongoDBTransactor{
+		client:    parts.client,
+		parts:     parts,
+		events:    events,
+		supported: supported,
+	}, nil
+}
+
+// RunInTransaction calls fn inside a MongoDB transaction and commits it if fn succeeds.
+// The context handed to fn carries the session, so every call made with it joins the
+// transaction. The driver may call fn again if the transaction hits a transient error;
+// the documents fn saved are only updated once the transaction commits, so every
+// attempt starts from the same state.
+func (t *MongoDBTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	ctx, cancel := BulkContext(ctx)
+	defer cancel()
+
+	tx := Tx{Parts: t.parts, Events: t.events}
+	if !t.supported {
+		attemptCtx, attempt := NewAttempt(ctx)
+		if err := fn(attemptCtx, tx); err != nil {
+			attempt.Abort()
+			return err
+		}
+		attempt.Commit()
+		return nil
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var attempt *Attempt
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// A previous attempt was aborted
+		attempt.Abort()
+		var attemptCtx context.Context
+		attemptCtx, attempt = NewAttempt(sc)
+		return nil, fn(attemptCtx, tx)
+	})
+	if err != nil {
+		attempt.Abort()
+		return fmt.Errorf("transaction aborted: %w", err)
+	}
+	attempt.Commit()
+	return nil
+}
+
+// inTransaction reports whether ctx carries the session of a running transaction,
+// i.e. a unit of work of RunInTransaction
+func inTransaction(ctx context.Context) bool {
+	return mongo.SessionFromContext(ctx) != nil
+}
+
+// transactionsSupported reports whether the server can run multi-document
+// transactions, i.e. is a replica set or sharded cluster
+func transactionsSupported(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, fmt.Errorf("failed to check transaction support: %w", err)
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// withTransaction runs fn in a transaction of its own, so a document and its
+// revision are written together. Within a unit of work of RunInTransaction, or
+// on a server without transactions, fn runs as it is. Like RunInTransaction, fn
+// may be called again after a transient error.
+func withTransaction(ctx context.Context, client *mongo.Client, supported bool, fn func(ctx context.Context) error) error {
+	if !supported || inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidPartOrder is returned when a new part sequence doesn't list every
+// part of the event exactly once
+var ErrInvalidPartOrder = errors.New("invalid part order")
+
+// orderReferenceLanguage is the language whose order the other languages follow
+const orderReferenceLanguage = "he"
+
+// PartGroup is one part of an event in all its languages
+type PartGroup struct {
+	Key   string // Translation group ID, or "order:<n>" for parts from before translation groups
+	Order int    // Order of the Hebrew part, or the most common order if there is none
+	Parts []*LessonPart
+}
+
+// Drifted reports whether the group's languages have different orders
+func (g *PartGroup) Drifted() bool {
+	for _, p := range g.Parts {
+		if p.Order != g.Order {
+			return true
+		}
+	}
+	return false
+}
+
+// Reference returns the Hebrew part of the group, or its first part
+func (g *PartGroup) Reference() *LessonPart {
+	for _, p := range g.Parts {
+		if p.Language == orderReferenceLanguage {
+			return p
+		}
+	}
+	return g.Parts[0]
+}
+
+// GroupEventParts groups an event's parts by translation group, sorted by order
+func GroupEventParts(parts []*LessonPart) []*PartGroup {
+	byKey := make(map[string]*PartGroup)
+	var groups []*PartGroup
+	for _, p := range parts {
+		key := p.TranslationGroupID
+		if key == "" {
+			key = fmt.Sprintf("order:%d", p.Order)
+		}
+		group, ok := byKey[key]
+		if !ok {
+			group = &PartGroup{Key: key}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.Parts = append(group.Parts, p)
+	}
+
+	for _, g := range groups {
+		sort.Slice(g.Parts, func(i, j int) bool { return g.Parts[i].Language < g.Parts[j].Language })
+		g.Order = referenceOrder(g.Parts)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Order != groups[j].Order {
+			return groups[i].Order < groups[j].Order
+		}
+		return groups[i].Reference().CreatedAt.Before(groups[j].Reference().CreatedAt)
+	})
+	return groups
+}
+
+// referenceOrder returns the order of the Hebrew part, or else the most common
+// order, the lowest on a tie
+func referenceOrder(parts []*LessonPart) int {
+	counts := make(map[int]int)
+	for _, p := range parts {
+		if p.Language == orderReferenceLanguage {
+			return p.Order
+		}
+		counts[p.Order]++
+	}
+	best, bestCount := 0, 0
+	for order, count := range counts {
+		if count > bestCount || (count == bestCount && order < best) {
+			best, bestCount = order, count
+		}
+	}
+	return best
+}
+
+// sequenceGroups maps a new sequence of part or translation group IDs to the
+// event's groups. The preparation part (order 0) may only come first, and every
+// other group must be listed exactly once.
+func sequenceGroups(groups []*PartGroup, ids []string) ([]*PartGroup, error) {
+	lookup := make(map[string]*PartGroup)
+	for _, g := range groups {
+		lookup[g.Key] = g
+		for _, p := range g.Parts {
+			lookup[p.ID] = g
+		}
+	}
+
+	listed := make(map[*PartGroup]bool)
+	var sequence []*PartGroup
+	for i, id := range ids {
+		group, ok := lookup[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not a part of this event", ErrInvalidPartOrder, id)
+		}
+		if listed[group] {
+			return nil, fmt.Errorf("%w: %s is listed more than once", ErrInvalidPartOrder, id)
+		}
+		listed[group] = true
+		if group.Order == 0 {
+			if i > 0 {
+				return nil, fmt.Errorf("%w: the preparation part %s keeps order 0, list it first or leave it out", ErrInvalidPartOrder, id)
+			}
+			continue
+		}
+		sequence = append(sequence, group)
+	}
+
+	var missing []string
+	for _, g := range groups {
+		if g.Order != 0 && !listed[g] {
+			missing = append(missing, g.Reference().ID)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing parts %s", ErrInvalidPartOrder, strings.Join(missing, ", "))
+	}
+	return sequence, nil
+}
+
+// ReorderEventParts gives the event's parts the order of ids, a sequence of part
+// or translation group IDs, in every language: the first gets order 1, the next 2
+// and so on. The preparation part keeps order 0. It returns the event's groups in
+// their new order and how many parts were changed.
+func ReorderEventParts(ctx context.Context, tx Tx, eventID string, ids []string, updatedBy string) ([]*PartGroup, int, error) {
+	groups, err := eventPartGroups(ctx, tx, eventID)
+	if err != nil {
+		return nil, 0, err
+	}
+	sequence, err := sequenceGroups(groups, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ordered []*PartGroup
+	for _, g := range groups {
+		if g.Order == 0 {
+			ordered = append(ordered, g)
+		}
+	}
+	for i, g := range sequence {
+		g.Order = i + 1
+		ordered = append(ordered, g)
+	}
+
+	changed, err := saveGroupOrders(ctx, tx, ordered, updatedBy)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ordered, changed, nil
+}
+
+// RepairEventPartOrder moves every language version of the event's parts to the
+// order of its Hebrew version, undoing drift between languages. It returns the
+// event's groups and how many parts were changed.
+func RepairEventPartOrder(ctx context.Context, tx Tx, eventID string, updatedBy string) ([]*PartGroup, int, error) {
+	groups, err := eventPartGroups(ctx, tx, eventID)
+	if err != nil {
+		return nil, 0, err
+	}
+	changed, err := saveGroupOrders(ctx, tx, groups, updatedBy)
+	if err != nil {
+		return nil, 0, err
+	}
+	return groups, changed, nil
+}
+
+// eventPartGroups loads the event's parts grouped by translation group
+func eventPartGroups(ctx context.Context, tx Tx, eventID string) ([]*PartGroup, error) {
+	parts, _, err := tx.Parts.ListPartsFiltered(ctx, PartQuery{EventID: eventID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parts: %w", err)
+	}
+	return GroupEventParts(parts), nil
+}
+
+// saveGroupOrders saves every part whose order differs from its group's
+func saveGroupOrders(ctx context.Context, tx Tx, groups []*PartGroup, updatedBy string) (int, error) {
+	changed := 0
+	for _, g := range groups {
+		for _, p := range g.Parts {
+			if p.Order == g.Order {
+				continue
+			}
+			p.Order = g.Order
+			p.UpdatedBy = updatedBy
+			if err := tx.Parts.SavePart(ctx, p); err != nil {
+				return changed, fmt.Errorf("failed to reorder %s part %s: %w", p.Language, p.ID, err)
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGroupEventParts(t *testing.T) {
+	early := time.Date(2026, time.March, 1, 8, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	tests := []struct {
+		name  string
+		parts []*LessonPart
+		want  []string // Key:Order of each group, in order
+	}{
+		{
+			name: "translation groups follow the Hebrew part",
+			parts: []*LessonPart{
+				{ID: "en2", Language: "en", Order: 1, TranslationGroupID: "g2"},
+				{ID: "he1", Language: "he", Order: 1, TranslationGroupID: "g1"},
+				{ID: "he2", Language: "he", Order: 2, TranslationGroupID: "g2"},
+				{ID: "en1", Language: "en", Order: 2, TranslationGroupID: "g1"},
+			},
+			want: []string{"g1:1", "g2:2"},
+		},
+		{
+			name: "parts without a translation group are grouped by order",
+			parts: []*LessonPart{
+				{ID: "he2", Language: "he", Order: 2},
+				{ID: "en1", Language: "en", Order: 1},
+				{ID: "he1", Language: "he", Order: 1},
+			},
+			want: []string{"order:1:1", "order:2:2"},
+		},
+		{
+			name: "without a Hebrew part the most common order wins, the lowest on a tie",
+			parts: []*LessonPart{
+				{ID: "en1", Language: "en", Order: 3, TranslationGroupID: "g1"},
+				{ID: "ru1", Language: "ru", Order: 2, TranslationGroupID: "g1"},
+				{ID: "es1", Language: "es", Order: 3, TranslationGroupID: "g1"},
+				{ID: "en2", Language: "en", Order: 5, TranslationGroupID: "g2"},
+				{ID: "ru2", Language: "ru", Order: 4, TranslationGroupID: "g2"},
+			},
+			want: []string{"g1:3", "g2:4"},
+		},
+		{
+			name: "groups at the same order are sorted by creation",
+			parts: []*LessonPart{
+				{ID: "he2", Language: "he", Order: 1, TranslationGroupID: "g2", CreatedAt: late},
+				{ID: "he1", Language: "he", Order: 1, TranslationGroupID: "g1", CreatedAt: early},
+			},
+			want: []string{"g1:1", "g2:1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, g := range GroupEventParts(tt.parts) {
+				got = append(got, fmt.Sprintf("%s:%d", g.Key, g.Order))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groups %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartGroupDrift(t *testing.T) {
+	groups := GroupEventParts([]*LessonPart{
+		{ID: "he1", Language: "he", Order: 1, TranslationGroupID: "g1"},
+		{ID: "en1", Language: "en", Order: 2, TranslationGroupID: "g1"},
+	})
+	if len(groups) != 1 || !groups[0].Drifted() || groups[0].Reference().ID != "he1" {
+		t.Errorf("groups %+v, want one drifted group led by he1", groups)
+	}
+}
+
+func TestSequenceGroups(t *testing.T) {
+	groups := GroupEventParts([]*LessonPart{
+		{ID: "prep", Language: "he", Order: 0, TranslationGroupID: "g0"},
+		{ID: "he1", Language: "he", Order: 1, TranslationGroupID: "g1"},
+		{ID: "en1", Language: "en", Order: 1, TranslationGroupID: "g1"},
+		{ID: "he2", Language: "he", Order: 2, TranslationGroupID: "g2"},
+		{ID: "legacy", Language: "he", Order: 3},
+	})
+	tests := []struct {
+		name    string
+		ids     []string
+		want    []string // Keys of the sequenced groups
+		invalid bool
+	}{
+		{name: "by part ID", ids: []string{"he2", "legacy", "en1"}, want: []string{"g2", "order:3", "g1"}},
+		{name: "by translation group ID", ids: []string{"g2", "g1", "order:3"}, want: []string{"g2", "g1", "order:3"}},
+		{name: "preparation part first", ids: []string{"prep", "he1", "he2", "legacy"}, want: []string{"g1", "g2", "order:3"}},
+		{name: "preparation part later", ids: []string{"he1", "prep", "he2", "legacy"}, invalid: true},
+		{name: "group listed twice", ids: []string{"he1", "en1", "he2", "legacy"}, invalid: true},
+		{name: "unknown part", ids: []string{"he1", "he2", "legacy", "other"}, invalid: true},
+		{name: "missing part", ids: []string{"he1", "he2"}, invalid: true},
+		{name: "nothing listed", ids: nil, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequence, err := sequenceGroups(groups, tt.ids)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidPartOrder) {
+					t.Errorf("error %v, want ErrInvalidPartOrder", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sequenceGroups: %v", err)
+			}
+			var got []string
+			for _, g := range sequence {
+				got = append(got, g.Key)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sequence %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
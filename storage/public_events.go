@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PublicEventsQuery selects public events to load with their parts and event type.
+// Events are sorted by date, newest first, then by their order.
+type PublicEventsQuery struct {
+	Languages []string   // Only parts in these languages are loaded
+	FromDate  *time.Time // Only events on or after this date
+	Types     []string   // Only events of these types; all types if empty
+	Limit     int
+}
+
+// EventWithParts is an event with its parts, sorted by order, and its event type
+type EventWithParts struct {
+	Event     *Event
+	Parts     []*LessonPart
+	EventType *EventType // Nil if the event type was deleted
+}
+
+// Filter returns the query's constraints on events as a MongoDB filter
+func (q PublicEventsQuery) Filter() bson.M {
+	filter := bson.M{"public": true, "deleted_at": notTrashed}
+	if q.FromDate != nil {
+		filter["date"] = bson.M{"$gte": *q.FromDate}
+	}
+	if len(q.Types) > 0 {
+		filter["type"] = bson.M{"$in": q.Types}
+	}
+	return filter
+}
+
+// PartFilter returns the query's constraints on the parts of an event
+func (q PublicEventsQuery) PartFilter(eventID string) PartQuery {
+	return PartQuery{EventID: eventID, Languages: q.Languages}
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PartQuery describes a filtered, sorted and paginated lookup of lesson parts.
+// Zero values mean "no constraint".
+type PartQuery struct {
+	IDs                []string   // Only parts with these IDs
+	EventID            string     // Only parts of this event
+	EventIDs           []string   // Only parts of these events
+	Language           string     // Only parts in this language
+	Languages          []string   // Only parts in one of these languages
+	PartType           string     // Only parts of this type
+	Order              *int       // Only parts at this position within the event
+	FromDate           *time.Time // Only parts dated on or after this time
+	ToDate             *time.Time // Only parts dated on or before this time
+	SourceID           string     // Only parts that reference this kabbalahmedia source
+	TranslationGroupID string     // Only parts in this translation group
+	TranslationStub    *bool      // Only parts that are (or aren't) untranslated stubs
+	PublicEventsOnly   bool       // Only parts of public, untrashed events; stores check the events themselves
+	Trashed            TrashFilter
+	Sort               []SortField
+	Limit              int
+	Offset             int
+}
+
+// SortField orders query results by a document field
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// DefaultPartSort orders parts by position within the event, then by language
+var DefaultPartSort = []SortField{{Field: "order"}, {Field: "language"}}
+
+// partSortFields lists the fields parts may be sorted by
+var partSortFields = map[string]bool{
+	"order":      true,
+	"language":   true,
+	"date":       true,
+	"created_at": true,
+	"title":      true,
+	"event_id":   true,
+	"part_type":  true,
+	"deleted_at": true,
+}
+
+// SortOrDefault returns the query's sort, falling back to DefaultPartSort
+func (q PartQuery) SortOrDefault() []SortField {
+	if len(q.Sort) == 0 {
+		return DefaultPartSort
+	}
+	return q.Sort
+}
+
+// ParseSort parses a comma-separated list of sort fields, each optionally
+// prefixed with "-" for descending order, e.g. "-date,order"
+func ParseSort(s string) ([]SortField, error) {
+	var fields []SortField
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+		if !partSortFields[field.Field] {
+			return nil, fmt.Errorf("unsupported sort field: %s", field.Field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Validate checks that the query only sorts by supported fields
+func (q PartQuery) Validate() error {
+	for _, f := range q.Sort {
+		if !partSortFields[f.Field] {
+			return fmt.Errorf("unsupported sort field: %s", f.Field)
+		}
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("limit and offset must not be negative")
+	}
+	return nil
+}
+
+// Filter converts the query to a MongoDB filter document. PublicEventsOnly
+// needs the events and isn't part of it.
+func (q PartQuery) Filter() bson.M {
+	filter := bson.M{}
+	if q.IDs != nil {
+		filter["_id"] = bson.M{"$in": q.IDs}
+	}
+	if q.EventID != "" {
+		filter["event_id"] = q.EventID
+	}
+	if q.EventIDs != nil {
+		cond := bson.M{"$in": q.EventIDs}
+		if q.EventID != "" {
+			cond["$eq"] = q.EventID
+		}
+		filter["event_id"] = cond
+	}
+	if q.Language != "" {
+		filter["language"] = q.Language
+	}
+	if q.Languages != nil {
+		cond := bson.M{"$in": q.Languages}
+		if q.Language != "" {
+			cond["$eq"] = q.Language
+		}
+		filter["language"] = cond
+	}
+	if q.PartType != "" {
+		filter["part_type"] = q.PartType
+	}
+	if q.Order != nil {
+		filter["order"] = *q.Order
+	}
+	if q.FromDate != nil || q.ToDate != nil {
+		dateFilter := bson.M{}
+		if q.FromDate != nil {
+			dateFilter["$gte"] = *q.FromDate
+		}
+		if q.ToDate != nil {
+			dateFilter["$lte"] = *q.ToDate
+		}
+		filter["date"] = dateFilter
+	}
+	if q.SourceID != "" {
+		filter["sources.source_id"] = q.SourceID
+	}
+	if q.TranslationGroupID != "" {
+		filter["translation_group_id"] = q.TranslationGroupID
+	}
+	if q.TranslationStub != nil {
+		if *q.TranslationStub {
+			filter["translation_stub"] = true
+		} else {
+			filter["translation_stub"] = bson.M{"$ne": true}
+		}
+	}
+	switch q.Trashed {
+	case ExcludeTrashed:
+		filter["deleted_at"] = notTrashed
+	case OnlyTrashed:
+		filter["deleted_at"] = bson.M{"$exists": true}
+	}
+	return filter
+}
+
+// Matches reports whether a part satisfies the query's filters (not its pagination).
+// Non-MongoDB stores use it to evaluate queries in memory, checking PublicEventsOnly
+// against their events themselves.
+func (q PartQuery) Matches(part *LessonPart) bool {
+	if q.IDs != nil && !containsString(q.IDs, part.ID) {
+		return false
+	}
+	switch q.Trashed {
+	case ExcludeTrashed:
+		if part.DeletedAt != nil {
+			return false
+		}
+	case OnlyTrashed:
+		if part.DeletedAt == nil {
+			return false
+		}
+	}
+	if q.EventID != "" && part.EventID != q.EventID {
+		return false
+	}
+	if q.EventIDs != nil && !containsString(q.EventIDs, part.EventID) {
+		return false
+	}
+	if q.Language != "" && part.Language != q.Language {
+		return false
+	}
+	if q.Languages != nil && !containsString(q.Languages, part.Language) {
+		return false
+	}
+	if q.PartType != "" && part.PartType != q.PartType {
+		return false
+	}
+	if q.TranslationStub != nil && part.TranslationStub != *q.TranslationStub {
+		return false
+	}
+	if q.Order != nil && part.Order != *q.Order {
+		return false
+	}
+	if q.TranslationGroupID != "" && part.TranslationGroupID != q.TranslationGroupID {
+		return false
+	}
+	if q.FromDate != nil && part.Date.Before(*q.FromDate) {
+		return false
+	}
+	if q.ToDate != nil && part.Date.After(*q.ToDate) {
+		return false
+	}
+	if q.SourceID != "" {
+		found := false
+		for _, source := range part.Sources {
+			if source.SourceID == q.SourceID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ComparePartsBy compares two parts by the given sort fields, returning a negative
+// number if a sorts first, a positive number if b does, and 0 if they are equal
+func ComparePartsBy(a, b *LessonPart, sort []SortField) int {
+	for _, f := range sort {
+		c := 0
+		switch f.Field {
+		case "order":
+			c = compareInts(a.Order, b.Order)
+		case "language":
+			c = compareStrings(a.Language, b.Language)
+		case "date":
+			c = compareTimes(a.Date, b.Date)
+		case "created_at":
+			c = compareTimes(a.CreatedAt, b.CreatedAt)
+		case "title":
+			c = compareStrings(a.Title, b.Title)
+		case "event_id":
+			c = compareStrings(a.EventID, b.EventID)
+		case "part_type":
+			c = compareStrings(a.PartType, b.PartType)
+		case "deleted_at":
+			c = compareTimes(timeOrZero(a.DeletedAt), timeOrZero(b.DeletedAt))
+		}
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareStrings(a.ID, b.ID)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Document types recorded in revisions
+const (
+	RevisionTypePart  = "part"
+	RevisionTypeEvent = "event"
+)
+
+// Revision is an immutable snapshot of a part or event, appended every time it is saved
+type Revision struct {
+	ID           string      `json:"id" bson:"_id"`
+	DocumentType string      `json:"document_type" bson:"document_type"` // "part" or "event"
+	DocumentID   string      `json:"document_id" bson:"document_id"`
+	Rev          int         `json:"rev" bson:"rev"` // 1 for the first save, counting up
+	Author       string      `json:"author,omitempty" bson:"author,omitempty"`
+	CreatedAt    time.Time   `json:"created_at" bson:"created_at"`
+	Part         *LessonPart `json:"part,omitempty" bson:"part,omitempty"`
+	Event        *Event      `json:"event,omitempty" bson:"event,omitempty"`
+}
+
+// RevisionID returns the ID of a document's n-th revision
+func RevisionID(documentType, documentID string, rev int) string {
+	return fmt.Sprintf("%s-%s-%d", documentType, documentID, rev)
+}
+
+// NewPartRevision creates an unnumbered revision of a part, authored by whoever last updated it
+func NewPartRevision(part *LessonPart) *Revision {
+	snapshot := *part
+	return &Revision{
+		DocumentType: RevisionTypePart,
+		DocumentID:   part.ID,
+		Author:       part.UpdatedBy,
+		CreatedAt:    time.Now(),
+		Part:         &snapshot,
+	}
+}
+
+// NewEventRevision creates an unnumbered revision of an event, authored by whoever last updated it
+func NewEventRevision(event *Event) *Revision {
+	snapshot := *event
+	return &Revision{
+		DocumentType: RevisionTypeEvent,
+		DocumentID:   event.ID,
+		Author:       event.UpdatedBy,
+		CreatedAt:    time.Now(),
+		Event:        &snapshot,
+	}
+}
+
+// Number assigns the revision its number and ID
+func (r *Revision) Number(rev int) {
+	r.Rev = rev
+	r.ID = RevisionID(r.DocumentType, r.DocumentID, rev)
+}
+
+// FieldChange is one field that differs between two revisions.
+// Nested fields such as per-language titles are reported as "titles.en".
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// unversionedFields are maintained by the stores on every save, so they differ
+// between any two revisions and aren't reported as changes
+var unversionedFields = []string{"version", "updated_at", "updated_by"}
+
+// DiffRevisions lists the fields that changed from one revision to another, sorted by field name
+func DiffRevisions(from, to *Revision) ([]FieldChange, error) {
+	if from.DocumentType != to.DocumentType || from.DocumentID != to.DocumentID {
+		return nil, fmt.Errorf("revisions belong to different documents")
+	}
+
+	fromFields, err := revisionFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := revisionFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range unversionedFields {
+		delete(fromFields, field)
+		delete(toFields, field)
+	}
+
+	changes := []FieldChange{}
+	diffFields("", fromFields, toFields, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// revisionFields returns the revision's snapshot as a JSON object
+func revisionFields(r *Revision) (map[string]interface{}, error) {
+	var snapshot interface{} = r.Part
+	if r.DocumentType == RevisionTypeEvent {
+		snapshot = r.Event
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode revision %d: %w", r.Rev, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode revision %d: %w", r.Rev, err)
+	}
+	return fields, nil
+}
+
+// diffFields appends the differences between two JSON objects to changes,
+// descending into nested objects and comparing arrays as a whole
+func diffFields(prefix string, from, to map[string]interface{}, changes *[]FieldChange) {
+	keys := make(map[string]bool, len(from)+len(to))
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		field := prefix + k
+		a, b := from[k], to[k]
+		aMap, aIsMap := a.(map[string]interface{})
+		bMap, bIsMap := b.(map[string]interface{})
+		switch {
+		case aIsMap && bIsMap:
+			diffFields(field+".", aMap, bMap, changes)
+		case aIsMap && b == nil:
+			diffFields(field+".", aMap, map[string]interface{}{}, changes)
+		case a == nil && bIsMap:
+			diffFields(field+".", map[string]interface{}{}, bMap, changes)
+		case !reflect.DeepEqual(a, b):
+			*changes = append(*changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+}
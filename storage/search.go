@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SearchQuery describes a full-text search over lesson parts or events.
+// Parts are searched by title, description and source titles; events by their titles.
+type SearchQuery struct {
+	Text       string
+	Language   string     // Parts: only this language; also selects the stemming rules
+	FromDate   *time.Time // Only documents dated on or after this time
+	ToDate     *time.Time // Only documents dated on or before this time
+	EventType  string     // Events: only this event type
+	PublicOnly bool       // Events: only public events
+	Limit      int
+}
+
+// PartHit is a part matched by a search, with its relevance score
+type PartHit struct {
+	Part  *LessonPart
+	Score float64
+}
+
+// EventHit is an event matched by a search, with its relevance score
+type EventHit struct {
+	Event *Event
+	Score float64
+}
+
+// Text index weights; MongoDB and the in-memory search rank with the same ones
+const (
+	searchWeightTitle       = 10
+	searchWeightSourceTitle = 5
+	searchWeightDescription = 1
+)
+
+// TitleLanguages are the languages event titles are indexed for
+var TitleLanguages = []string{"he", "en", "ru", "es", "de", "it", "fr", "uk", "tr", "pt-BR", "bg"}
+
+// textSearchLanguages maps part languages to the MongoDB text search languages
+// that stem them. Hebrew has no MongoDB stemmer; SearchTerms handles its prefixes instead.
+var textSearchLanguages = map[string]string{
+	"en":    "english",
+	"ru":    "russian",
+	"es":    "spanish",
+	"de":    "german",
+	"it":    "italian",
+	"fr":    "french",
+	"pt-BR": "portuguese",
+	"tr":    "turkish",
+}
+
+// TextSearchLanguage returns the MongoDB text search language for a part language
+func TextSearchLanguage(language string) string {
+	if l, ok := textSearchLanguages[language]; ok {
+		return l
+	}
+	return "none"
+}
+
+// hebrewPrefixes are the one-letter words written attached to the next word:
+// and, the, in, to, from, that, as
+const hebrewPrefixes = "והבלמשכ"
+
+// searchWord is a normalized word and where it appears in the original text
+type searchWord struct {
+	text       string
+	start, end int
+}
+
+// splitWords splits text into lower-cased words without combining marks such as Hebrew points
+func splitWords(text string) []searchWord {
+	var words []searchWord
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && b.Len() > 0 {
+			words = append(words, searchWord{text: b.String(), start: start, end: end})
+		}
+		b.Reset()
+		start = -1
+	}
+	for i, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if start < 0 {
+				start = i
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.Is(unicode.Mn, r) && start >= 0:
+			// Drop points and accents but keep the word together
+		case r == '"' || r == '\'' || r == '׳' || r == '״':
+			// Hebrew abbreviations and geresh are written inside words
+			if start < 0 || i+utf8.RuneLen(r) >= len(text) {
+				flush(i)
+			}
+		default:
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return words
+}
+
+// hebrewVariants returns the word with up to two attached prefix letters removed
+func hebrewVariants(word string) []string {
+	variants := []string{word}
+	runes := []rune(word)
+	for i := 0; i < 2 && len(runes)-i > 2; i++ {
+		if !strings.ContainsRune(hebrewPrefixes, runes[i]) {
+			break
+		}
+		variants = append(variants, string(runes[i+1:]))
+	}
+	return variants
+}
+
+// isHebrew reports whether a word is written in Hebrew letters
+func isHebrew(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return unicode.Is(unicode.Hebrew, r)
+}
+
+// SearchTerms splits a search string into normalized words. Hebrew words are
+// also looked up without their attached prefix letters.
+func SearchTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, w := range splitWords(text) {
+		variants := []string{w.text}
+		if isHebrew(w.text) {
+			variants = hebrewVariants(w.text)
+		}
+		for _, v := range variants {
+			if !seen[v] {
+				seen[v] = true
+				terms = append(terms, v)
+			}
+		}
+	}
+	return terms
+}
+
+// wordMatches reports whether a normalized document word matches a search term.
+// Longer words also match on a shared stem, which stands in for stemming outside MongoDB.
+func wordMatches(word, term string) bool {
+	candidates := []string{word}
+	if isHebrew(word) {
+		candidates = hebrewVariants(word)
+	}
+	for _, c := range candidates {
+		if c == term {
+			return true
+		}
+		if utf8.RuneCountInString(c) >= 4 && utf8.RuneCountInString(term) >= 4 &&
+			(strings.HasPrefix(c, term) || strings.HasPrefix(term, c)) {
+			return true
+		}
+	}
+	return false
+}
+
+// countMatches returns how many words of text match one of the terms
+func countMatches(text string, terms []string) int {
+	n := 0
+	for _, w := range splitWords(text) {
+		for _, t := range terms {
+			if wordMatches(w.text, t) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// ScorePart ranks a part against search terms with the text index weights
+func ScorePart(part *LessonPart, terms []string) float64 {
+	score := searchWeightTitle * countMatches(part.Title, terms)
+	score += searchWeightDescription * countMatches(part.Description, terms)
+	for _, s := range part.Sources {
+		score += searchWeightSourceTitle * countMatches(s.SourceTitle, terms)
+	}
+	return float64(score)
+}
+
+// ScoreEvent ranks an event against search terms by its titles
+func ScoreEvent(event *Event, terms []string) float64 {
+	score := 0
+	for _, title := range event.Titles {
+		score += countMatches(title, terms)
+	}
+	return float64(score)
+}
+
+// Highlight HTML-escapes text and wraps the words that match a term in <mark> tags.
+// Texts longer than maxLen runes are cut to an excerpt around the first match.
+// It returns "" if nothing matches.
+func Highlight(text string, terms []string, maxLen int) string {
+	var matched []searchWord
+	for _, w := range splitWords(text) {
+		for _, t := range terms {
+			if wordMatches(w.text, t) {
+				matched = append(matched, w)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return ""
+	}
+
+	from, to := 0, len(text)
+	if maxLen > 0 && utf8.RuneCountInString(text) > maxLen {
+		from = backRunes(text, matched[0].start, maxLen/4)
+		to = forwardRunes(text, from, maxLen)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, w := range matched {
+		if w.start < from || w.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:w.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[w.start:w.end]))
+		b.WriteString("</mark>")
+		pos = w.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// backRunes returns the byte offset n runes before offset i
+func backRunes(text string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:i])
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes returns the byte offset n runes after offset i
+func forwardRunes(text string, i, n int) int {
+	for ; n > 0 && i < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return i
+}
+
+// PartFilter returns the non-text constraints of a part search
+func (q SearchQuery) PartFilter() PartQuery {
+	return PartQuery{Language: q.Language, FromDate: q.FromDate, ToDate: q.ToDate}
+}
+
+// EventFilter returns the non-text constraints of an event search as a MongoDB filter
+func (q SearchQuery) EventFilter() bson.M {
+	filter := bson.M{"deleted_at": notTrashed}
+	if q.EventType != "" {
+		filter["type"] = q.EventType
+	}
+	if q.PublicOnly {
+		filter["public"] = true
+	}
+	if q.FromDate != nil || q.ToDate != nil {
+		dateFilter := bson.M{}
+		if q.FromDate != nil {
+			dateFilter["$gte"] = *q.FromDate
+		}
+		if q.ToDate != nil {
+			dateFilter["$lte"] = *q.ToDate
+		}
+		filter["date"] = dateFilter
+	}
+	return filter
+}
+
+// partTextFilters returns the filters of a part search, one per query to run.
+// Parts are indexed with the stemming rules of their own language, so a search
+// without a language runs one query for each stemmed language the terms could
+// be written in, judging by their script, and one unstemmed query for the parts
+// of every other language.
+func (q SearchQuery) partTextFilters(terms []string) []bson.M {
+	search := strings.Join(terms, " ")
+	if q.Language != "" {
+		filter := q.PartFilter().Filter()
+		filter["$text"] = bson.M{"$search": search, "$language": TextSearchLanguage(q.Language)}
+		return []bson.M{filter}
+	}
+
+	languages := stemmedLanguagesOf(terms)
+	filters := make([]bson.M, 0, len(languages)+1)
+	for _, language := range languages {
+		lq := q
+		lq.Language = language
+		filter := lq.PartFilter().Filter()
+		filter["$text"] = bson.M{"$search": search, "$language": TextSearchLanguage(language)}
+		filters = append(filters, filter)
+	}
+	filter := q.PartFilter().Filter()
+	if len(languages) > 0 {
+		filter["language"] = bson.M{"$nin": languages}
+	}
+	filter["$text"] = bson.M{"$search": search, "$language": "none"}
+	return append(filters, filter)
+}
+
+// stemmedLanguagesOf returns the part languages with a MongoDB stemmer that are
+// written in the script of one of the terms, sorted
+func stemmedLanguagesOf(terms []string) []string {
+	var languages []string
+	for language := range textSearchLanguages {
+		script := unicode.Latin
+		if language == "ru" {
+			script = unicode.Cyrillic
+		}
+		for _, term := range terms {
+			if r, _ := utf8.DecodeRuneInString(term); unicode.Is(script, r) {
+				languages = append(languages, language)
+				break
+			}
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
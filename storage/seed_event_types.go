@@ -1,13 +1,14 @@
 package storage
 
 import (
+	"context"
 	"log"
 )
 
 // SeedDefaultEventTypes populates the event_types collection with default types
 // if the collection is empty (first run).
-func SeedDefaultEventTypes(store EventTypeStore) error {
-	count, err := store.CountEventTypes()
+func SeedDefaultEventTypes(ctx context.Context, store EventTypeStore) error {
+	count, err := store.CountEventTypes(ctx)
 	if err != nil {
 		return err
 	}
@@ -158,7 +159,7 @@ func SeedDefaultEventTypes(store EventTypeStore) error {
 			Order:  d.order,
 			Titles: d.titles,
 		}
-		if err := store.CreateEventType(et); err != nil {
+		if err := store.CreateEventType(ctx, et); err != nil {
 			return err
 		}
 	}
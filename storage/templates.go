@@ -10,6 +10,7 @@ type TemplateConfig struct {
 	Languages   []string             `json:"languages" bson:"languages"`
 	Preparation map[string]string    `json:"preparation" bson:"preparation"`
 	Templates   []TemplateDefinition `json:"templates" bson:"templates"`
+	Version     int                  `json:"version" bson:"version"` // Incremented on every save, used for optimistic concurrency
 }
 
 // TemplateDefinition defines a title template with translations
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Timeouts bounds how long storage operations may run, by operation class
+type Timeouts struct {
+	Read  time.Duration // Single-document reads and filtered lists
+	Write time.Duration // Single-document writes
+	Bulk  time.Duration // Full scans, purges and transactions spanning several writes
+}
+
+// DefaultTimeouts are used for every class that isn't configured
+var DefaultTimeouts = Timeouts{
+	Read:  5 * time.Second,
+	Write: 5 * time.Second,
+	Bulk:  30 * time.Second,
+}
+
+var (
+	timeoutsMutex sync.RWMutex
+	timeouts      = DefaultTimeouts
+)
+
+// ConfigureTimeouts sets the storage timeouts. Zero fields keep their defaults.
+func ConfigureTimeouts(t Timeouts) {
+	if t.Read <= 0 {
+		t.Read = DefaultTimeouts.Read
+	}
+	if t.Write <= 0 {
+		t.Write = DefaultTimeouts.Write
+	}
+	if t.Bulk <= 0 {
+		t.Bulk = DefaultTimeouts.Bulk
+	}
+
+	timeoutsMutex.Lock()
+	defer timeoutsMutex.Unlock()
+	timeouts = t
+}
+
+// CurrentTimeouts returns the configured storage timeouts
+func CurrentTimeouts() Timeouts {
+	timeoutsMutex.RLock()
+	defer timeoutsMutex.RUnlock()
+	return timeouts
+}
+
+// ReadContext derives a context for a read from ctx, bounded by the read timeout
+func ReadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, CurrentTimeouts().Read)
+}
+
+// WriteContext derives a context for a write from ctx, bounded by the write timeout
+func WriteContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, CurrentTimeouts().Write)
+}
+
+// BulkContext derives a context for a bulk operation from ctx, bounded by the bulk timeout.
+// Handlers use it to put an overall deadline on units of work made of many calls.
+func BulkContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, CurrentTimeouts().Bulk)
+}
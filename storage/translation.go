@@ -0,0 +1,23 @@
+package storage
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// NewTranslationGroupID returns a new translation group ID.
+// Groups have no collection of their own, so the ID is an ObjectID rather than
+// a short ID that could only be checked for collisions against one.
+func NewTranslationGroupID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// TranslationsQuery returns a query for every language version of a part, the part included.
+// Parts created before translation groups existed are matched by event and order.
+func TranslationsQuery(part *LessonPart) PartQuery {
+	if part.TranslationGroupID != "" {
+		return PartQuery{TranslationGroupID: part.TranslationGroupID, Sort: []SortField{{Field: "language"}}}
+	}
+	if part.EventID != "" {
+		order := part.Order
+		return PartQuery{EventID: part.EventID, Order: &order, Sort: []SortField{{Field: "language"}}}
+	}
+	return PartQuery{IDs: []string{part.ID}}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Deletion stamps documents moved to the trash by one delete operation.
+// An event and the parts trashed with it share the same stamp, which is how
+// they are found again and restored together.
+type Deletion struct {
+	At time.Time
+	By string
+}
+
+// NewDeletion creates a deletion stamp for the current time. The time is truncated
+// to milliseconds so it compares equal after a round trip through MongoDB.
+func NewDeletion(by string) Deletion {
+	return Deletion{
+		At: time.Now().UTC().Truncate(time.Millisecond),
+		By: by,
+	}
+}
+
+// Matches reports whether a document's deleted_at belongs to this deletion
+func (d Deletion) Matches(deletedAt *time.Time) bool {
+	return deletedAt != nil && deletedAt.Equal(d.At)
+}
+
+// TrashFilter selects how trashed documents are treated by a query
+type TrashFilter int
+
+const (
+	ExcludeTrashed TrashFilter = iota // Default: hide trashed documents
+	IncludeTrashed                    // Return trashed and live documents
+	OnlyTrashed                       // Return only trashed documents
+)
+
+// notTrashed is the filter matching documents that are not in the trash
+var notTrashed = bson.M{"$exists": false}
+
+// WithoutTrashed returns filter restricted to documents that are not in the trash,
+// unless the filter already constrains deleted_at itself
+func WithoutTrashed(filter bson.M) bson.M {
+	if _, ok := filter["deleted_at"]; ok {
+		return filter
+	}
+	f := bson.M{"deleted_at": notTrashed}
+	for k, v := range filter {
+		f[k] = v
+	}
+	return f
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrVersionConflict is returned when a document is saved with a version other than
+// the stored one, i.e. someone else changed it since it was read
+var ErrVersionConflict = errors.New("version conflict: the document was changed by someone else")
+
+// versionFilter matches documents at the given version. Documents written before
+// versioning was introduced have no version field and count as version 0.
+func versionFilter(version int) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}